@@ -0,0 +1,34 @@
+// migrate-passwords users.password (legacy) kolonundaki değerleri password_hash'e
+// taşır ve kolonu düşürür. Kolon geri dönüşsüz silindiği için API açılışta bunu
+// yapmaz; deploy'da bilerek çalıştırılır. Tekrar çalıştırmak zararsızdır.
+//
+//	go run ./cmd/migrate-passwords
+package main
+
+import (
+	"log"
+
+	"github.com/joho/godotenv"
+	"gorm.io/driver/postgres"
+	"gorm.io/gorm"
+
+	"example.com/ecom-go/internal/app"
+	"example.com/ecom-go/internal/service"
+)
+
+func main() {
+	if err := godotenv.Load(); err != nil {
+		log.Println("No .env file found, using system environment variables")
+	}
+
+	db, err := gorm.Open(postgres.Open(app.DatabaseDSN()), &gorm.Config{})
+	if err != nil {
+		log.Fatalf("db connect: %v", err)
+	}
+
+	n, err := service.MigrateLegacyPasswords(db, service.NewPasswordHasher())
+	if err != nil {
+		log.Fatalf("migrate: %v", err)
+	}
+	log.Printf("legacy passwords migrated: %d (password column dropped)", n)
+}
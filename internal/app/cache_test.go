@@ -0,0 +1,47 @@
+package app
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+func TestVersionSubSecond(t *testing.T) {
+	base := time.Date(2026, 1, 2, 3, 4, 5, 100, time.UTC)
+	a, b := version{N: 3, Last: base}, version{N: 3, Last: base.Add(time.Millisecond)}
+	if a.String() == b.String() {
+		t.Fatalf("same-second updates share ETag part %q", a)
+	}
+}
+
+func TestCacheableLastModified(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	last := time.Date(2026, 1, 2, 3, 4, 5, 500_000_000, time.UTC)
+	serve := func(h http.Header) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		c, _ := gin.CreateTestContext(w)
+		c.Request = httptest.NewRequest(http.MethodGet, "/", nil)
+		c.Request.Header = h
+		if !cacheable(c, `W/"v1"`, last) {
+			c.Status(http.StatusOK)
+		}
+		c.Writer.WriteHeaderNow()
+		return w
+	}
+
+	w := serve(http.Header{})
+	lm := w.Header().Get("Last-Modified")
+	if lm != "Fri, 02 Jan 2026 03:04:05 GMT" {
+		t.Fatalf("Last-Modified = %q", lm)
+	}
+	// kendi yazdığımız (saniyeye kesilmiş) tarih geri gelince 304 olmalı
+	if w := serve(http.Header{"If-Modified-Since": {lm}}); w.Code != http.StatusNotModified {
+		t.Fatalf("If-Modified-Since: status = %d, want 304", w.Code)
+	}
+	if w := serve(http.Header{"If-None-Match": {`W/"v0"`}, "If-Modified-Since": {lm}}); w.Code != http.StatusOK {
+		t.Fatalf("stale ETag: status = %d, want 200", w.Code)
+	}
+}
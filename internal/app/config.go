@@ -1,10 +1,29 @@
 package app
 
-import "os"
+import (
+	"fmt"
+	"os"
+
+	"example.com/ecom-go/internal/service"
+)
 
 type Config struct {
 	Env, Port string
-}	
+
+	// CORS_ALLOWED_ORIGINS: ayrı frontend domain'leri, ör. "https://shop.cakarokko.com"
+	AllowedOrigins []string
+	// SECURITY_CSP: boşsa web/ sayfalarına uygun varsayılan CSP
+	CSP string
+	// WEB_DIR: doluysa frontend gömülü FS yerine bu dizinden (canlı) servis edilir
+	WebDir string
+	// STORAGE_DIR: yüklenen görseller + üretilen thumbnail'ler (+ faturalar)
+	StorageDir string
+	// SELLER_*: faturadaki satıcı bilgisi; INVOICE_PREFIX: fatura serisi ("INV" → INV-2026-000001)
+	Seller        service.Seller
+	InvoicePrefix string
+	// ENABLE_SEED: POST /api/admin/seed (demo verisi, tabloları boşaltır); sadece APP_ENV=dev
+	EnableSeed bool
+}
 
 
 func getEnv(k, d string) string {
@@ -16,5 +35,36 @@ func LoadConfig() Config {
 	return Config{
 		Env:  getEnv("APP_ENV", "dev"),
 		Port: getEnv("APP_PORT", "8080"),
+
+		AllowedOrigins: splitList(os.Getenv("CORS_ALLOWED_ORIGINS")),
+		CSP:            os.Getenv("SECURITY_CSP"),
+		WebDir:         os.Getenv("WEB_DIR"),
+		StorageDir:     getEnv("STORAGE_DIR", "data/uploads"),
+
+		Seller: service.Seller{
+			Name:      getEnv("SELLER_NAME", "Cakarokko"),
+			Address:   os.Getenv("SELLER_ADDRESS"),
+			TaxOffice: os.Getenv("SELLER_TAX_OFFICE"),
+			TaxID:     os.Getenv("SELLER_TAX_ID"),
+			Email:     os.Getenv("SELLER_EMAIL"),
+			Phone:     os.Getenv("SELLER_PHONE"),
+		},
+		InvoicePrefix: getEnv("INVOICE_PREFIX", "INV"),
+		EnableSeed:    os.Getenv("ENABLE_SEED") == "true",
+	}
+}
+
+// DatabaseDSN DATABASE_URL'i, yoksa PG* değişkenlerinden DSN'i döner.
+// cmd/api ve cmd/migrate-passwords aynı bağlantıyı kullansın diye burada.
+func DatabaseDSN() string {
+	if dsn := os.Getenv("DATABASE_URL"); dsn != "" {
+		return dsn
 	}
+	return fmt.Sprintf("host=%s port=%s user=%s password=%s dbname=%s sslmode=%s",
+		getEnv("PGHOST", "127.0.0.1"),
+		getEnv("PGPORT", "5433"),
+		getEnv("PGUSER", "postgres"),
+		getEnv("PGPASSWORD", "postgres"),
+		getEnv("PGDATABASE", "ecom"),
+		getEnv("PGSSLMODE", "disable"))
 }
@@ -0,0 +1,116 @@
+package app
+
+import (
+	"crypto/hmac"
+	crand "crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"log"
+	"net/http"
+	"os"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+)
+
+const (
+	csrfCookie = "csrf_token"
+	csrfHeader = "X-CSRF-Token"
+	csrfField  = "csrf_token" // script'siz HTML formlar için (header yerine)
+)
+
+// sessionCookies cookie ile kimlik doğrulamada kullanılan isimler
+// ("session" gin login'i, "auth" handlers.AuthHTTP).
+var sessionCookies = []string{"session", "auth"}
+
+// csrfGuard session'a bağlı (HMAC) token ile CSRF koruması.
+//
+// Token = HMAC(CSRF_SECRET, session cookie). JS'in okuyabilmesi için HttpOnly
+// olmayan csrf_token cookie'sine yazılır; cookie ile doğrulanan ve güvenli
+// olmayan (POST/PUT/PATCH/DELETE) isteklerde X-CSRF-Token header'ı bununla
+// eşleşmeli (HTML formlarda csrf_token alanı). Bearer token'lı istekler (cookie yok) kontrol edilmez.
+type csrfGuard struct {
+	key []byte
+}
+
+func newCSRFGuard() *csrfGuard {
+	key := []byte(os.Getenv("CSRF_SECRET"))
+	if len(key) == 0 {
+		// restart'ta token'lar değişir; JS cookie'yi yeniden alır
+		key = make([]byte, 32)
+		if _, err := crand.Read(key); err != nil {
+			panic(err)
+		}
+		log.Println("CSRF_SECRET not set, using a random per-process key")
+	}
+	return &csrfGuard{key: key}
+}
+
+func sessionFromCookie(c *gin.Context) string {
+	for _, name := range sessionCookies {
+		if v, err := c.Cookie(name); err == nil && v != "" {
+			return v
+		}
+	}
+	return ""
+}
+
+func (g *csrfGuard) tokenFor(session string) string {
+	m := hmac.New(sha256.New, g.key)
+	m.Write([]byte(session))
+	return base64.RawURLEncoding.EncodeToString(m.Sum(nil))
+}
+
+func (g *csrfGuard) setCookie(w http.ResponseWriter, tok string) {
+	http.SetCookie(w, &http.Cookie{
+		Name:     csrfCookie,
+		Value:    tok,
+		Path:     "/",
+		HttpOnly: false, // JS header'a koyabilsin
+		Secure:   true,
+		SameSite: http.SameSiteLaxMode,
+	})
+}
+
+func (g *csrfGuard) Middleware() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		session := sessionFromCookie(c)
+		if session == "" || strings.HasPrefix(c.GetHeader("Authorization"), "Bearer ") {
+			c.Next()
+			return
+		}
+		want := g.tokenFor(session)
+
+		switch c.Request.Method {
+		case http.MethodGet, http.MethodHead, http.MethodOptions:
+			if v, _ := c.Cookie(csrfCookie); v != want {
+				g.setCookie(c.Writer, want)
+			}
+			c.Next()
+			return
+		}
+
+		got := c.GetHeader(csrfHeader)
+		if got == "" && c.ContentType() == "application/x-www-form-urlencoded" {
+			got = c.PostForm(csrfField)
+		}
+		if !hmac.Equal([]byte(got), []byte(want)) {
+			g.setCookie(c.Writer, want) // bayat cookie'yi tazele; istemci tekrar deneyebilir
+			c.AbortWithStatusJSON(http.StatusForbidden, gin.H{"error": "csrf token missing or invalid"})
+			return
+		}
+		c.Next()
+	}
+}
+
+// Handler GET /api/auth/csrf: token'ı JSON olarak da verir (cookie okuyamayan istemciler için).
+func (g *csrfGuard) Handler(c *gin.Context) {
+	session := sessionFromCookie(c)
+	if session == "" {
+		c.JSON(http.StatusOK, gin.H{"token": nil})
+		return
+	}
+	tok := g.tokenFor(session)
+	g.setCookie(c.Writer, tok)
+	c.JSON(http.StatusOK, gin.H{"token": tok})
+}
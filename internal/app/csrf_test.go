@@ -0,0 +1,95 @@
+package app
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+)
+
+func csrfRouter(g *csrfGuard) *gin.Engine {
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+	r.Use(g.Middleware())
+	ok := func(c *gin.Context) { c.Status(http.StatusNoContent) }
+	r.GET("/x", ok)
+	r.POST("/x", ok)
+	return r
+}
+
+func TestCSRFMiddleware(t *testing.T) {
+	g := &csrfGuard{key: []byte("test-key")}
+	r := csrfRouter(g)
+	valid := g.tokenFor("sess-1")
+	form := func(tok string) string { return url.Values{csrfField: {tok}}.Encode() }
+
+	for _, tc := range []struct {
+		name        string
+		method      string
+		session     string // boşsa cookie yok
+		bearer      bool
+		header      string
+		contentType string
+		body        string
+		want        int
+	}{
+		{"safe method", http.MethodGet, "sess-1", false, "", "", "", http.StatusNoContent},
+		{"no session cookie", http.MethodPost, "", false, "", "", "", http.StatusNoContent},
+		{"missing token", http.MethodPost, "sess-1", false, "", "", "", http.StatusForbidden},
+		{"wrong token", http.MethodPost, "sess-1", false, "nope", "", "", http.StatusForbidden},
+		// başka session'ın token'ı bu session için geçmez
+		{"token of other session", http.MethodPost, "sess-1", false, g.tokenFor("sess-2"), "", "", http.StatusForbidden},
+		{"valid header", http.MethodPost, "sess-1", false, valid, "", "", http.StatusNoContent},
+		{"bearer skips check", http.MethodPost, "sess-1", true, "", "", "", http.StatusNoContent},
+		{"form field", http.MethodPost, "sess-1", false, "", "application/x-www-form-urlencoded", form(valid), http.StatusNoContent},
+		{"wrong form field", http.MethodPost, "sess-1", false, "", "application/x-www-form-urlencoded", form("nope"), http.StatusForbidden},
+		// alan yedeği sadece form isteklerinde: JSON gövdedeki alan sayılmaz
+		{"form field in json", http.MethodPost, "sess-1", false, "", "application/json", form(valid), http.StatusForbidden},
+		// header varsa form alanına bakılmaz
+		{"wrong header with valid field", http.MethodPost, "sess-1", false, "nope", "application/x-www-form-urlencoded", form(valid), http.StatusForbidden},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			req := httptest.NewRequest(tc.method, "/x", strings.NewReader(tc.body))
+			if tc.session != "" {
+				req.AddCookie(&http.Cookie{Name: "session", Value: tc.session})
+			}
+			if tc.bearer {
+				req.Header.Set("Authorization", "Bearer abc")
+			}
+			if tc.header != "" {
+				req.Header.Set(csrfHeader, tc.header)
+			}
+			if tc.contentType != "" {
+				req.Header.Set("Content-Type", tc.contentType)
+			}
+			w := httptest.NewRecorder()
+			r.ServeHTTP(w, req)
+			if w.Code != tc.want {
+				t.Fatalf("status = %d, want %d (%s)", w.Code, tc.want, w.Body)
+			}
+		})
+	}
+}
+
+func TestCSRFMiddlewareSetsCookie(t *testing.T) {
+	g := &csrfGuard{key: []byte("test-key")}
+	r := csrfRouter(g)
+	for _, name := range sessionCookies {
+		req := httptest.NewRequest(http.MethodGet, "/x", nil)
+		req.AddCookie(&http.Cookie{Name: name, Value: "sess-1"})
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, req)
+		var got string
+		for _, ck := range w.Result().Cookies() {
+			if ck.Name == csrfCookie {
+				got = ck.Value
+			}
+		}
+		if got != g.tokenFor("sess-1") {
+			t.Fatalf("%s cookie: csrf cookie = %q, want session token", name, got)
+		}
+	}
+}
@@ -0,0 +1,48 @@
+package app
+
+import (
+	"html/template"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+)
+
+// magicConfirmPage GET /api/auth/magic'in cevabı: link GET'te tüketilmez, mail
+// tarayıcıları/önizlemeleri linki açsa da giriş olmaz. Kullanıcı butona basınca
+// form token'ı POST eder. Script yok (CSP); oturum varsa CSRF token form alanında.
+var magicConfirmPage = template.Must(template.New("magic").Parse(`<!doctype html>
+<html lang="tr">
+<head>
+  <meta charset="utf-8">
+  <meta name="viewport" content="width=device-width,initial-scale=1">
+  <meta name="referrer" content="no-referrer">
+  <link rel="stylesheet" href="/assets/app.css">
+  <title>Giriş • Ecom GO</title>
+</head>
+<body>
+  <main class="container">
+    <section class="card">
+      <h2>Giriş yap</h2>
+      <p class="muted">Cakarokko'ya bu cihazdan giriş yapmak için devam et.</p>
+      <form method="post" action="/api/auth/magic">
+        <input type="hidden" name="token" value="{{.Token}}">
+        {{if .CSRF}}<input type="hidden" name="` + csrfField + `" value="{{.CSRF}}">{{end}}
+        <button type="submit">Giriş yap</button>
+      </form>
+    </section>
+  </main>
+</body>
+</html>
+`))
+
+func (g *csrfGuard) renderMagicConfirm(c *gin.Context, token string) {
+	data := struct{ Token, CSRF string }{Token: token}
+	if s := sessionFromCookie(c); s != "" {
+		data.CSRF = g.tokenFor(s)
+	}
+	h := c.Writer.Header()
+	h.Set("Content-Type", "text/html; charset=utf-8")
+	h.Set("Cache-Control", "no-store")
+	c.Status(http.StatusOK)
+	_ = magicConfirmPage.Execute(c.Writer, data)
+}
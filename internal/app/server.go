@@ -1,35 +1,36 @@
 package app
 
 import (
+	"fmt"
+	"io"
+	"io/fs"
 	"net/http"
+	"net/url"
 	"os"
+	"regexp"
+	"strconv"
 	"strings"
 	"log"
 	"errors"
+	"time"
 	"github.com/gin-gonic/gin"
-	"gorm.io/driver/postgres"
 	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+	"example.com/ecom-go/internal/handlers"
 	"example.com/ecom-go/internal/model"
+	"example.com/ecom-go/internal/money"
 	"example.com/ecom-go/internal/service"
+	"example.com/ecom-go/internal/storage"
+	"example.com/ecom-go/internal/validate"
+	webui "example.com/ecom-go/web"
 )
 
-func NewServer(cfg Config) (*gin.Engine, func(), error) {
-	// --- DB bağlan ---
-	dsn := os.Getenv("DB_DSN")
-	db, err := gorm.Open(postgres.Open(dsn), &gorm.Config{})
-	if err != nil {
-		return nil, nil, err
-	}
-
+// NewServer rotaları kurar. DB ve JWT anahtarları main'de bir kez açılır;
+// bağlantının sahibi çağıran taraftır (kapatmak da ona düşer).
+func NewServer(cfg Config, db *gorm.DB, keys *service.KeySet) (*gin.Engine, error) {
 	// --- Migrations (burayı genişlettik) ---
-	if err := db.AutoMigrate(
-		&model.Product{},
-		&model.User{},
-		&model.CartItem{},
-		&model.Order{},
-		&model.OrderItem{},
-	); err != nil {
-		return nil, nil, err
+	if err := migrate(db); err != nil {
+		return nil, err
 	}
 
 	// --- Gin ---
@@ -37,70 +38,225 @@ func NewServer(cfg Config) (*gin.Engine, func(), error) {
 		gin.SetMode(gin.ReleaseMode)
 	}
 	r := gin.Default()
+	r.Use(securityHeaders(cfg), cors(cfg))
+
+	// frontend binary'de gömülü; WEB_DIR=./web ile diskten canlı düzenleme
+	var webFS fs.FS = webui.FS
+	if cfg.WebDir != "" {
+		webFS = os.DirFS(cfg.WebDir)
+	}
+	web, err := newWebAssets(webFS, cfg.WebDir != "")
+	if err != nil {
+		return nil, err
+	}
 
-	// sayfalar
-	r.GET("/", func(c *gin.Context) { c.File("./web/index.html") })
-	r.GET("/auth", func(c *gin.Context) { c.File("./web/auth.html") })
-	r.GET("/cart", func(c *gin.Context) { c.File("./web/cart.html") })
+	// sayfalar (ETag ile yeniden doğrulanır)
+	r.GET("/", web.Page("index.html"))
+	r.GET("/auth", web.Page("auth.html"))
+	r.GET("/cart", web.Page("cart.html"))
 
-	// statik dosyalar: /assets altında servis et
-	r.Static("/assets", "./web/assets")
+	// statik dosyalar: /assets altında servis et (?v=hash → immutable)
+	r.GET("/assets/*filepath", web.Asset)
+	r.HEAD("/assets/*filepath", web.Asset)
 
-	// cache kapatma (debug)
+	// API cevapları varsayılan olarak kişisel: cache'lenmesin.
+	// Public olanlar (ürünler, JWKS) kendi Cache-Control'ünü yazar.
 	r.Use(func(c *gin.Context) {
-		c.Header("Cache-Control", "no-store")
+		if strings.HasPrefix(c.Request.URL.Path, "/api/") {
+			c.Header("Cache-Control", "no-store")
+		}
 		c.Next()
 	})
 
+	// CSRF: cookie ile gelen POST/DELETE'lerde X-CSRF-Token şart (Bearer hariç)
+	csrf := newCSRFGuard()
+	r.Use(csrf.Middleware())
+	r.GET("/api/auth/csrf", csrf.Handler)
+
 	// --- Servisler ---
 	emailSvc := service.NewEmailService()
-	auth := service.NewAuthService(db)
-	cart := service.NewCartService(db)
-	checkout := service.NewCheckoutService(db, emailSvc)
+	auth := service.NewAuthService(db, keys, emailSvc)
+	oidc := service.NewOIDCService(db, keys)
+	account := service.NewAccountService(db, emailSvc)
+	fx := service.NewCurrencyService(db)
+	products := service.NewProductService(db, fx)
+	categories := service.NewCategoryService(db)
+	variants := service.NewVariantService(db, fx)
+	store, err := storage.NewLocal(cfg.StorageDir)
+	if err != nil {
+		return nil, err
+	}
+	images := service.NewImageService(db, store)
+	tax := service.NewTaxService(db)
+	addresses := service.NewAddressService(db)
+	shipping := service.NewShippingService(db)
+	cart := service.NewCartService(db, fx, tax, shipping)
+	promotions := service.NewPromotionService(db)
+	invoices := service.NewInvoiceService(db, store, cfg.Seller, cfg.InvoicePrefix)
+	checkout := service.NewCheckoutService(db, emailSvc, fx, tax, shipping, invoices)
+	orders := service.NewOrderService(db, emailSvc)
+	returns := service.NewReturnService(db, emailSvc)
 
 	// --- Public rotalar ---
 	r.GET("/health", func(c *gin.Context) { c.JSON(http.StatusOK, gin.H{"ok": true}) })
 
-	// Ürünler
-	r.GET("/api/products", func(c *gin.Context) {
-		var ps []model.Product
-		if err := db.Order("id asc").Find(&ps).Error; err != nil {
+	// Diğer servisler token'ları bununla doğrular (secret paylaşmadan)
+	r.GET("/.well-known/jwks.json", func(c *gin.Context) {
+		c.Header("Cache-Control", "public, max-age=300")
+		c.JSON(http.StatusOK, keys.JWKS())
+	})
+
+	// Ürünler: ?q= (tam metin), min_price/max_price (?currency'nin en küçük biriminde,
+	// listede görünen fiyata göre), sort, limit, cursor.
+	// Kategori sayfası aynı sorguyu alt kategoriler dahil filtreyle çalıştırır.
+	listProducts := func(c *gin.Context, category string) {
+		pq, err := productQuery(c)
+		if err != nil {
+			validate.Fail(c, err)
+			return
+		}
+		pq.Category = category
+
+		// koşullu GET: liste ancak ürünler, varyant fiyatları, kategori ağacı ya da kurlar değişince değişir
+		var parts []string
+		var last time.Time
+		for _, m := range []any{&model.Product{}, &model.ProductVariant{}, &model.Category{}, &model.ExchangeRate{}} {
+			v, err := tableVersion(db, m)
+			if err != nil {
+				c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+				return
+			}
+			parts = append(parts, v.String())
+			if v.Last.After(last) {
+				last = v.Last
+			}
+		}
+		// aynı veri, farklı sorgu → farklı gövde
+		etag := fmt.Sprintf(`W/"p-%s-%s"`, strings.Join(parts, "-"), shortHash([]byte(c.Request.URL.Path+"?"+c.Request.URL.RawQuery)))
+		if cacheable(c, etag, last) {
+			return
+		}
+
+		page, err := products.Search(pq)
+		switch {
+		case errors.Is(err, service.ErrInvalidCursor):
+			validate.Fail(c, validate.Errors{"cursor": "invalid or does not match sort"})
+		case errors.Is(err, service.ErrCategoryNotFound):
+			c.JSON(http.StatusNotFound, gin.H{"error": "category not found"})
+		case errors.Is(err, service.ErrNoExchangeRate):
+			validate.Fail(c, validate.Errors{"currency": err.Error()})
+		case err != nil:
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		default:
+			c.JSON(http.StatusOK, page)
+		}
+	}
+	r.GET("/api/products", func(c *gin.Context) { listProducts(c, "") })
+	r.GET("/api/categories/:slug/products", func(c *gin.Context) { listProducts(c, c.Param("slug")) })
+
+	// Kategori ağacı
+	r.GET("/api/categories", func(c *gin.Context) {
+		cv, err := tableVersion(db, &model.Category{})
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+		if cacheable(c, fmt.Sprintf(`W/"c-%s"`, cv), cv.Last) {
+			return
+		}
+		tree, err := categories.Tree()
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+		c.JSON(http.StatusOK, tree)
+	})
+
+	// Kurlar (admin girer; gösterim ve checkout çevirisi bunlarla yapılır)
+	r.GET("/api/exchange-rates", func(c *gin.Context) {
+		rates, err := fx.Rates()
+		if err != nil {
 			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 			return
 		}
-		c.JSON(http.StatusOK, ps)
+		c.JSON(http.StatusOK, gin.H{"default": fx.Default(), "rates": rates})
 	})
-	r.POST("/api/admin/seed", func(c *gin.Context) {
-		// Order ve Cart tablosu da varsa onları da temizleyelim ve ID’leri sıfırlayalım
-		db.Exec("TRUNCATE TABLE order_items, orders, cart_items, products RESTART IDENTITY CASCADE")
 
-		data := []model.Product{
-			{Name: "Blue T-Shirt", PriceCents: 1999, ImageURL: "https://picsum.photos/seed/blue/600/400"},
-			{Name: "Red Hoodie", PriceCents: 4599, ImageURL: "https://picsum.photos/seed/red/600/400"},
-			{Name: "Sneakers", PriceCents: 6999, ImageURL: "https://picsum.photos/seed/shoes/600/400"},
+	// Ürün detayı: seçenekler + varyantlar (fiyat/stok anlık → cache'lenmez)
+	r.GET("/api/products/:id", func(c *gin.Context) {
+		id, ok := paramID(c)
+		if !ok {
+			return
+		}
+		cur, err := currencyParam(c)
+		if err != nil {
+			validate.Fail(c, err)
+			return
 		}
-		for _, p := range data {
-			db.Create(&p)
+		p, err := variants.Detail(id, cur)
+		if errors.Is(err, service.ErrProductNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+			return
 		}
-		c.JSON(http.StatusOK, gin.H{"ok": true})
+		if errors.Is(err, service.ErrNoExchangeRate) {
+			validate.Fail(c, validate.Errors{"currency": err.Error()})
+			return
+		}
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+		c.JSON(http.StatusOK, p)
+	})
+
+	// Ürün görselleri: id'ler değişmez (yeni yükleme = yeni id) → immutable cache.
+	// Thumbnail ilk istekte üretilip storage'a yazılır.
+	r.GET("/media/images/:id", func(c *gin.Context) {
+		id, ok := paramID(c)
+		if !ok {
+			return
+		}
+		rc, info, err := images.Open(c.Request.Context(), id)
+		serveImage(c, rc, info, fmt.Sprintf(`"img-%d"`, id), err)
+	})
+	r.GET("/media/images/:id/thumb/:w", func(c *gin.Context) {
+		id, ok := paramID(c)
+		if !ok {
+			return
+		}
+		w, _ := strconv.Atoi(c.Param("w"))
+		rc, info, err := images.Thumb(c.Request.Context(), id, w)
+		serveImage(c, rc, info, fmt.Sprintf(`"img-%d-%d"`, id, w), err)
 	})
 
 	// --- Auth ---
 
 	r.POST("/api/auth/register", func(c *gin.Context) {
-		        var req struct {
-        		Email    string `json:"email"`
-        		Password string `json:"password"`
-    		}
-    		if err := c.ShouldBindJSON(&req); err != nil {
-    		    	c.JSON(400, gin.H{"error": "invalid payload"})
-        		return
-    		}
+		var req struct {
+			Email    string `json:"email"`
+			Password string `json:"password"`
+		}
+		if err := validate.Bind(c, &req); err != nil {
+			validate.Fail(c, err)
+			return
+		}
+		req.Email = validate.NormalizeEmail(req.Email)
+		fe := validate.Errors{}
+		validate.Email(fe, "email", req.Email)
+		validate.Required(fe, "password", req.Password)
+		if err := fe.Err(); err != nil {
+			validate.Fail(c, err)
+			return
+		}
 
     		// 🔐 Kullanıcı kaydı dene
 		err := auth.Register(req.Email, req.Password)
 
+		var fe2 validate.Errors
 		switch {
+		case errors.As(err, &fe2):
+			validate.Fail(c, fe2)
+			return
 		case err == nil || errors.Is(err, service.ErrExistsUnverified):
     			c.JSON(http.StatusOK, gin.H{"ok": true})
     			return
@@ -121,29 +277,33 @@ func NewServer(cfg Config) (*gin.Engine, func(), error) {
 			Password string `json:"password"`
 		}
 		var req reqBody
-		if err := c.BindJSON(&req); err != nil || req.Email == "" || req.Password == "" {
-			c.JSON(http.StatusBadRequest, gin.H{"error": "invalid request"})
+		if err := validate.Bind(c, &req); err != nil {
+			validate.Fail(c, err)
+			return
+		}
+		req.Email = validate.NormalizeEmail(req.Email)
+		fe := validate.Errors{}
+		validate.Required(fe, "email", req.Email)
+		validate.Required(fe, "password", req.Password)
+		if err := fe.Err(); err != nil {
+			validate.Fail(c, err)
 			return
 		}
 
-		tok, err := auth.Login(req.Email, req.Password)
+		res, err := auth.Login(req.Email, req.Password)
 		if err != nil {
 			// Not: service.Login zaten Verified=false ise kabul etmiyor.
 			// Dışarıya nedeni yansıtma (invalid creds de).
 			c.JSON(http.StatusUnauthorized, gin.H{"error": "invalid credentials"})
 			return
 		}
+		// 2FA açık → cookie yok; istemci kodu /api/auth/mfa ile gönderir
+		if res.MFARequired {
+			c.JSON(http.StatusOK, gin.H{"mfa_required": true, "mfa_token": res.Token})
+			return
+		}
 
-		// Güvenli cookie
-		http.SetCookie(c.Writer, &http.Cookie{
-			Name:     "session",
-			Value:    tok,
-			Path:     "/",
-			MaxAge:   7 * 24 * 3600,
-			HttpOnly: true,
-			Secure:   true,                   // sadece HTTPS
-			SameSite: http.SameSiteLaxMode,   // form login için ideal
-		})
+		setSessionCookie(c.Writer, res.Token)
 		// JSON cevabı (opsiyonel: token’ı döndürme)
 		c.JSON(http.StatusOK, gin.H{
 			"ok":         true,
@@ -152,17 +312,94 @@ func NewServer(cfg Config) (*gin.Engine, func(), error) {
 		})
 	})
 
+	// 2FA ikinci adım: mfa_token + TOTP (veya kurtarma) kodu → session
+	r.POST("/api/auth/mfa", func(c *gin.Context) {
+		var req struct {
+			MFAToken string `json:"mfa_token"`
+			Code     string `json:"code"`
+		}
+		if err := validate.Bind(c, &req); err != nil {
+			validate.Fail(c, err)
+			return
+		}
+		fe := validate.Errors{}
+		validate.Required(fe, "mfa_token", req.MFAToken)
+		validate.Required(fe, "code", req.Code)
+		if err := fe.Err(); err != nil {
+			validate.Fail(c, err)
+			return
+		}
+		tok, err := auth.VerifyMFA(req.MFAToken, req.Code)
+		if errors.Is(err, service.ErrMFALocked) {
+			c.JSON(http.StatusTooManyRequests, gin.H{"error": err.Error()})
+			return
+		}
+		if err != nil {
+			c.JSON(http.StatusUnauthorized, gin.H{"error": "invalid code"})
+			return
+		}
+		setSessionCookie(c.Writer, tok)
+		c.JSON(http.StatusOK, gin.H{"ok": true})
+	})
+
+	// --- OIDC / sosyal giriş (authorization code + PKCE) ---
+	r.GET("/api/auth/oidc/providers", func(c *gin.Context) {
+		c.JSON(http.StatusOK, gin.H{"providers": oidc.Providers()})
+	})
+
+	r.GET("/api/auth/oidc/:provider/start", func(c *gin.Context) {
+		authURL, flow, err := oidc.Start(c.Param("provider"), c.Query("redirect"))
+		if err != nil {
+			if errors.Is(err, service.ErrUnknownProvider) {
+				c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+				return
+			}
+			log.Printf("oidc start: %v", err)
+			c.JSON(http.StatusBadGateway, gin.H{"error": "provider unavailable"})
+			return
+		}
+		// state/nonce/verifier imzalı cookie'de; callback top-level GET olduğu için Lax yeterli
+		http.SetCookie(c.Writer, &http.Cookie{
+			Name:     "oidc_flow",
+			Value:    flow,
+			Path:     "/api/auth/oidc/",
+			MaxAge:   600,
+			HttpOnly: true,
+			Secure:   true,
+			SameSite: http.SameSiteLaxMode,
+		})
+		c.Redirect(http.StatusFound, authURL)
+	})
+
+	r.GET("/api/auth/oidc/:provider/callback", func(c *gin.Context) {
+		flow, _ := c.Cookie("oidc_flow")
+		http.SetCookie(c.Writer, &http.Cookie{Name: "oidc_flow", Path: "/api/auth/oidc/", MaxAge: -1, HttpOnly: true, Secure: true})
+		if e := c.Query("error"); e != "" {
+			c.Redirect(http.StatusFound, "/auth?error="+url.QueryEscape(e))
+			return
+		}
+		res, redirect, err := oidc.Callback(c.Param("provider"), c.Query("code"), c.Query("state"), flow)
+		if err != nil {
+			log.Printf("oidc callback: %v", err)
+			reason := "oidc"
+			if errors.Is(err, service.ErrOIDCEmailUnverified) {
+				reason = "email_unverified"
+			}
+			c.Redirect(http.StatusFound, "/auth?error="+reason)
+			return
+		}
+		// 2FA: token fragment'ta kalır (sunucu loglarına düşmez), auth.js kodu ister
+		if res.MFARequired {
+			c.Redirect(http.StatusFound, "/auth?redirect="+url.QueryEscape(redirect)+"#mfa="+res.Token)
+			return
+		}
+		setSessionCookie(c.Writer, res.Token)
+		c.Redirect(http.StatusFound, redirect)
+	})
+
 	r.POST("/api/auth/logout", func(c *gin.Context) {
-    		http.SetCookie(c.Writer, &http.Cookie{
-    	    Name:     "session",
- 	       Value:    "",
-       		 Path:     "/",
-      		  MaxAge:   -1,
-     		   HttpOnly: true,
-       			Secure:   true,
-       			 SameSite: http.SameSiteLaxMode,
-    		})
-    		c.JSON(http.StatusOK, gin.H{"ok": true})
+		clearSessionCookie(c.Writer)
+		c.JSON(http.StatusOK, gin.H{"ok": true})
 	})
 
 	// KOD DOĞRULAMA
@@ -172,10 +409,18 @@ func NewServer(cfg Config) (*gin.Engine, func(), error) {
 	        Email string `json:"email"`
         	Code  string `json:"code"`
     	    }
-  	  if err := c.BindJSON(&req); err != nil {
-        c.JSON(http.StatusBadRequest, gin.H{"error": "bad json"})
-        return
-  	  }
+		if err := validate.Bind(c, &req); err != nil {
+			validate.Fail(c, err)
+			return
+		}
+		req.Email = validate.NormalizeEmail(req.Email)
+		fe := validate.Errors{}
+		validate.Email(fe, "email", req.Email)
+		validate.Code(fe, "code", req.Code)
+		if err := fe.Err(); err != nil {
+			validate.Fail(c, err)
+			return
+		}
  	   if err := auth.VerifyCode(req.Email, req.Code); err != nil {
         // istersen sabitle: "invalid or expired code"
         	c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
@@ -186,14 +431,68 @@ func NewServer(cfg Config) (*gin.Engine, func(), error) {
 
 	r.POST("/api/auth/resend-code", func(c *gin.Context) {
     		var req struct{ Email string `json:"email"` }
-    		if err := c.BindJSON(&req); err != nil || req.Email == "" {
-    		    c.JSON(http.StatusBadRequest, gin.H{"error": "invalid request"})
-    			return
-    		}
+		if err := validate.Bind(c, &req); err != nil {
+			validate.Fail(c, err)
+			return
+		}
+		req.Email = validate.NormalizeEmail(req.Email)
+		fe := validate.Errors{}
+		validate.Email(fe, "email", req.Email)
+		if err := fe.Err(); err != nil {
+			validate.Fail(c, err)
+			return
+		}
   	  _ = auth.ResendCode(req.Email) // enumeration önleme
  	   c.JSON(http.StatusOK, gin.H{"ok": true})
 		})
 
+	// --- Şifresiz giriş (magic link) ---
+	r.POST("/api/auth/magic-link", func(c *gin.Context) {
+		var req struct{ Email string `json:"email"` }
+		if err := validate.Bind(c, &req); err != nil {
+			validate.Fail(c, err)
+			return
+		}
+		req.Email = validate.NormalizeEmail(req.Email)
+		fe := validate.Errors{}
+		validate.Email(fe, "email", req.Email)
+		if err := fe.Err(); err != nil {
+			validate.Fail(c, err)
+			return
+		}
+		if err := auth.RequestMagicLink(req.Email); err != nil {
+			log.Printf("magic link: %v", err)
+		}
+		c.JSON(http.StatusOK, gin.H{"ok": true}) // enumeration önleme
+	})
+
+	// maildeki link sadece onay sayfasını açar; token POST'ta tüketilir
+	r.GET("/api/auth/magic", func(c *gin.Context) {
+		tok := c.Query("token")
+		if tok == "" {
+			c.Redirect(http.StatusFound, "/auth?error=link_expired")
+			return
+		}
+		csrf.renderMagicConfirm(c, tok)
+	})
+
+	r.POST("/api/auth/magic", func(c *gin.Context) {
+		res, err := auth.ConsumeMagicLink(c.PostForm("token"))
+		if err != nil {
+			if !errors.Is(err, service.ErrInvalidMagicLink) {
+				log.Printf("magic consume: %v", err)
+			}
+			c.Redirect(http.StatusSeeOther, "/auth?error=link_expired")
+			return
+		}
+		if res.MFARequired {
+			c.Redirect(http.StatusSeeOther, "/auth#mfa="+res.Token)
+			return
+		}
+		setSessionCookie(c.Writer, res.Token)
+		c.Redirect(http.StatusSeeOther, "/")
+	})
+
 	r.GET("/api/auth/verify", func(c *gin.Context) { 
     		t := c.Query("token")
    		 if t == "" {
@@ -208,6 +507,15 @@ func NewServer(cfg Config) (*gin.Engine, func(), error) {
    	 c.Redirect(http.StatusFound, "/")
 })
 
+	// handlers.AuthHTTP (net/http imzalı) rotaları; servisler yukarıdakilerle aynı örnekler
+	authHTTP := handlers.NewAuthHTTP(auth, account)
+	r.POST("/api/register", gin.WrapF(authHTTP.Register))
+	r.POST("/api/verify", gin.WrapF(authHTTP.Verify))
+	r.POST("/api/login", gin.WrapF(authHTTP.Login)) // Login içinde HttpOnly cookie set edilir
+	r.POST("/api/logout", gin.WrapF(authHTTP.Logout))
+	r.GET("/api/me", gin.WrapF(authHTTP.Me))
+	r.POST("/api/resend", gin.WrapF(authHTTP.Resend))
+	r.GET("/api/ping", func(c *gin.Context) { c.String(200, "ok") })
 
 	// --- Auth middleware (userID'yi context'e koyar) ---
 	authMW := func(c *gin.Context) {
@@ -224,68 +532,1778 @@ func NewServer(cfg Config) (*gin.Engine, func(), error) {
 			c.AbortWithStatusJSON(401, gin.H{"error": "login required"})
 			return
 		}
-		uid, err := auth.ParseToken(tok)
+		uid, authAt, err := auth.ParseSession(tok)
 		if err != nil {
 			c.AbortWithStatusJSON(401, gin.H{"error": "invalid session"})
 			return
 		}
 		c.Set("userID", uid)
+		c.Set("authAt", authAt) // şifresiz hesapta hassas işlemler için (reauth)
 		c.Next()
 	}
 
-	// --- Sepet + Checkout ---
-	r.POST("/api/cart/add", authMW, func(c *gin.Context) {
+	// authMW'den sonra: users.is_admin şart
+	adminMW := func(c *gin.Context) {
+		var u model.User
+		if err := db.Select("id", "is_admin").First(&u, c.GetUint("userID")).Error; err != nil || !u.IsAdmin {
+			c.AbortWithStatusJSON(http.StatusForbidden, gin.H{"error": "admin only"})
+			return
+		}
+		c.Next()
+	}
+
+	// --- 2FA (TOTP) yönetimi ---
+	r.POST("/api/auth/totp/enroll", authMW, func(c *gin.Context) {
 		var req struct {
-			ProductID uint `json:"product_id"`
-			Qty       int  `json:"qty"`
+			Password string `json:"password"`
 		}
-		if err := c.BindJSON(&req); err != nil {
-			c.JSON(400, gin.H{"error": "bad json"})
-			return
+		// şifresiz hesap gövdesiz gönderebilir (yeni girişle)
+		if c.Request.ContentLength != 0 {
+			if err := validate.Bind(c, &req); err != nil {
+				validate.Fail(c, err)
+				return
+			}
 		}
-		uid := c.GetUint("userID")
-		if err := cart.Add(uid, req.ProductID, req.Qty); err != nil {
-			c.JSON(400, gin.H{"error": err.Error()})
+		secret, uri, err := auth.EnrollTOTP(c.GetUint("userID"), req.Password, c.GetTime("authAt"))
+		if err != nil {
+			mfaFail(c, err)
 			return
 		}
-		c.JSON(200, gin.H{"ok": true})
+		c.JSON(http.StatusOK, gin.H{"secret": secret, "provisioning_uri": uri})
 	})
 
-	r.GET("/api/cart", authMW, func(c *gin.Context) {
-		uid := c.GetUint("userID")
-		items, err := cart.Get(uid)
+	r.POST("/api/auth/totp/confirm", authMW, func(c *gin.Context) {
+		var req struct {
+			Code string `json:"code"`
+		}
+		if err := validate.Bind(c, &req); err != nil {
+			validate.Fail(c, err)
+			return
+		}
+		fe := validate.Errors{}
+		validate.Code(fe, "code", req.Code)
+		if err := fe.Err(); err != nil {
+			validate.Fail(c, err)
+			return
+		}
+		codes, err := auth.ConfirmTOTP(c.GetUint("userID"), req.Code)
 		if err != nil {
-			c.JSON(500, gin.H{"error": err.Error()})
+			mfaFail(c, err)
 			return
 		}
-		c.JSON(200, items)
+		// kurtarma kodları sadece bu cevapta düz metin döner
+		c.JSON(http.StatusOK, gin.H{"ok": true, "recovery_codes": codes})
 	})
 
-	r.POST("/api/checkout", authMW, func(c *gin.Context) {
+	r.POST("/api/auth/totp/disable", authMW, func(c *gin.Context) {
+		var req struct {
+			Password string `json:"password"`
+			Code     string `json:"code"`
+		}
+		if err := validate.Bind(c, &req); err != nil {
+			validate.Fail(c, err)
+			return
+		}
+		fe := validate.Errors{}
+		validate.Required(fe, "code", req.Code)
+		if err := fe.Err(); err != nil {
+			validate.Fail(c, err)
+			return
+		}
+		if err := auth.DisableTOTP(c.GetUint("userID"), req.Password, req.Code, c.GetTime("authAt")); err != nil {
+			mfaFail(c, err)
+			return
+		}
+		c.JSON(http.StatusOK, gin.H{"ok": true})
+	})
+
+	// --- Hesap yönetimi (GET /api/me cmd/api'de handlers.AuthHTTP.Me) ---
+	r.POST("/api/me/password", authMW, func(c *gin.Context) {
+		var req struct {
+			CurrentPassword string `json:"current_password"`
+			NewPassword     string `json:"new_password"`
+		}
+		if err := validate.Bind(c, &req); err != nil {
+			validate.Fail(c, err)
+			return
+		}
+		fe := validate.Errors{}
+		validate.Required(fe, "new_password", req.NewPassword)
+		if err := fe.Err(); err != nil {
+			validate.Fail(c, err)
+			return
+		}
 		uid := c.GetUint("userID")
-		order, err := checkout.Checkout(uid)
+		if err := account.ChangePassword(uid, req.CurrentPassword, req.NewPassword, c.GetTime("authAt")); err != nil {
+			accountFail(c, err)
+			return
+		}
+		// diğer oturumlar düştü; bu istemci yeni cookie ile devam eder (Bearer istemci yeniden giriş yapar)
+		tok, err := auth.NewSession(uid)
 		if err != nil {
-			c.JSON(400, gin.H{"error": err.Error()})
+			accountFail(c, err)
 			return
 		}
-		c.JSON(200, order)
+		setSessionCookie(c.Writer, tok)
+		c.JSON(http.StatusOK, gin.H{"ok": true})
 	})
 
-	// --- cleanup ---
-	cleanup := func() {
-		if s, err := db.DB(); err == nil {
-			_ = s.Close()
+	r.POST("/api/me/email", authMW, func(c *gin.Context) {
+		var req struct {
+			Email    string `json:"email"`
+			Password string `json:"password"`
 		}
-	}
-	// (opsiyonel SPA fallback)
-	r.NoRoute(func(c *gin.Context) {
-		p := c.Request.URL.Path
-		if strings.HasPrefix(p, "/api/") || strings.HasPrefix(p, "/assets/") {
-			c.JSON(404, gin.H{"error": "not found"})
+		if err := validate.Bind(c, &req); err != nil {
+			validate.Fail(c, err)
+			return
+		}
+		req.Email = validate.NormalizeEmail(req.Email)
+		fe := validate.Errors{}
+		validate.Email(fe, "email", req.Email)
+		if err := fe.Err(); err != nil {
+			validate.Fail(c, err)
+			return
+		}
+		// password şifresiz (OIDC / magic link) hesapta boş kalır; servis yeni giriş arar
+		if err := account.RequestEmailChange(c.GetUint("userID"), req.Email, req.Password, c.GetTime("authAt")); err != nil {
+			accountFail(c, err)
+			return
+		}
+		// kod yeni adrese gitti; /api/me/email/confirm ile onaylanır
+		c.JSON(http.StatusOK, gin.H{"ok": true})
+	})
+
+	r.POST("/api/me/email/confirm", authMW, func(c *gin.Context) {
+		var req struct {
+			Code string `json:"code"`
+		}
+		if err := validate.Bind(c, &req); err != nil {
+			validate.Fail(c, err)
+			return
+		}
+		fe := validate.Errors{}
+		validate.Code(fe, "code", req.Code)
+		if err := fe.Err(); err != nil {
+			validate.Fail(c, err)
+			return
+		}
+		if err := account.ConfirmEmailChange(c.GetUint("userID"), req.Code); err != nil {
+			accountFail(c, err)
+			return
+		}
+		c.JSON(http.StatusOK, gin.H{"ok": true})
+	})
+
+	r.DELETE("/api/me", authMW, func(c *gin.Context) {
+		var req struct {
+			Password string `json:"password"`
+		}
+		// şifresiz hesap gövdesiz DELETE gönderebilir
+		if c.Request.ContentLength != 0 {
+			if err := validate.Bind(c, &req); err != nil {
+				validate.Fail(c, err)
+				return
+			}
+		}
+		if err := account.Delete(c.GetUint("userID"), req.Password, c.GetTime("authAt")); err != nil {
+			accountFail(c, err)
 			return
 		}
-		c.File("./web/index.html")
+		clearSessionCookie(c.Writer)
+		c.JSON(http.StatusOK, gin.H{"ok": true})
 	})
 
-	return r, cleanup, nil
+	// --- Adres defteri ---
+	bindAddress := func(c *gin.Context) (service.AddressInput, bool) {
+		var req struct {
+			Label      string `json:"label"`
+			FullName   string `json:"full_name"`
+			Phone      string `json:"phone"`
+			Line1      string `json:"line1"`
+			Line2      string `json:"line2"`
+			District   string `json:"district"`
+			City       string `json:"city"`
+			PostalCode string `json:"postal_code"`
+			Country    string `json:"country"`
+			IsDefault  bool   `json:"is_default"`
+		}
+		if err := validate.Bind(c, &req); err != nil {
+			validate.Fail(c, err)
+			return service.AddressInput{}, false
+		}
+		in := service.AddressInput{
+			Label: strings.TrimSpace(req.Label),
+			PostalAddress: model.PostalAddress{
+				FullName: strings.TrimSpace(req.FullName), Phone: strings.TrimSpace(req.Phone),
+				Line1: strings.TrimSpace(req.Line1), Line2: strings.TrimSpace(req.Line2),
+				District: strings.TrimSpace(req.District), City: strings.TrimSpace(req.City),
+				PostalCode: strings.TrimSpace(req.PostalCode), Country: strings.ToUpper(strings.TrimSpace(req.Country)),
+			},
+			IsDefault: req.IsDefault,
+		}
+		fe := validate.Errors{}
+		validate.Required(fe, "full_name", in.FullName)
+		validate.Required(fe, "phone", in.Phone)
+		validate.Required(fe, "line1", in.Line1)
+		validate.Required(fe, "city", in.City)
+		if !service.ValidJurisdiction(in.Country) {
+			fe.Add("country", "must be a two-letter country code")
+		}
+		if in.Phone != "" && strings.Trim(in.Phone, "+0123456789 ()-") != "" {
+			fe.Add("phone", "must contain only digits, spaces and + ( ) -")
+		}
+		for field, v := range map[string]struct {
+			s   string
+			max int
+		}{
+			"label": {in.Label, 50}, "full_name": {in.FullName, 100}, "phone": {in.Phone, 32},
+			"line1": {in.Line1, 200}, "line2": {in.Line2, 200}, "district": {in.District, 100},
+			"city": {in.City, 100}, "postal_code": {in.PostalCode, 16},
+		} {
+			if len(v.s) > v.max {
+				fe.Add(field, fmt.Sprintf("must be at most %d characters", v.max))
+			}
+		}
+		if err := fe.Err(); err != nil {
+			validate.Fail(c, err)
+			return service.AddressInput{}, false
+		}
+		return in, true
+	}
+	addressFail := func(c *gin.Context, err error) {
+		switch {
+		case errors.Is(err, service.ErrAddressNotFound):
+			c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		case errors.Is(err, service.ErrAddressLimit):
+			c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+		default:
+			log.Printf("address: %v", err)
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "internal error"})
+		}
+	}
+	r.GET("/api/addresses", authMW, func(c *gin.Context) {
+		list, err := addresses.List(c.GetUint("userID"))
+		if err != nil {
+			addressFail(c, err)
+			return
+		}
+		c.JSON(http.StatusOK, list)
+	})
+	r.POST("/api/addresses", authMW, func(c *gin.Context) {
+		in, ok := bindAddress(c)
+		if !ok {
+			return
+		}
+		a, err := addresses.Create(c.GetUint("userID"), in)
+		if err != nil {
+			addressFail(c, err)
+			return
+		}
+		c.JSON(http.StatusCreated, a)
+	})
+	r.PUT("/api/addresses/:id", authMW, func(c *gin.Context) {
+		id, ok := paramID(c)
+		if !ok {
+			return
+		}
+		in, ok := bindAddress(c)
+		if !ok {
+			return
+		}
+		a, err := addresses.Update(c.GetUint("userID"), id, in)
+		if err != nil {
+			addressFail(c, err)
+			return
+		}
+		c.JSON(http.StatusOK, a)
+	})
+	r.DELETE("/api/addresses/:id", authMW, func(c *gin.Context) {
+		id, ok := paramID(c)
+		if !ok {
+			return
+		}
+		if err := addresses.Delete(c.GetUint("userID"), id); err != nil {
+			addressFail(c, err)
+			return
+		}
+		c.JSON(http.StatusOK, gin.H{"ok": true})
+	})
+	r.POST("/api/addresses/:id/default", authMW, func(c *gin.Context) {
+		id, ok := paramID(c)
+		if !ok {
+			return
+		}
+		if err := addresses.SetDefault(c.GetUint("userID"), id); err != nil {
+			addressFail(c, err)
+			return
+		}
+		c.JSON(http.StatusOK, gin.H{"ok": true})
+	})
+
+	// Kargo seçenekleri: ?country=TR o ülkeye gönderenler
+	r.GET("/api/shipping-methods", func(c *gin.Context) {
+		country := strings.ToUpper(c.Query("country"))
+		if country != "" && !service.ValidJurisdiction(country) {
+			validate.Fail(c, validate.Errors{"country": "must be a two-letter country code"})
+			return
+		}
+		methods, err := shipping.Methods(country)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+		c.JSON(http.StatusOK, methods)
+	})
+
+	// --- Sepet + Checkout ---
+	r.POST("/api/cart/add", authMW, func(c *gin.Context) {
+		var req struct {
+			ProductID uint `json:"product_id"`
+			VariantID uint `json:"variant_id"` // varyantı olan ürünlerde zorunlu
+			Qty       int  `json:"qty"`
+		}
+		if err := validate.Bind(c, &req); err != nil {
+			validate.Fail(c, err)
+			return
+		}
+		fe := validate.Errors{}
+		validate.PositiveID(fe, "product_id", req.ProductID)
+		validate.Qty(fe, "qty", req.Qty)
+		if err := fe.Err(); err != nil {
+			validate.Fail(c, err)
+			return
+		}
+		uid := c.GetUint("userID")
+		if err := cart.Add(uid, req.ProductID, req.VariantID, req.Qty); err != nil {
+			switch {
+			case errors.Is(err, service.ErrProductNotFound):
+				validate.Fail(c, validate.Errors{"product_id": "product not found"})
+			case errors.Is(err, service.ErrVariantNotFound), errors.Is(err, service.ErrVariantRequired):
+				validate.Fail(c, validate.Errors{"variant_id": err.Error()})
+			case errors.Is(err, service.ErrOutOfStock):
+				validate.Fail(c, validate.Errors{"qty": err.Error()})
+			case errors.Is(err, service.ErrQtyLimit):
+				validate.Fail(c, validate.Errors{"qty": err.Error()})
+			default:
+				c.JSON(400, gin.H{"error": err.Error()})
+			}
+			return
+		}
+		c.JSON(200, gin.H{"ok": true})
+	})
+
+	// Kupon: sepete uygulanabiliyorsa kaydedilir, indirimli toplamlar döner
+	r.POST("/api/cart/coupon", authMW, func(c *gin.Context) {
+		var req struct {
+			Code string `json:"code"`
+		}
+		if err := validate.Bind(c, &req); err != nil {
+			validate.Fail(c, err)
+			return
+		}
+		in, err := summaryInput(c)
+		if err != nil {
+			validate.Fail(c, err)
+			return
+		}
+		code := service.NormalizeCoupon(req.Code)
+		if !couponRe.MatchString(code) {
+			validate.Fail(c, validate.Errors{"code": "is not a valid coupon code"})
+			return
+		}
+		sum, err := cart.ApplyCoupon(c.GetUint("userID"), code, in)
+		if err != nil {
+			quoteFail(c, err)
+			return
+		}
+		c.JSON(http.StatusOK, sum)
+	})
+	r.DELETE("/api/cart/coupon", authMW, func(c *gin.Context) {
+		if err := cart.RemoveCoupon(c.GetUint("userID")); err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+		c.JSON(http.StatusOK, gin.H{"ok": true})
+	})
+
+	// Sepet özeti: satırlar, indirimler, vergi, kargo tahmini, toplam ve uyarılar.
+	// ?currency=&address_id=&shipping_method= checkout'a gidecek seçimler (opsiyonel).
+	r.GET("/api/cart/summary", authMW, func(c *gin.Context) {
+		in, err := summaryInput(c)
+		if err != nil {
+			validate.Fail(c, err)
+			return
+		}
+		sum, err := cart.Summary(c.GetUint("userID"), in)
+		if err != nil {
+			quoteFail(c, err)
+			return
+		}
+		c.JSON(http.StatusOK, sum)
+	})
+
+	r.GET("/api/cart", authMW, func(c *gin.Context) {
+		uid := c.GetUint("userID")
+		cur, err := currencyParam(c)
+		if err != nil {
+			validate.Fail(c, err)
+			return
+		}
+		items, err := cart.Get(uid, cur)
+		if errors.Is(err, service.ErrNoExchangeRate) {
+			validate.Fail(c, validate.Errors{"currency": err.Error()})
+			return
+		}
+		if err != nil {
+			c.JSON(500, gin.H{"error": err.Error()})
+			return
+		}
+		c.JSON(200, items)
+	})
+
+	// {"shipping_method":"standard","address_id":3,"currency":"USD","cart_version":"..."}
+	// address_id yoksa varsayılan adres, currency yoksa varsayılan para birimi
+	r.POST("/api/checkout", authMW, func(c *gin.Context) {
+		uid := c.GetUint("userID")
+		var req struct {
+			Currency       string `json:"currency"`
+			AddressID      uint   `json:"address_id"`
+			ShippingMethod string `json:"shipping_method"`
+			CartVersion    string `json:"cart_version"`
+		}
+		if err := validate.Bind(c, &req); err != nil {
+			validate.Fail(c, err)
+			return
+		}
+		fe := validate.Errors{}
+		in := service.CheckoutInput{AddressID: req.AddressID, ShippingMethod: strings.TrimSpace(req.ShippingMethod), CartVersion: req.CartVersion}
+		validate.Required(fe, "shipping_method", in.ShippingMethod)
+		if req.Currency != "" {
+			var err error
+			if in.Currency, err = money.ParseCurrency(req.Currency); err != nil {
+				fe.Add("currency", "unsupported currency")
+			}
+		}
+		if err := fe.Err(); err != nil {
+			validate.Fail(c, err)
+			return
+		}
+		order, err := checkout.Checkout(uid, in)
+		switch {
+		case errors.Is(err, service.ErrNoExchangeRate):
+			validate.Fail(c, validate.Errors{"currency": err.Error()})
+			return
+		case errors.Is(err, service.ErrAddressNotFound), errors.Is(err, service.ErrAddressRequired):
+			validate.Fail(c, validate.Errors{"address_id": err.Error()})
+			return
+		case errors.Is(err, service.ErrShippingMethodNotFound):
+			validate.Fail(c, validate.Errors{"shipping_method": err.Error()})
+			return
+		case errors.Is(err, service.ErrCouponInvalid), errors.Is(err, service.ErrCouponExhausted):
+			validate.Fail(c, validate.Errors{"coupon": err.Error()})
+			return
+		}
+		if errors.Is(err, service.ErrOutOfStock) {
+			c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+			return
+		}
+		// istemci değişiklikleri gösterip cart_version ile tekrar gönderir
+		var pc *service.PricesChangedError
+		if errors.As(err, &pc) {
+			c.JSON(http.StatusConflict, gin.H{"error": service.ErrPricesChanged.Error(), "cart_version": pc.Version, "changes": pc.Changes})
+			return
+		}
+		if errors.Is(err, service.ErrNoTaxRate) {
+			// yapılandırma eksiği: admin oran tanımlamalı
+			log.Printf("checkout: %v", err)
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "internal error"})
+			return
+		}
+		if err != nil {
+			c.JSON(400, gin.H{"error": err.Error()})
+			return
+		}
+		c.JSON(200, order)
+	})
+
+	// Siparişlerim; iptal sadece kargolanmamış siparişte (tamamı iade, stok geri)
+	r.GET("/api/orders", authMW, func(c *gin.Context) {
+		list, err := orders.List(c.GetUint("userID"))
+		if err != nil {
+			orderFail(c, err)
+			return
+		}
+		c.JSON(http.StatusOK, list)
+	})
+	r.GET("/api/orders/:id", authMW, func(c *gin.Context) {
+		id, ok := paramID(c)
+		if !ok {
+			return
+		}
+		o, err := orders.Get(c.GetUint("userID"), id)
+		if err != nil {
+			orderFail(c, err)
+			return
+		}
+		c.JSON(http.StatusOK, o)
+	})
+	r.POST("/api/orders/:id/cancel", authMW, func(c *gin.Context) {
+		id, ok := paramID(c)
+		if !ok {
+			return
+		}
+		o, err := orders.Cancel(c.GetUint("userID"), id)
+		if err != nil {
+			orderFail(c, err)
+			return
+		}
+		c.JSON(http.StatusOK, o)
+	})
+
+	// Fatura PDF'i (sadece sipariş sahibine); ilk istekte üretilip depolanır
+	r.GET("/api/orders/:id/invoice.pdf", authMW, func(c *gin.Context) {
+		id, ok := paramID(c)
+		if !ok {
+			return
+		}
+		b, inv, err := invoices.PDF(c.Request.Context(), c.GetUint("userID"), id)
+		if err != nil {
+			orderFail(c, err)
+			return
+		}
+		c.Header("Content-Disposition", fmt.Sprintf(`inline; filename="%s.pdf"`, inv.Number))
+		c.Header("Cache-Control", "private, no-store")
+		c.Data(http.StatusOK, "application/pdf", b)
+	})
+
+	// İade talepleri (RMA): kargolanmış siparişte satır + adet + sebep
+	// {"items":[{"order_item_id":7,"qty":1}],"reason":"beden olmadı"}
+	r.POST("/api/orders/:id/returns", authMW, func(c *gin.Context) {
+		id, ok := paramID(c)
+		if !ok {
+			return
+		}
+		var req struct {
+			Items []struct {
+				OrderItemID uint `json:"order_item_id"`
+				Qty         int  `json:"qty"`
+			} `json:"items"`
+			Reason string `json:"reason"`
+		}
+		if err := validate.Bind(c, &req); err != nil {
+			validate.Fail(c, err)
+			return
+		}
+		fe := validate.Errors{}
+		in := service.ReturnInput{Reason: strings.TrimSpace(req.Reason)}
+		validate.Required(fe, "reason", in.Reason)
+		if len(in.Reason) > 500 {
+			fe.Add("reason", "must be at most 500 characters")
+		}
+		if len(req.Items) == 0 {
+			fe.Add("items", "choose at least one item")
+		}
+		for _, it := range req.Items {
+			validate.PositiveID(fe, "items", it.OrderItemID)
+			if it.Qty <= 0 {
+				fe.Add("items", "qty must be > 0")
+			}
+			in.Items = append(in.Items, service.RefundLine{OrderItemID: it.OrderItemID, Qty: it.Qty})
+		}
+		if err := fe.Err(); err != nil {
+			validate.Fail(c, err)
+			return
+		}
+		ret, err := returns.Open(c.GetUint("userID"), id, in)
+		if err != nil {
+			orderFail(c, err)
+			return
+		}
+		c.JSON(http.StatusCreated, ret)
+	})
+	r.GET("/api/returns", authMW, func(c *gin.Context) {
+		list, err := returns.List(c.GetUint("userID"))
+		if err != nil {
+			orderFail(c, err)
+			return
+		}
+		c.JSON(http.StatusOK, list)
+	})
+	r.GET("/api/returns/:id", authMW, func(c *gin.Context) {
+		id, ok := paramID(c)
+		if !ok {
+			return
+		}
+		ret, err := returns.Get(c.GetUint("userID"), id)
+		if err != nil {
+			orderFail(c, err)
+			return
+		}
+		c.JSON(http.StatusOK, ret)
+	})
+
+	// --- Admin: kategori ağacı ---
+	admin := r.Group("/api/admin", authMW, adminMW)
+
+	// demo verisi: tabloları boşaltır; sadece dev'de ve ENABLE_SEED ile açılır
+	if cfg.Env == "dev" && cfg.EnableSeed {
+		admin.POST("/seed", func(c *gin.Context) {
+			// Order ve Cart tablosu da varsa onları da temizleyelim ve ID’leri sıfırlayalım.
+			// order_id'yi FK'siz tutan tablolar da: yoksa sıfırlanan sipariş ID'leri eski
+			// faturalara/iadelere çarpar (invoices.order_id unique → checkout düşer)
+			db.Exec("TRUNCATE TABLE order_items, orders, cart_items, products, invoices, invoice_counters, " +
+				"return_requests, promotion_redemptions RESTART IDENTITY CASCADE")
+
+			data := []model.Product{
+				{Name: "Blue T-Shirt", Description: "Pamuklu mavi tişört, cotton crew neck", PriceCents: 1999, WeightGrams: 200, ImageURL: "https://picsum.photos/seed/blue/600/400"},
+				{Name: "Red Hoodie", Description: "Kapüşonlu kırmızı sweatshirt, warm fleece", PriceCents: 4599, WeightGrams: 600, ImageURL: "https://picsum.photos/seed/red/600/400"},
+				{Name: "Sneakers", Description: "Günlük spor ayakkabı, running shoes", PriceCents: 6999, WeightGrams: 900, ImageURL: "https://picsum.photos/seed/shoes/600/400"},
+			}
+			for _, p := range data {
+				db.Create(&p)
+			}
+
+			// varyantlı örnek: beden × renk
+			tee := model.Product{Name: "Basic T-Shirt", Description: "Pamuklu basic tişört, beden ve renk seçenekli", PriceCents: 2499, WeightGrams: 200, ImageURL: "/assets/img/tshirt-black.jpg"}
+			db.Create(&tee)
+			size, _ := variants.AddOption(tee.ID, "Beden", []string{"M", "L"})
+			color, _ := variants.AddOption(tee.ID, "Renk", []string{"Siyah"})
+			if len(size.Values) == 2 && len(color.Values) == 1 {
+				l := int64(2699)
+				_, _ = variants.CreateVariant(tee.ID, service.VariantInput{SKU: "TEE-M-BLK", ImageURL: "/assets/img/tshirt-m-black.jpg", Stock: 10,
+					ValueIDs: []uint{size.Values[0].ID, color.Values[0].ID}})
+				_, _ = variants.CreateVariant(tee.ID, service.VariantInput{SKU: "TEE-L-BLK", PriceCents: &l, Stock: 5,
+					ValueIDs: []uint{size.Values[1].ID, color.Values[0].ID}})
+			}
+
+			// kargo: 500 ₺ üzeri ücretsiz standart + ağırlığa göre hızlı (varsa dokunma)
+			for _, m := range []model.ShippingMethod{
+				{Code: "standard", Name: "Standart Kargo", Kind: model.ShippingFreeOver, Currency: "TRY", FeeCents: 4990, FreeOverCents: 50000, TaxClass: "standard", Active: true},
+				{Code: "express", Name: "Hızlı Kargo", Kind: model.ShippingWeight, Currency: "TRY", FeeCents: 7990, PerKgCents: 1500, Countries: "TR", TaxClass: "standard", Active: true, Position: 1},
+			} {
+				db.Clauses(clause.OnConflict{DoNothing: true}).Create(&m)
+			}
+			c.JSON(http.StatusOK, gin.H{"ok": true})
+		})
+	}
+
+	type categoryReq struct {
+		Name     string `json:"name"`
+		Slug     string `json:"slug"`
+		ParentID *uint  `json:"parent_id"`
+		Position int    `json:"position"`
+	}
+	bindCategory := func(c *gin.Context) (service.CategoryInput, bool) {
+		var req categoryReq
+		if err := validate.Bind(c, &req); err != nil {
+			validate.Fail(c, err)
+			return service.CategoryInput{}, false
+		}
+		req.Name = strings.TrimSpace(req.Name)
+		fe := validate.Errors{}
+		validate.Required(fe, "name", req.Name)
+		if len(req.Name) > 100 {
+			fe.Add("name", "must be at most 100 characters")
+		}
+		if req.Slug != "" && !service.ValidSlug(req.Slug) {
+			fe.Add("slug", "use lowercase letters, digits and single dashes")
+		}
+		if req.ParentID != nil {
+			validate.PositiveID(fe, "parent_id", *req.ParentID)
+		}
+		if req.Position < 0 {
+			fe.Add("position", "must be >= 0")
+		}
+		if err := fe.Err(); err != nil {
+			validate.Fail(c, err)
+			return service.CategoryInput{}, false
+		}
+		return service.CategoryInput{Name: req.Name, Slug: req.Slug, ParentID: req.ParentID, Position: req.Position}, true
+	}
+	catalogFail := func(c *gin.Context, err error) {
+		var fe validate.Errors
+		switch {
+		case errors.As(err, &fe):
+			validate.Fail(c, fe)
+		case errors.Is(err, service.ErrSlugTaken):
+			c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+		case errors.Is(err, service.ErrCategoryCycle), errors.Is(err, service.ErrCategoryHasChildren):
+			c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+		case errors.Is(err, service.ErrSKUTaken), errors.Is(err, service.ErrVariantExists), errors.Is(err, service.ErrHasVariants):
+			c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+		case errors.Is(err, service.ErrCategoryNotFound), errors.Is(err, service.ErrProductNotFound),
+			errors.Is(err, service.ErrVariantNotFound), errors.Is(err, service.ErrOptionNotFound),
+			errors.Is(err, service.ErrImageNotFound):
+			c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		default:
+			log.Printf("catalog: %v", err)
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "internal error"})
+		}
+	}
+	admin.POST("/categories", func(c *gin.Context) {
+		in, ok := bindCategory(c)
+		if !ok {
+			return
+		}
+		cat, err := categories.Create(in)
+		if err != nil {
+			catalogFail(c, err)
+			return
+		}
+		c.JSON(http.StatusCreated, cat)
+	})
+	// tam güncelleme: parent_id null → köke taşı
+	admin.PUT("/categories/:id", func(c *gin.Context) {
+		id, ok := paramID(c)
+		if !ok {
+			return
+		}
+		in, ok := bindCategory(c)
+		if !ok {
+			return
+		}
+		cat, err := categories.Update(id, in)
+		if err != nil {
+			catalogFail(c, err)
+			return
+		}
+		c.JSON(http.StatusOK, cat)
+	})
+	admin.DELETE("/categories/:id", func(c *gin.Context) {
+		id, ok := paramID(c)
+		if !ok {
+			return
+		}
+		if err := categories.Delete(id); err != nil {
+			catalogFail(c, err)
+			return
+		}
+		c.JSON(http.StatusOK, gin.H{"ok": true})
+	})
+	// ürünün kategorilerini verilen listeyle değiştirir ([] → hepsini kaldır)
+	admin.PUT("/products/:id/categories", func(c *gin.Context) {
+		id, ok := paramID(c)
+		if !ok {
+			return
+		}
+		var req struct {
+			CategoryIDs []uint `json:"category_ids"`
+		}
+		if err := validate.Bind(c, &req); err != nil {
+			validate.Fail(c, err)
+			return
+		}
+		fe := validate.Errors{}
+		for _, cid := range req.CategoryIDs {
+			validate.PositiveID(fe, "category_ids", cid)
+		}
+		if err := fe.Err(); err != nil {
+			validate.Fail(c, err)
+			return
+		}
+		if err := categories.SetProductCategories(id, req.CategoryIDs); err != nil {
+			catalogFail(c, err)
+			return
+		}
+		c.JSON(http.StatusOK, gin.H{"ok": true})
+	})
+
+	// --- Admin: seçenekler + varyantlar ---
+	admin.POST("/products/:id/options", func(c *gin.Context) {
+		id, ok := paramID(c)
+		if !ok {
+			return
+		}
+		var req struct {
+			Name   string   `json:"name"`
+			Values []string `json:"values"`
+		}
+		if err := validate.Bind(c, &req); err != nil {
+			validate.Fail(c, err)
+			return
+		}
+		req.Name = strings.TrimSpace(req.Name)
+		fe := validate.Errors{}
+		validate.Required(fe, "name", req.Name)
+		if len(req.Values) == 0 {
+			fe.Add("values", "at least one value is required")
+		}
+		seen := map[string]bool{}
+		for i, v := range req.Values {
+			req.Values[i] = strings.TrimSpace(v)
+			k := strings.ToLower(req.Values[i])
+			if k == "" || seen[k] {
+				fe.Add("values", "values must be non-empty and unique")
+			}
+			seen[k] = true
+		}
+		if err := fe.Err(); err != nil {
+			validate.Fail(c, err)
+			return
+		}
+		opt, err := variants.AddOption(id, req.Name, req.Values)
+		if err != nil {
+			catalogFail(c, err)
+			return
+		}
+		c.JSON(http.StatusCreated, opt)
+	})
+	admin.DELETE("/options/:id", func(c *gin.Context) {
+		id, ok := paramID(c)
+		if !ok {
+			return
+		}
+		if err := variants.DeleteOption(id); err != nil {
+			catalogFail(c, err)
+			return
+		}
+		c.JSON(http.StatusOK, gin.H{"ok": true})
+	})
+
+	bindVariant := func(c *gin.Context) (service.VariantInput, bool) {
+		var req struct {
+			SKU            string `json:"sku"`
+			PriceCents     *int64 `json:"price_cents"` // null → ürün fiyatı
+			ImageURL       string `json:"image_url"`
+			Stock          int    `json:"stock"`
+			OptionValueIDs []uint `json:"option_value_ids"`
+		}
+		if err := validate.Bind(c, &req); err != nil {
+			validate.Fail(c, err)
+			return service.VariantInput{}, false
+		}
+		req.SKU = strings.TrimSpace(req.SKU)
+		fe := validate.Errors{}
+		validate.Required(fe, "sku", req.SKU)
+		if len(req.SKU) > 64 {
+			fe.Add("sku", "must be at most 64 characters")
+		}
+		if req.PriceCents != nil && *req.PriceCents < 0 {
+			fe.Add("price_cents", "must be >= 0")
+		}
+		if req.Stock < 0 {
+			fe.Add("stock", "must be >= 0")
+		}
+		if err := fe.Err(); err != nil {
+			validate.Fail(c, err)
+			return service.VariantInput{}, false
+		}
+		return service.VariantInput{
+			SKU: req.SKU, PriceCents: req.PriceCents, ImageURL: strings.TrimSpace(req.ImageURL),
+			Stock: req.Stock, ValueIDs: req.OptionValueIDs,
+		}, true
+	}
+	admin.POST("/products/:id/variants", func(c *gin.Context) {
+		id, ok := paramID(c)
+		if !ok {
+			return
+		}
+		in, ok := bindVariant(c)
+		if !ok {
+			return
+		}
+		v, err := variants.CreateVariant(id, in)
+		if err != nil {
+			catalogFail(c, err)
+			return
+		}
+		c.JSON(http.StatusCreated, v)
+	})
+	admin.PUT("/variants/:id", func(c *gin.Context) {
+		id, ok := paramID(c)
+		if !ok {
+			return
+		}
+		in, ok := bindVariant(c)
+		if !ok {
+			return
+		}
+		v, err := variants.UpdateVariant(id, in)
+		if err != nil {
+			catalogFail(c, err)
+			return
+		}
+		c.JSON(http.StatusOK, v)
+	})
+	admin.DELETE("/variants/:id", func(c *gin.Context) {
+		id, ok := paramID(c)
+		if !ok {
+			return
+		}
+		if err := variants.DeleteVariant(id); err != nil {
+			catalogFail(c, err)
+			return
+		}
+		c.JSON(http.StatusOK, gin.H{"ok": true})
+	})
+
+	// --- Admin: kurlar ---
+	// {"from":"USD","to":"TRY","rate":"34.25"}: 1 USD = 34.25 TRY (ondalık string, float değil)
+	admin.PUT("/exchange-rates", func(c *gin.Context) {
+		var req struct {
+			From string `json:"from"`
+			To   string `json:"to"`
+			Rate string `json:"rate"`
+		}
+		if err := validate.Bind(c, &req); err != nil {
+			validate.Fail(c, err)
+			return
+		}
+		fe := validate.Errors{}
+		from, err := money.ParseCurrency(req.From)
+		if err != nil {
+			fe.Add("from", "unsupported currency")
+		}
+		to, err := money.ParseCurrency(req.To)
+		if err != nil {
+			fe.Add("to", "unsupported currency")
+		}
+		if from != "" && from == to {
+			fe.Add("to", "must differ from from")
+		}
+		if _, err := money.ParseRate(req.Rate); err != nil {
+			fe.Add("rate", "must be a positive decimal string")
+		}
+		if err := fe.Err(); err != nil {
+			validate.Fail(c, err)
+			return
+		}
+		er, err := fx.SetRate(from, to, req.Rate)
+		if err != nil {
+			catalogFail(c, err)
+			return
+		}
+		c.JSON(http.StatusOK, er)
+	})
+
+	// --- Admin: vergi ---
+	admin.GET("/tax-rates", func(c *gin.Context) {
+		rates, err := tax.Rates()
+		if err != nil {
+			catalogFail(c, err)
+			return
+		}
+		c.JSON(http.StatusOK, gin.H{"prices_include_tax": tax.PricesIncludeTax(), "jurisdiction": tax.Jurisdiction(), "rates": rates})
+	})
+	// {"jurisdiction":"TR","class":"reduced","name":"KDV %10","basis_points":1000}
+	admin.PUT("/tax-rates", func(c *gin.Context) {
+		var req struct {
+			Jurisdiction string `json:"jurisdiction"`
+			Class        string `json:"class"`
+			Name         string `json:"name"`
+			BasisPoints  int    `json:"basis_points"`
+		}
+		if err := validate.Bind(c, &req); err != nil {
+			validate.Fail(c, err)
+			return
+		}
+		req.Jurisdiction = strings.ToUpper(strings.TrimSpace(req.Jurisdiction))
+		req.Name = strings.TrimSpace(req.Name)
+		fe := validate.Errors{}
+		if !service.ValidJurisdiction(req.Jurisdiction) {
+			fe.Add("jurisdiction", "must be a two-letter country code")
+		}
+		if len(req.Class) > 32 || !service.ValidSlug(req.Class) {
+			fe.Add("class", "use lowercase letters, digits and single dashes (max 32)")
+		}
+		validate.Required(fe, "name", req.Name)
+		if req.BasisPoints < 0 || req.BasisPoints > 10000 {
+			fe.Add("basis_points", "must be between 0 and 10000")
+		}
+		if err := fe.Err(); err != nil {
+			validate.Fail(c, err)
+			return
+		}
+		tr, err := tax.SetRate(req.Jurisdiction, req.Class, req.Name, req.BasisPoints)
+		if err != nil {
+			catalogFail(c, err)
+			return
+		}
+		c.JSON(http.StatusOK, tr)
+	})
+	admin.PUT("/products/:id/tax-class", func(c *gin.Context) {
+		id, ok := paramID(c)
+		if !ok {
+			return
+		}
+		var req struct {
+			TaxClass string `json:"tax_class"`
+		}
+		if err := validate.Bind(c, &req); err != nil {
+			validate.Fail(c, err)
+			return
+		}
+		if !service.ValidSlug(req.TaxClass) {
+			validate.Fail(c, validate.Errors{"tax_class": "is required"})
+			return
+		}
+		if err := tax.SetProductClass(id, req.TaxClass); err != nil {
+			catalogFail(c, err)
+			return
+		}
+		c.JSON(http.StatusOK, gin.H{"ok": true})
+	})
+
+	// --- Admin: kargo yöntemleri ---
+	bindShipping := func(c *gin.Context) (service.ShippingInput, bool) {
+		var req struct {
+			Code          string   `json:"code"`
+			Name          string   `json:"name"`
+			Kind          string   `json:"kind"` // flat | free_over | weight
+			Currency      string   `json:"currency"`
+			FeeCents      int64    `json:"fee_cents"`
+			FreeOverCents int64    `json:"free_over_cents"`
+			PerKgCents    int64    `json:"per_kg_cents"`
+			Countries     []string `json:"countries"`
+			TaxClass      string   `json:"tax_class"`
+			Active        *bool    `json:"active"` // yoksa true
+			Position      int      `json:"position"`
+		}
+		if err := validate.Bind(c, &req); err != nil {
+			validate.Fail(c, err)
+			return service.ShippingInput{}, false
+		}
+		in := service.ShippingInput{
+			Code: strings.TrimSpace(req.Code), Name: strings.TrimSpace(req.Name), Kind: req.Kind,
+			FeeCents: req.FeeCents, FreeOverCents: req.FreeOverCents, PerKgCents: req.PerKgCents,
+			TaxClass: req.TaxClass, Active: req.Active == nil || *req.Active, Position: req.Position,
+		}
+		fe := validate.Errors{}
+		if len(in.Code) > 32 || !service.ValidSlug(in.Code) {
+			fe.Add("code", "use lowercase letters, digits and single dashes (max 32)")
+		}
+		validate.Required(fe, "name", in.Name)
+		if !service.ValidShippingKind(in.Kind) {
+			fe.Add("kind", "must be flat, free_over or weight")
+		}
+		in.Currency = fx.Default()
+		if req.Currency != "" {
+			var err error
+			if in.Currency, err = money.ParseCurrency(req.Currency); err != nil {
+				fe.Add("currency", "unsupported currency")
+			}
+		}
+		if in.FeeCents < 0 || in.FreeOverCents < 0 || in.PerKgCents < 0 {
+			fe.Add("fee_cents", "amounts must be >= 0")
+		}
+		if in.Kind == model.ShippingFreeOver && in.FreeOverCents == 0 {
+			fe.Add("free_over_cents", "is required for free_over")
+		}
+		for _, cc := range req.Countries {
+			cc = strings.ToUpper(strings.TrimSpace(cc))
+			if !service.ValidJurisdiction(cc) {
+				fe.Add("countries", "must be two-letter country codes")
+			}
+			in.Countries = append(in.Countries, cc)
+		}
+		if in.TaxClass == "" {
+			in.TaxClass = "standard"
+		} else if !service.ValidSlug(in.TaxClass) {
+			fe.Add("tax_class", "use lowercase letters, digits and single dashes")
+		}
+		if err := fe.Err(); err != nil {
+			validate.Fail(c, err)
+			return service.ShippingInput{}, false
+		}
+		return in, true
+	}
+	shippingFail := func(c *gin.Context, err error) {
+		switch {
+		case errors.Is(err, service.ErrShippingCodeTaken):
+			c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+		case errors.Is(err, service.ErrShippingMethodNotFound):
+			c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		default:
+			catalogFail(c, err)
+		}
+	}
+	admin.GET("/shipping-methods", func(c *gin.Context) {
+		all, err := shipping.All()
+		if err != nil {
+			shippingFail(c, err)
+			return
+		}
+		c.JSON(http.StatusOK, all)
+	})
+	admin.POST("/shipping-methods", func(c *gin.Context) {
+		in, ok := bindShipping(c)
+		if !ok {
+			return
+		}
+		m, err := shipping.Create(in)
+		if err != nil {
+			shippingFail(c, err)
+			return
+		}
+		c.JSON(http.StatusCreated, m)
+	})
+	admin.PUT("/shipping-methods/:id", func(c *gin.Context) {
+		id, ok := paramID(c)
+		if !ok {
+			return
+		}
+		in, ok := bindShipping(c)
+		if !ok {
+			return
+		}
+		m, err := shipping.Update(id, in)
+		if err != nil {
+			shippingFail(c, err)
+			return
+		}
+		c.JSON(http.StatusOK, m)
+	})
+	admin.DELETE("/shipping-methods/:id", func(c *gin.Context) {
+		id, ok := paramID(c)
+		if !ok {
+			return
+		}
+		if err := shipping.Delete(id); err != nil {
+			shippingFail(c, err)
+			return
+		}
+		c.JSON(http.StatusOK, gin.H{"ok": true})
+	})
+
+	// --- Admin: promosyonlar ---
+	bindPromotion := func(c *gin.Context) (service.PromotionInput, bool) {
+		var req struct {
+			Name          string     `json:"name"`
+			Code          string     `json:"code"` // boş → otomatik kampanya
+			Kind          string     `json:"kind"` // percent | fixed | buy_x_get_y
+			PercentBP     int        `json:"percent_bp"`
+			AmountCents   int64      `json:"amount_cents"`
+			Currency      string     `json:"currency"`
+			BuyQty        int        `json:"buy_qty"`
+			GetQty        int        `json:"get_qty"`
+			MinOrderCents int64      `json:"min_order_cents"`
+			UsageLimit    int        `json:"usage_limit"`
+			PerUserLimit  int        `json:"per_user_limit"`
+			StartsAt      *time.Time `json:"starts_at"`
+			EndsAt        *time.Time `json:"ends_at"`
+			Active        *bool      `json:"active"` // yoksa true
+			ProductIDs    []uint     `json:"product_ids"`
+			CategoryIDs   []uint     `json:"category_ids"`
+		}
+		if err := validate.Bind(c, &req); err != nil {
+			validate.Fail(c, err)
+			return service.PromotionInput{}, false
+		}
+		in := service.PromotionInput{
+			Name: strings.TrimSpace(req.Name), Code: service.NormalizeCoupon(req.Code), Kind: req.Kind,
+			PercentBP: req.PercentBP, AmountCents: req.AmountCents, BuyQty: req.BuyQty, GetQty: req.GetQty,
+			MinOrderCents: req.MinOrderCents, UsageLimit: req.UsageLimit, PerUserLimit: req.PerUserLimit,
+			StartsAt: req.StartsAt, EndsAt: req.EndsAt, Active: req.Active == nil || *req.Active,
+			ProductIDs: req.ProductIDs, CategoryIDs: req.CategoryIDs,
+		}
+		fe := validate.Errors{}
+		validate.Required(fe, "name", in.Name)
+		if len(in.Name) > 100 {
+			fe.Add("name", "must be at most 100 characters")
+		}
+		if in.Code != "" && !couponRe.MatchString(in.Code) {
+			fe.Add("code", "use 3-32 letters, digits, - or _")
+		}
+		switch in.Kind {
+		case model.PromoPercent:
+			if in.PercentBP <= 0 || in.PercentBP > 10000 {
+				fe.Add("percent_bp", "must be between 1 and 10000")
+			}
+		case model.PromoFixed:
+			if in.AmountCents <= 0 {
+				fe.Add("amount_cents", "must be > 0")
+			}
+		case model.PromoBuyXGetY:
+			if in.BuyQty <= 0 {
+				fe.Add("buy_qty", "must be > 0")
+			}
+			if in.GetQty <= 0 {
+				fe.Add("get_qty", "must be > 0")
+			}
+		default:
+			fe.Add("kind", "must be percent, fixed or buy_x_get_y")
+		}
+		in.Currency = fx.Default()
+		if req.Currency != "" {
+			var err error
+			if in.Currency, err = money.ParseCurrency(req.Currency); err != nil {
+				fe.Add("currency", "unsupported currency")
+			}
+		}
+		if in.MinOrderCents < 0 {
+			fe.Add("min_order_cents", "must be >= 0")
+		}
+		if in.UsageLimit < 0 || in.PerUserLimit < 0 {
+			fe.Add("usage_limit", "limits must be >= 0")
+		}
+		if in.Code == "" && (in.UsageLimit > 0 || in.PerUserLimit > 0) {
+			fe.Add("usage_limit", "usage limits require a coupon code")
+		}
+		if in.StartsAt != nil && in.EndsAt != nil && !in.EndsAt.After(*in.StartsAt) {
+			fe.Add("ends_at", "must be after starts_at")
+		}
+		for _, id := range in.ProductIDs {
+			validate.PositiveID(fe, "product_ids", id)
+		}
+		for _, id := range in.CategoryIDs {
+			validate.PositiveID(fe, "category_ids", id)
+		}
+		if err := fe.Err(); err != nil {
+			validate.Fail(c, err)
+			return service.PromotionInput{}, false
+		}
+		return in, true
+	}
+	promotionFail := func(c *gin.Context, err error) {
+		switch {
+		case errors.Is(err, service.ErrCouponCodeTaken):
+			c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+		case errors.Is(err, service.ErrPromotionNotFound):
+			c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		case errors.Is(err, service.ErrProductNotFound):
+			validate.Fail(c, validate.Errors{"product_ids": err.Error()})
+		case errors.Is(err, service.ErrCategoryNotFound):
+			validate.Fail(c, validate.Errors{"category_ids": err.Error()})
+		default:
+			catalogFail(c, err)
+		}
+	}
+	admin.GET("/promotions", func(c *gin.Context) {
+		list, err := promotions.List()
+		if err != nil {
+			promotionFail(c, err)
+			return
+		}
+		c.JSON(http.StatusOK, list)
+	})
+	admin.POST("/promotions", func(c *gin.Context) {
+		in, ok := bindPromotion(c)
+		if !ok {
+			return
+		}
+		p, err := promotions.Create(in)
+		if err != nil {
+			promotionFail(c, err)
+			return
+		}
+		c.JSON(http.StatusCreated, p)
+	})
+	admin.PUT("/promotions/:id", func(c *gin.Context) {
+		id, ok := paramID(c)
+		if !ok {
+			return
+		}
+		in, ok := bindPromotion(c)
+		if !ok {
+			return
+		}
+		p, err := promotions.Update(id, in)
+		if err != nil {
+			promotionFail(c, err)
+			return
+		}
+		c.JSON(http.StatusOK, p)
+	})
+	admin.DELETE("/promotions/:id", func(c *gin.Context) {
+		id, ok := paramID(c)
+		if !ok {
+			return
+		}
+		if err := promotions.Delete(id); err != nil {
+			promotionFail(c, err)
+			return
+		}
+		c.JSON(http.StatusOK, gin.H{"ok": true})
+	})
+
+	// --- Admin: siparişler (kargolama, iade) ---
+	admin.GET("/orders/:id", func(c *gin.Context) {
+		id, ok := paramID(c)
+		if !ok {
+			return
+		}
+		o, err := orders.Get(0, id)
+		if err != nil {
+			orderFail(c, err)
+			return
+		}
+		c.JSON(http.StatusOK, o)
+	})
+	admin.POST("/orders/:id/ship", func(c *gin.Context) {
+		id, ok := paramID(c)
+		if !ok {
+			return
+		}
+		o, err := orders.Ship(id)
+		if err != nil {
+			orderFail(c, err)
+			return
+		}
+		c.JSON(http.StatusOK, o)
+	})
+	// {"full":true} kalan her şey (kargo dahil) ya da
+	// {"items":[{"order_item_id":7,"qty":1}],"shipping":false,"restock":true,"reason":"..."}
+	admin.POST("/orders/:id/refunds", func(c *gin.Context) {
+		id, ok := paramID(c)
+		if !ok {
+			return
+		}
+		var req struct {
+			Full  bool `json:"full"`
+			Items []struct {
+				OrderItemID uint `json:"order_item_id"`
+				Qty         int  `json:"qty"`
+			} `json:"items"`
+			Shipping bool   `json:"shipping"`
+			Restock  bool   `json:"restock"`
+			Reason   string `json:"reason"`
+		}
+		if err := validate.Bind(c, &req); err != nil {
+			validate.Fail(c, err)
+			return
+		}
+		fe := validate.Errors{}
+		in := service.RefundInput{Full: req.Full, Shipping: req.Shipping, Restock: req.Restock, Reason: strings.TrimSpace(req.Reason)}
+		for _, it := range req.Items {
+			validate.PositiveID(fe, "items", it.OrderItemID)
+			if it.Qty <= 0 {
+				fe.Add("items", "qty must be > 0")
+			}
+			in.Items = append(in.Items, service.RefundLine{OrderItemID: it.OrderItemID, Qty: it.Qty})
+		}
+		if !in.Full && len(in.Items) == 0 && !in.Shipping {
+			fe.Add("items", "nothing to refund")
+		}
+		if len(in.Reason) > 255 {
+			fe.Add("reason", "must be at most 255 characters")
+		}
+		if err := fe.Err(); err != nil {
+			validate.Fail(c, err)
+			return
+		}
+		o, err := orders.Refund(id, in)
+		if err != nil {
+			orderFail(c, err)
+			return
+		}
+		c.JSON(http.StatusOK, o)
+	})
+
+	// --- Admin: iade talepleri ---
+	// ?status=requested|approved|rejected|received|refunded
+	admin.GET("/returns", func(c *gin.Context) {
+		list, err := returns.All(c.Query("status"))
+		if err != nil {
+			orderFail(c, err)
+			return
+		}
+		c.JSON(http.StatusOK, list)
+	})
+	admin.GET("/returns/:id", func(c *gin.Context) {
+		id, ok := paramID(c)
+		if !ok {
+			return
+		}
+		ret, err := returns.Get(0, id)
+		if err != nil {
+			orderFail(c, err)
+			return
+		}
+		c.JSON(http.StatusOK, ret)
+	})
+	// approve/reject/receive/refund: {"note":"...","restock":true}; restock sadece receive'de
+	returnStep := func(step func(id uint, restock bool, note string) (model.ReturnRequest, error)) gin.HandlerFunc {
+		return func(c *gin.Context) {
+			id, ok := paramID(c)
+			if !ok {
+				return
+			}
+			var req struct {
+				Note    string `json:"note"`
+				Restock bool   `json:"restock"`
+			}
+			if c.Request.ContentLength != 0 {
+				if err := validate.Bind(c, &req); err != nil {
+					validate.Fail(c, err)
+					return
+				}
+			}
+			if len(req.Note) > 500 {
+				validate.Fail(c, validate.Errors{"note": "must be at most 500 characters"})
+				return
+			}
+			ret, err := step(id, req.Restock, req.Note)
+			if err != nil {
+				orderFail(c, err)
+				return
+			}
+			c.JSON(http.StatusOK, ret)
+		}
+	}
+	admin.POST("/returns/:id/approve", returnStep(func(id uint, _ bool, note string) (model.ReturnRequest, error) { return returns.Approve(id, note) }))
+	admin.POST("/returns/:id/reject", returnStep(func(id uint, _ bool, note string) (model.ReturnRequest, error) { return returns.Reject(id, note) }))
+	admin.POST("/returns/:id/receive", returnStep(returns.Receive))
+	admin.POST("/returns/:id/refund", returnStep(func(id uint, _ bool, note string) (model.ReturnRequest, error) { return returns.Refund(id, note) }))
+
+	// --- Admin: ürün görselleri ---
+	// multipart/form-data, alan adı "file"; tip içerikten (sniff) belirlenir
+	admin.POST("/products/:id/images", func(c *gin.Context) {
+		id, ok := paramID(c)
+		if !ok {
+			return
+		}
+		c.Request.Body = http.MaxBytesReader(c.Writer, c.Request.Body, images.MaxBytes()+64<<10) // + multipart zarfı
+		fh, err := c.FormFile("file")
+		if err != nil {
+			var mbe *http.MaxBytesError
+			if errors.As(err, &mbe) {
+				c.JSON(http.StatusRequestEntityTooLarge, gin.H{"error": service.ErrImageTooLarge.Error()})
+				return
+			}
+			validate.Fail(c, validate.Errors{"file": "is required"})
+			return
+		}
+		f, err := fh.Open()
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		defer f.Close()
+		img, err := images.Upload(id, f)
+		switch {
+		case errors.Is(err, service.ErrImageTooLarge):
+			c.JSON(http.StatusRequestEntityTooLarge, gin.H{"error": err.Error()})
+		case errors.Is(err, service.ErrUnsupportedImage):
+			c.JSON(http.StatusUnsupportedMediaType, gin.H{"error": "file must be a JPEG, PNG, GIF or WebP image"})
+		case err != nil:
+			catalogFail(c, err)
+		default:
+			c.JSON(http.StatusCreated, img)
+		}
+	})
+	admin.PUT("/products/:id/images/order", func(c *gin.Context) {
+		id, ok := paramID(c)
+		if !ok {
+			return
+		}
+		var req struct {
+			ImageIDs []uint `json:"image_ids"`
+		}
+		if err := validate.Bind(c, &req); err != nil {
+			validate.Fail(c, err)
+			return
+		}
+		if err := images.Reorder(id, req.ImageIDs); err != nil {
+			catalogFail(c, err)
+			return
+		}
+		c.JSON(http.StatusOK, gin.H{"ok": true})
+	})
+	admin.DELETE("/images/:id", func(c *gin.Context) {
+		id, ok := paramID(c)
+		if !ok {
+			return
+		}
+		if err := images.Delete(id); err != nil {
+			catalogFail(c, err)
+			return
+		}
+		c.JSON(http.StatusOK, gin.H{"ok": true})
+	})
+
+	// (opsiyonel SPA fallback)
+	r.NoRoute(func(c *gin.Context) {
+		p := c.Request.URL.Path
+		if strings.HasPrefix(p, "/api/") || strings.HasPrefix(p, "/assets/") || strings.HasPrefix(p, "/media/") {
+			c.JSON(404, gin.H{"error": "not found"})
+			return
+		}
+		web.Page("index.html")(c)
+	})
+
+	return r, nil
+}
+
+// setSessionCookie login / 2FA sonrası session JWT'yi HttpOnly cookie olarak yazar.
+func setSessionCookie(w http.ResponseWriter, tok string) {
+	http.SetCookie(w, &http.Cookie{
+		Name:     "session",
+		Value:    tok,
+		Path:     "/",
+		MaxAge:   7 * 24 * 3600,
+		HttpOnly: true,
+		Secure:   true,                 // sadece HTTPS
+		SameSite: http.SameSiteLaxMode, // form login için ideal
+	})
+}
+
+// version bir tablonun satır sayısı + son updated_at'i; ETag'e tam hassasiyetle girer
+// (aynı saniyedeki iki güncelleme farklı ETag üretsin).
+type version struct {
+	N    int64
+	Last time.Time
+}
+
+func (v version) String() string { return fmt.Sprintf("%d-%d", v.N, v.Last.UnixNano()) }
+
+func tableVersion(db *gorm.DB, m any) (version, error) {
+	var meta struct {
+		N    int64
+		Last *time.Time
+	}
+	if err := db.Model(m).Select("COUNT(*) AS n, MAX(updated_at) AS last").Scan(&meta).Error; err != nil {
+		return version{}, err
+	}
+	v := version{N: meta.N}
+	if meta.Last != nil {
+		v.Last = meta.Last.UTC()
+	}
+	return v, nil
+}
+
+// cacheable public liste cevabına ETag/Last-Modified yazar; istemcinin
+// kopyası güncelse 304 döner ve true verir (handler başka bir şey yazmaz).
+// Last-Modified HTTP tarihi olduğundan saniyeye kesilir; kesin karşılaştırma ETag'le.
+func cacheable(c *gin.Context, etag string, last time.Time) bool {
+	c.Header("Cache-Control", "public, no-cache")
+	c.Header("ETag", etag)
+	last = last.Truncate(time.Second)
+	if !last.IsZero() {
+		c.Header("Last-Modified", last.Format(http.TimeFormat))
+	}
+	if notModified(c.Request, etag, last) {
+		c.Status(http.StatusNotModified)
+		return true
+	}
+	return false
+}
+
+// notModified If-None-Match (öncelikli) ve If-Modified-Since'ı değerlendirir.
+func notModified(r *http.Request, etag string, last time.Time) bool {
+	if inm := r.Header.Get("If-None-Match"); inm != "" {
+		for _, t := range strings.Split(inm, ",") {
+			t = strings.TrimSpace(t)
+			if t == "*" || strings.TrimPrefix(t, "W/") == strings.TrimPrefix(etag, "W/") {
+				return true
+			}
+		}
+		return false
+	}
+	if ims := r.Header.Get("If-Modified-Since"); ims != "" && !last.IsZero() {
+		if t, err := http.ParseTime(ims); err == nil && !last.After(t) {
+			return true
+		}
+	}
+	return false
+}
+
+// serveImage storage'dan gelen görseli yazar; Range/304 ServeContent'te.
+func serveImage(c *gin.Context, rc io.ReadCloser, info storage.Info, etag string, err error) {
+	switch {
+	case errors.Is(err, service.ErrImageNotFound), errors.Is(err, service.ErrThumbSize):
+		c.JSON(http.StatusNotFound, gin.H{"error": "not found"})
+		return
+	case err != nil:
+		log.Printf("media: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "internal error"})
+		return
+	}
+	defer rc.Close()
+	h := c.Writer.Header()
+	h.Set("Content-Type", info.ContentType)
+	h.Set("Cache-Control", "public, max-age=31536000, immutable")
+	h.Set("ETag", etag)
+	if rs, ok := rc.(io.ReadSeeker); ok {
+		http.ServeContent(c.Writer, c.Request, "", info.ModTime, rs)
+		return
+	}
+	if notModified(c.Request, etag, time.Time{}) {
+		c.Status(http.StatusNotModified)
+		return
+	}
+	if info.Size > 0 {
+		h.Set("Content-Length", strconv.FormatInt(info.Size, 10))
+	}
+	c.Status(http.StatusOK)
+	_, _ = io.Copy(c.Writer, rc)
+}
+
+// couponRe normalize edilmiş (büyük harf) kupon kodu.
+var couponRe = regexp.MustCompile(`^[A-Z0-9][A-Z0-9_-]{2,31}$`)
+
+// summaryInput sepet özeti sorgusu: ?currency=&address_id=&shipping_method=
+func summaryInput(c *gin.Context) (service.SummaryInput, error) {
+	var in service.SummaryInput
+	cur, err := currencyParam(c)
+	if err != nil {
+		return in, err
+	}
+	in.Currency = cur
+	if v := c.Query("address_id"); v != "" {
+		id, err := strconv.ParseUint(v, 10, 32)
+		if err != nil || id == 0 {
+			return in, validate.Errors{"address_id": "must be a positive id"}
+		}
+		in.AddressID = uint(id)
+	}
+	in.ShippingMethod = strings.TrimSpace(c.Query("shipping_method"))
+	return in, nil
+}
+
+// quoteFail sepet fiyatlama/kupon hatalarını ortak gövdeye çevirir.
+func quoteFail(c *gin.Context, err error) {
+	switch {
+	case errors.Is(err, service.ErrCouponNotFound), errors.Is(err, service.ErrCouponInvalid), errors.Is(err, service.ErrCouponExhausted):
+		validate.Fail(c, validate.Errors{"code": err.Error()})
+	case errors.Is(err, service.ErrNoExchangeRate):
+		validate.Fail(c, validate.Errors{"currency": err.Error()})
+	case errors.Is(err, service.ErrAddressNotFound):
+		validate.Fail(c, validate.Errors{"address_id": err.Error()})
+	case errors.Is(err, service.ErrShippingMethodNotFound):
+		validate.Fail(c, validate.Errors{"shipping_method": err.Error()})
+	default:
+		log.Printf("cart pricing: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "internal error"})
+	}
+}
+
+// orderFail sipariş iptal/iade ve iade talebi hatalarını ortak gövdeye çevirir.
+func orderFail(c *gin.Context, err error) {
+	var fe validate.Errors
+	switch {
+	case errors.As(err, &fe):
+		validate.Fail(c, fe)
+	case errors.Is(err, service.ErrOrderNotFound), errors.Is(err, service.ErrReturnNotFound), errors.Is(err, service.ErrInvoiceNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+	case errors.Is(err, service.ErrOrderNotCancellable), errors.Is(err, service.ErrOrderNotRefundable), errors.Is(err, service.ErrOrderNotShippable),
+		errors.Is(err, service.ErrOrderNotReturnable), errors.Is(err, service.ErrReturnStatus):
+		c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+	default:
+		log.Printf("order: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "internal error"})
+	}
+}
+
+// paramID :id yol parametresi; geçersizse 404 yazar.
+func paramID(c *gin.Context) (uint, bool) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil || id == 0 {
+		c.JSON(http.StatusNotFound, gin.H{"error": "not found"})
+		return 0, false
+	}
+	return uint(id), true
+}
+
+// currencyParam ?currency= gösterim para birimi; boşsa "" (servis varsayılanı).
+func currencyParam(c *gin.Context) (money.Currency, error) {
+	v := c.Query("currency")
+	if v == "" {
+		return "", nil
+	}
+	cur, err := money.ParseCurrency(v)
+	if err != nil {
+		return "", validate.Errors{"currency": "unsupported currency"}
+	}
+	return cur, nil
+}
+
+// productQuery GET /api/products query string'ini doğrular.
+func productQuery(c *gin.Context) (service.ProductQuery, error) {
+	fe := validate.Errors{}
+	pq := service.ProductQuery{
+		Q:      strings.TrimSpace(c.Query("q")),
+		Sort:   c.Query("sort"),
+		Cursor: c.Query("cursor"),
+	}
+	if len(pq.Q) > 200 {
+		fe.Add("q", "must be at most 200 characters")
+	}
+	price := func(field string) *int64 {
+		v := c.Query(field)
+		if v == "" {
+			return nil
+		}
+		n, err := strconv.ParseInt(v, 10, 64)
+		if err != nil || n < 0 {
+			fe.Add(field, "must be a non-negative amount in cents")
+			return nil
+		}
+		return &n
+	}
+	pq.MinPrice, pq.MaxPrice = price("min_price"), price("max_price")
+	if cur, err := currencyParam(c); err != nil {
+		fe.Add("currency", "unsupported currency")
+	} else {
+		pq.Currency = cur
+	}
+	if pq.MinPrice != nil && pq.MaxPrice != nil && *pq.MinPrice > *pq.MaxPrice {
+		fe.Add("max_price", "must be greater than or equal to min_price")
+	}
+	switch pq.Sort {
+	case "", service.SortPriceAsc, service.SortPriceDesc, service.SortNewest, service.SortDefault:
+	case service.SortRelevance:
+		if pq.Q == "" {
+			fe.Add("sort", "relevance requires q")
+		}
+	default:
+		fe.Add("sort", "must be one of relevance, price_asc, price_desc, newest, id")
+	}
+	if v := c.Query("limit"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n < 1 || n > service.MaxPageSize {
+			fe.Add("limit", fmt.Sprintf("must be between 1 and %d", service.MaxPageSize))
+		}
+		pq.Limit = n
+	}
+	return pq, fe.Err()
+}
+
+func clearSessionCookie(w http.ResponseWriter) {
+	http.SetCookie(w, &http.Cookie{
+		Name:     "session",
+		Value:    "",
+		Path:     "/",
+		MaxAge:   -1,
+		HttpOnly: true,
+		Secure:   true,
+		SameSite: http.SameSiteLaxMode,
+	})
+}
+
+func accountFail(c *gin.Context, err error) {
+	var fe validate.Errors
+	switch {
+	case errors.As(err, &fe):
+		validate.Fail(c, fe)
+	case errors.Is(err, service.ErrEmailTaken):
+		c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+	case errors.Is(err, service.ErrReauthRequired):
+		// istemci yeniden giriş (OIDC / magic link) isteyip işlemi tekrarlar
+		c.JSON(http.StatusForbidden, gin.H{"error": err.Error(), "reauth_required": true})
+	case errors.Is(err, gorm.ErrRecordNotFound):
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "invalid session"})
+	case errors.Is(err, service.ErrInvalidCode), errors.Is(err, service.ErrCodeExpired),
+		errors.Is(err, service.ErrNoPendingEmail):
+		validate.Fail(c, validate.Errors{"code": err.Error()})
+	default:
+		log.Printf("account: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "internal error"})
+	}
+}
+
+func mfaFail(c *gin.Context, err error) {
+	var fe validate.Errors
+	switch {
+	case errors.As(err, &fe):
+		validate.Fail(c, fe)
+	case errors.Is(err, service.ErrReauthRequired):
+		c.JSON(http.StatusForbidden, gin.H{"error": err.Error(), "reauth_required": true})
+	case errors.Is(err, service.ErrInvalidMFACode):
+		validate.Fail(c, validate.Errors{"code": err.Error()})
+	case errors.Is(err, service.ErrMFAAlreadyEnabled), errors.Is(err, service.ErrMFANotEnrolled):
+		c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+	default:
+		log.Printf("totp: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "internal error"})
+	}
 }
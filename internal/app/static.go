@@ -0,0 +1,267 @@
+package app
+
+import (
+	"bytes"
+	"compress/gzip"
+	"crypto/sha256"
+	"encoding/hex"
+	"io/fs"
+	"mime"
+	"net/http"
+	"path"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// webAssets web/ ağacını servis eder (prod'da gömülü embed.FS, dev'de disk).
+//
+// Sayfalardaki /assets/... referansları içerik hash'iyle (?v=<hash>) yeniden
+// yazılır; doğru hash'le gelen asset isteği "immutable" olarak bir yıl
+// cache'lenir, hash'siz istekler kısa süre cache'lenip ETag ile doğrulanır.
+// Sayfalar (HTML) her seferinde ETag ile yeniden doğrulanır (no-cache).
+//
+// Metin dosyalarının gzip hali açılışta bellekte üretilir; yanında "x.br"
+// (scripts/precompress.sh) varsa brotli isteyen istemcilere o verilir.
+//
+// dev=true iken (WEB_DIR) istenen dosya mtime'ı değiştiyse diskten yeniden
+// okunur, sayfalar her istekte yeniden yazılır: canlı düzenleme.
+type webAssets struct {
+	fsys  fs.FS
+	dev   bool
+	mu    sync.Mutex          // dev'de files önbellek olarak güncellenir
+	files map[string]*webFile // "assets/app.css", "index.html", ...
+}
+
+type webFile struct {
+	body, gz, br []byte
+	hash         string
+	ctype        string
+	mod          time.Time
+}
+
+var (
+	assetRef = regexp.MustCompile(`(href|src)="/(assets/[^"?#]+)"`)
+	webPages = []string{"index.html", "auth.html", "cart.html"}
+)
+
+func newWebAssets(fsys fs.FS, dev bool) (*webAssets, error) {
+	w := &webAssets{fsys: fsys, dev: dev}
+	if err := w.load(); err != nil {
+		return nil, err
+	}
+	return w, nil
+}
+
+func (w *webAssets) load() error {
+	files := map[string]*webFile{}
+	err := fs.WalkDir(w.fsys, "assets", func(p string, d fs.DirEntry, err error) error {
+		if err != nil || d.IsDir() || strings.HasSuffix(p, ".br") || strings.HasSuffix(p, ".gz") {
+			return err
+		}
+		f, err := w.readFile(p, nil)
+		if err != nil {
+			return err
+		}
+		files[p] = f
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	rewrite := pageRewriter(func(p string) (*webFile, bool) {
+		f, ok := files[p]
+		return f, ok
+	})
+	for _, name := range webPages {
+		f, err := w.readFile(name, rewrite)
+		if err != nil {
+			return err
+		}
+		files[name] = f
+	}
+	w.files = files
+	return nil
+}
+
+// pageRewriter sayfadaki /assets/... referanslarına lookup'ın verdiği hash'i ekler.
+func pageRewriter(lookup func(string) (*webFile, bool)) func([]byte) []byte {
+	return func(b []byte) []byte {
+		return assetRef.ReplaceAllFunc(b, func(m []byte) []byte {
+			sub := assetRef.FindSubmatch(m)
+			a, ok := lookup(string(sub[2]))
+			if !ok {
+				return m
+			}
+			return []byte(string(sub[1]) + `="/` + string(sub[2]) + `?v=` + a.hash + `"`)
+		})
+	}
+}
+
+func (w *webAssets) readFile(p string, rewrite func([]byte) []byte) (*webFile, error) {
+	b, err := fs.ReadFile(w.fsys, p)
+	if err != nil {
+		return nil, err
+	}
+	if rewrite != nil {
+		b = rewrite(b)
+	}
+	f := &webFile{body: b, hash: shortHash(b), ctype: contentType(p)}
+	if fi, err := fs.Stat(w.fsys, p); err == nil {
+		f.mod = fi.ModTime() // embed.FS'te sıfır; ETag yeterli
+	}
+	if w.dev || !compressible(f.ctype) || len(b) < 512 {
+		return f, nil
+	}
+	var buf bytes.Buffer
+	zw, _ := gzip.NewWriterLevel(&buf, gzip.BestCompression)
+	_, _ = zw.Write(b)
+	_ = zw.Close()
+	if buf.Len() < len(b) {
+		f.gz = buf.Bytes()
+	}
+	// sayfalar yeniden yazıldığı için .br kopyası sadece asset'lerde geçerli
+	if rewrite == nil {
+		if br, err := fs.ReadFile(w.fsys, p+".br"); err == nil {
+			f.br = br
+		}
+	}
+	return f, nil
+}
+
+func contentType(p string) string {
+	switch path.Ext(p) {
+	case ".js":
+		return "text/javascript; charset=utf-8"
+	case ".html":
+		return "text/html; charset=utf-8"
+	}
+	if t := mime.TypeByExtension(path.Ext(p)); t != "" {
+		return t
+	}
+	return "application/octet-stream"
+}
+
+func compressible(ctype string) bool {
+	return strings.HasPrefix(ctype, "text/") || strings.Contains(ctype, "javascript") ||
+		strings.Contains(ctype, "json") || strings.Contains(ctype, "svg")
+}
+
+func shortHash(b []byte) string {
+	sum := sha256.Sum256(b)
+	return hex.EncodeToString(sum[:6])
+}
+
+func (w *webAssets) get(p string) (*webFile, bool) {
+	if w.dev {
+		if slices.Contains(webPages, p) {
+			// sayfa küçük; referans verdiği asset'lerin hash'i değişmiş olabilir
+			f, err := w.readFile(p, pageRewriter(w.devAsset))
+			return f, err == nil
+		}
+		return w.devAsset(p)
+	}
+	f, ok := w.files[p]
+	return f, ok
+}
+
+// devAsset asset'i önbellekten verir; mtime ya da boyut değiştiyse sadece o dosya yeniden okunur.
+func (w *webAssets) devAsset(p string) (*webFile, bool) {
+	if strings.HasSuffix(p, ".br") || strings.HasSuffix(p, ".gz") {
+		return nil, false
+	}
+	fi, err := fs.Stat(w.fsys, p)
+	if err != nil || fi.IsDir() {
+		return nil, false
+	}
+	w.mu.Lock()
+	f, ok := w.files[p]
+	w.mu.Unlock()
+	if ok && f.mod.Equal(fi.ModTime()) && int64(len(f.body)) == fi.Size() {
+		return f, true
+	}
+	if f, err = w.readFile(p, nil); err != nil {
+		return nil, false
+	}
+	w.mu.Lock()
+	w.files[p] = f
+	w.mu.Unlock()
+	return f, true
+}
+
+// serve Accept-Encoding'e göre br/gzip/ham gövdeyi yazar; Range/304 ServeContent'te.
+func (w *webAssets) serve(c *gin.Context, name string, f *webFile) {
+	h := c.Writer.Header()
+	h.Set("Content-Type", f.ctype)
+	h.Set("ETag", `"`+f.hash+`"`)
+	body := f.body
+	if f.gz != nil || f.br != nil {
+		h.Add("Vary", "Accept-Encoding")
+		ae := c.GetHeader("Accept-Encoding")
+		switch {
+		case f.br != nil && acceptsEncoding(ae, "br"):
+			h.Set("Content-Encoding", "br")
+			h.Set("ETag", `"`+f.hash+`-br"`)
+			body = f.br
+		case f.gz != nil && acceptsEncoding(ae, "gzip"):
+			h.Set("Content-Encoding", "gzip")
+			h.Set("ETag", `"`+f.hash+`-gz"`)
+			body = f.gz
+		}
+	}
+	http.ServeContent(c.Writer, c.Request, name, f.mod, bytes.NewReader(body))
+}
+
+func acceptsEncoding(header, enc string) bool {
+	for _, part := range strings.Split(header, ",") {
+		name, params, _ := strings.Cut(strings.TrimSpace(part), ";")
+		if !strings.EqualFold(strings.TrimSpace(name), enc) {
+			continue
+		}
+		if q, ok := strings.CutPrefix(strings.ReplaceAll(params, " ", ""), "q="); ok {
+			if v, err := strconv.ParseFloat(q, 64); err == nil && v == 0 {
+				return false
+			}
+		}
+		return true
+	}
+	return false
+}
+
+// Page fingerprint'li HTML sayfasını ETag ile servis eder.
+func (w *webAssets) Page(name string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		f, ok := w.get(name)
+		if !ok {
+			c.Status(http.StatusNotFound)
+			return
+		}
+		c.Header("Cache-Control", "no-cache")
+		w.serve(c, name, f)
+	}
+}
+
+// Asset GET /assets/*filepath.
+func (w *webAssets) Asset(c *gin.Context) {
+	p := path.Join("assets", path.Clean("/"+c.Param("filepath")))
+	f, ok := w.get(p)
+	if !ok {
+		c.JSON(http.StatusNotFound, gin.H{"error": "not found"})
+		return
+	}
+	switch {
+	case w.dev:
+		c.Header("Cache-Control", "no-cache")
+	case c.Query("v") == f.hash:
+		c.Header("Cache-Control", "public, max-age=31536000, immutable")
+	default:
+		c.Header("Cache-Control", "public, max-age=300")
+	}
+	w.serve(c, p, f)
+}
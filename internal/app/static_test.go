@@ -0,0 +1,48 @@
+package app
+
+import (
+	"strings"
+	"testing"
+	"testing/fstest"
+	"time"
+)
+
+func TestWebAssetsDevReload(t *testing.T) {
+	t0 := time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+	fsys := fstest.MapFS{
+		"assets/app.css": {Data: []byte("body{color:red}"), ModTime: t0},
+		"assets/app.js":  {Data: []byte("console.log(1)"), ModTime: t0},
+		"index.html":     {Data: []byte(`<link href="/assets/app.css">`), ModTime: t0},
+		"auth.html":      {Data: []byte(`<p>auth</p>`), ModTime: t0},
+		"cart.html":      {Data: []byte(`<p>cart</p>`), ModTime: t0},
+	}
+	w, err := newWebAssets(fsys, true)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	css, _ := w.get("assets/app.css")
+	js, _ := w.get("assets/app.js")
+	if again, _ := w.get("assets/app.css"); again != css {
+		t.Fatal("unchanged asset was reloaded")
+	}
+
+	// sadece değişen dosya yeniden okunur
+	fsys["assets/app.css"] = &fstest.MapFile{Data: []byte("body{color:blue}"), ModTime: t0.Add(time.Second)}
+	fresh, ok := w.get("assets/app.css")
+	if !ok || fresh == css || string(fresh.body) != "body{color:blue}" {
+		t.Fatalf("changed asset not reloaded: %q", fresh.body)
+	}
+	if again, _ := w.get("assets/app.js"); again != js {
+		t.Fatal("untouched asset was reloaded")
+	}
+
+	// sayfa yeni hash'i gösterir
+	page, ok := w.get("index.html")
+	if !ok || !strings.Contains(string(page.body), "?v="+fresh.hash) {
+		t.Fatalf("page not rewritten with new hash: %q", page.body)
+	}
+	if _, ok := w.get("assets/missing.css"); ok {
+		t.Fatal("missing asset found")
+	}
+}
@@ -2,14 +2,17 @@ package handlers
 
 import (
 	"encoding/json"
+	"errors"
 	"net/http"
+	"strings"
 	"time"
-	"github.com/gin-gonic/gin"
 	"example.com/ecom-go/internal/service"
+	"example.com/ecom-go/internal/validate"
 )
 
 type AuthHTTP struct {
 	S service.AuthService
+	A service.AccountService
 }
 
 type loginReq struct {
@@ -18,7 +21,9 @@ type loginReq struct {
 }
 
 
-func NewAuthHTTP(s service.AuthService) *AuthHTTP { return &AuthHTTP{S: s} }
+func NewAuthHTTP(s service.AuthService, a service.AccountService) *AuthHTTP {
+	return &AuthHTTP{S: s, A: a}
+}
 
 type jsonMap map[string]any
 
@@ -28,19 +33,36 @@ func writeJSON(w http.ResponseWriter, status int, v any) {
 	_ = json.NewEncoder(w).Encode(v)
 }
 
+// fail validate katmanının ortak hata gövdesini yazar.
+func fail(w http.ResponseWriter, err error) {
+	status, body := validate.Response(err)
+	writeJSON(w, status, body)
+}
+
 func (h *AuthHTTP) Register(w http.ResponseWriter, r *http.Request) {
 	var in struct {
 		Email    string `json:"email"`
 		Password string `json:"password"`
 		Password2 string `json:"password2"`
 	}
-	if err := json.NewDecoder(r.Body).Decode(&in); err != nil {
-		writeJSON(w, 400, jsonMap{"error":"Geçersiz JSON"}); return
+	if err := validate.Decode(w, r, &in); err != nil {
+		fail(w, err); return
 	}
+	in.Email = validate.NormalizeEmail(in.Email)
+	fe := validate.Errors{}
+	validate.Email(fe, "email", in.Email)
+	validate.Required(fe, "password", in.Password)
 	if in.Password != in.Password2 {
-		writeJSON(w, 400, jsonMap{"error":"Şifreler aynı değil"}); return
+		fe.Add("password2", "Şifreler aynı değil")
+	}
+	if err := fe.Err(); err != nil {
+		fail(w, err); return
 	}
 	if err := h.S.Register(in.Email, in.Password); err != nil {
+		var pe validate.Errors
+		if errors.As(err, &pe) {
+			fail(w, pe); return
+		}
 		// exists durumlarını kullanıcı dostu döndür
 		if err == service.ErrExistsUnverified {
 			writeJSON(w, 200, jsonMap{"ok": true, "info":"Doğrulama kodu tekrar gönderildi"}); return
@@ -58,41 +80,47 @@ func (h *AuthHTTP) Verify(w http.ResponseWriter, r *http.Request) {
 		Email string `json:"email"`
 		Code  string `json:"code"`
 	}
-	if err := json.NewDecoder(r.Body).Decode(&in); err != nil {
-		writeJSON(w, 400, jsonMap{"error":"Geçersiz JSON"}); return
+	if err := validate.Decode(w, r, &in); err != nil {
+		fail(w, err); return
+	}
+	in.Email = validate.NormalizeEmail(in.Email)
+	fe := validate.Errors{}
+	validate.Email(fe, "email", in.Email)
+	validate.Code(fe, "code", in.Code)
+	if err := fe.Err(); err != nil {
+		fail(w, err); return
 	}
 	if err := h.S.VerifyCode(in.Email, in.Code); err != nil {
 		writeJSON(w, 400, jsonMap{"error": err.Error()}); return
 	}
 	writeJSON(w, 200, jsonMap{"ok": true})
 }
-func (h *Auth) Resend(c *gin.Context) {
-    var req struct {
-        Email string `json:"email" binding:"required,email"`
-    }
-    if err := c.ShouldBindJSON(&req); err != nil {
-        c.JSON(http.StatusBadRequest, gin.H{"error": "geçersiz istek"})
-        return
-    }
-    // E-posta var/yok, doğrulanmış/ değil — ayrıntı sızdırmamak için her durumda 200 döneceğiz.
-    _ = h.Svc.ResendVerification(c.Request.Context(), req.Email)
-    c.JSON(http.StatusOK, gin.H{"ok": true})
-}
-
 func (h *AuthHTTP) Login(w http.ResponseWriter, r *http.Request) {
 	var in struct {
 		Email    string `json:"email"`
 		Password string `json:"password"`
 	}
-	if err := json.NewDecoder(r.Body).Decode(&in); err != nil {
-		writeJSON(w, 400, jsonMap{"error":"Geçersiz JSON"}); return
+	if err := validate.Decode(w, r, &in); err != nil {
+		fail(w, err); return
+	}
+	in.Email = validate.NormalizeEmail(in.Email)
+	fe := validate.Errors{}
+	validate.Required(fe, "email", in.Email)
+	validate.Required(fe, "password", in.Password)
+	if err := fe.Err(); err != nil {
+		fail(w, err); return
 	}
 
 	// 👉 AuthService.Login JWT üretir
-	token, err := h.S.Login(in.Email, in.Password)
+	res, err := h.S.Login(in.Email, in.Password)
 	if err != nil {
 		writeJSON(w, 401, jsonMap{"error":"E-posta/şifre hatalı veya doğrulanmamış hesap"}); return
 	}
+	// 2FA açık: cookie yazma, ikinci adım /api/auth/mfa
+	if res.MFARequired {
+		writeJSON(w, 200, jsonMap{"mfa_required": true, "mfa_token": res.Token}); return
+	}
+	token := res.Token
 
 	// 👉 BURASI: JWT’yi HttpOnly cookie olarak yaz
 	http.SetCookie(w, &http.Cookie{
@@ -122,23 +150,41 @@ func (h *AuthHTTP) Logout(w http.ResponseWriter, r *http.Request) {
 }
 
 func (h *AuthHTTP) Me(w http.ResponseWriter, r *http.Request) {
-	c, err := r.Cookie("auth")
-	if err != nil || c.Value == "" {
+	uid, err := h.S.ParseToken(requestToken(r))
+	if err != nil || uid == 0 {
 		writeJSON(w, 401, jsonMap{"error":"Giriş gerekli"}); return
 	}
-	uid, err := h.S.ParseToken(c.Value)
-	if err != nil || uid == 0 {
+	p, err := h.A.Profile(uid)
+	if err != nil {
+		// token geçerli ama hesap silinmiş olabilir
 		writeJSON(w, 401, jsonMap{"error":"Giriş gerekli"}); return
 	}
-	// İstersen burada DB’den e-posta da çekip döndürebilirsin
-	writeJSON(w, 200, jsonMap{"id": uid})
-
+	writeJSON(w, 200, p)
+}
 
+// requestToken Bearer header'ı, yoksa "session" (gin login) veya "auth" cookie'sini döner.
+func requestToken(r *http.Request) string {
+	if ah := r.Header.Get("Authorization"); strings.HasPrefix(ah, "Bearer ") {
+		return strings.TrimPrefix(ah, "Bearer ")
+	}
+	for _, name := range []string{"session", "auth"} {
+		if c, err := r.Cookie(name); err == nil && c.Value != "" {
+			return c.Value
+		}
+	}
+	return ""
 }
+
 func (h *AuthHTTP) Resend(w http.ResponseWriter, r *http.Request) {
     var in struct{ Email string `json:"email"` }
-    if err := json.NewDecoder(r.Body).Decode(&in); err != nil || in.Email == "" {
-        writeJSON(w, 400, jsonMap{"error": "Geçersiz JSON"}); return
+    if err := validate.Decode(w, r, &in); err != nil {
+        fail(w, err); return
+    }
+    in.Email = validate.NormalizeEmail(in.Email)
+    fe := validate.Errors{}
+    validate.Email(fe, "email", in.Email)
+    if err := fe.Err(); err != nil {
+        fail(w, err); return
     }
     // Kullanıcı yoksa bile service sessiz döner (enumeration engeli)
     if err := h.S.ResendCode(in.Email); err != nil {
@@ -1,55 +1,549 @@
 package model
-import "time"
+import (
+	"database/sql/driver"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"time"
+
+	"gorm.io/gorm"
+
+	"example.com/ecom-go/internal/money"
+)
 
 type Product struct {
   ID         uint      `gorm:"primaryKey"`
-  Name       string
-  ImageURL   string
+  Name        string
+  Description string
+  ImageURL    string
   PriceCents int64
+  Currency   string `gorm:"size:3;not null;default:'TRY'"` // PriceCents'in para birimi
+  TaxClass   string `gorm:"size:32;not null;default:'standard'"` // TaxRate.Class
+  WeightGrams int   `gorm:"not null;default:0"` // ağırlığa göre kargo için
   CreatedAt  time.Time
   UpdatedAt  time.Time
+  // Price istenen para biriminde gösterim fiyatı (servis doldurur)
+  Price      *money.Money `gorm:"-" json:",omitempty"`
+  Categories []Category `gorm:"many2many:product_categories" json:",omitempty"`
+  Options    []ProductOption  `json:",omitempty"`
+  Variants   []ProductVariant `json:",omitempty"`
+  Images     []ProductImage   `json:",omitempty"`
+}
+
+// ProductImage yüklenen ürün görseli; dosya storage'da Key altında durur.
+// Position 0 olan kapak görselidir (Product.ImageURL onun thumbnail'i).
+type ProductImage struct {
+	ID          uint   `gorm:"primaryKey"`
+	ProductID   uint   `gorm:"index;not null"`
+	Key         string `gorm:"uniqueIndex;not null" json:"-"`
+	ContentType string `gorm:"not null"`
+	Width       int
+	Height      int
+	Position    int `gorm:"not null;default:0"`
+	CreatedAt   time.Time
+	URL         string `gorm:"-"`
+	ThumbURL    string `gorm:"-"`
+}
+
+// ListThumbWidth ürün listesinde kullanılan thumbnail genişliği.
+const ListThumbWidth = 320
+
+// ImageURL /media altındaki orijinal; ImageThumbURL liste görünümü için küçültülmüş hali.
+func ImageURL(id uint) string            { return fmt.Sprintf("/media/images/%d", id) }
+func ImageThumbURL(id uint, w int) string { return fmt.Sprintf("/media/images/%d/thumb/%d", id, w) }
+
+func (i *ProductImage) AfterFind(*gorm.DB) error {
+	i.URL, i.ThumbURL = ImageURL(i.ID), ImageThumbURL(i.ID, ListThumbWidth)
+	return nil
+}
+
+// ProductOption ürün seçeneği ("Beden", "Renk") ve alabileceği değerler.
+type ProductOption struct {
+	ID        uint   `gorm:"primaryKey"`
+	ProductID uint   `gorm:"index;not null"`
+	Name      string `gorm:"not null"`
+	Position  int    `gorm:"not null;default:0"`
+	Values    []ProductOptionValue `gorm:"foreignKey:OptionID" json:",omitempty"`
+}
+
+type ProductOptionValue struct {
+	ID       uint   `gorm:"primaryKey"`
+	OptionID uint   `gorm:"index;not null"`
+	Value    string `gorm:"not null"`
+	Position int    `gorm:"not null;default:0"`
+	Option   *ProductOption `gorm:"foreignKey:OptionID" json:",omitempty"`
+}
+
+// ProductVariant satılabilir birim: her seçenekten bir değer (M + Siyah).
+// PriceCents nil ise ürünün fiyatı, ImageURL boşsa ürünün görseli geçerli.
+type ProductVariant struct {
+	ID         uint   `gorm:"primaryKey"`
+	ProductID  uint   `gorm:"index;not null"`
+	SKU        string `gorm:"column:sku;uniqueIndex;not null"`
+	PriceCents *int64
+	ImageURL   string
+	Stock      int `gorm:"not null;default:0"`
+	Values     []ProductOptionValue `gorm:"many2many:variant_option_values"`
+	CreatedAt  time.Time
+	UpdatedAt  time.Time
+	Price      *money.Money `gorm:"-" json:",omitempty"` // gösterim fiyatı
+}
+
+// BasePrice ürünün kendi para birimindeki fiyatı.
+func (p Product) BasePrice() money.Money {
+	return money.New(p.PriceCents, money.Currency(p.Currency))
+}
+
+// BasePrice varyantın geçerli birim fiyatı (override ürünün para biriminde).
+func (v ProductVariant) BasePrice(p Product) money.Money {
+	if v.PriceCents != nil {
+		return money.New(*v.PriceCents, money.Currency(p.Currency))
+	}
+	return p.BasePrice()
+}
+
+// Selected seçilen değerleri seçenek sırasıyla döner (Values.Option preload'lu olmalı).
+func (v ProductVariant) Selected() SelectedOptions {
+	vals := append([]ProductOptionValue(nil), v.Values...)
+	sort.SliceStable(vals, func(i, j int) bool {
+		if vals[i].Option == nil || vals[j].Option == nil {
+			return false
+		}
+		return vals[i].Option.Position < vals[j].Option.Position
+	})
+	out := make(SelectedOptions, 0, len(vals))
+	for _, ov := range vals {
+		name := ""
+		if ov.Option != nil {
+			name = ov.Option.Name
+		}
+		out = append(out, SelectedOption{Name: name, Value: ov.Value})
+	}
+	return out
+}
+
+// SelectedOption sipariş anındaki seçenek/değer; OrderItem'da jsonb saklanır,
+// böylece varyant sonradan değişse de geçmiş sipariş doğru kalır.
+type SelectedOption struct {
+	Name  string `json:"name"`
+	Value string `json:"value"`
+}
+
+type SelectedOptions []SelectedOption
+
+func (o SelectedOptions) Value() (driver.Value, error) {
+	if o == nil {
+		return "[]", nil
+	}
+	b, err := json.Marshal(o)
+	return string(b), err
+}
+
+func (o *SelectedOptions) Scan(src any) error {
+	switch v := src.(type) {
+	case nil:
+		*o = nil
+		return nil
+	case []byte:
+		return json.Unmarshal(v, o)
+	case string:
+		return json.Unmarshal([]byte(v), o)
+	}
+	return fmt.Errorf("SelectedOptions: unsupported type %T", src)
+}
+
+// Category katalog ağacı düğümü; ParentID nil ise kök.
+type Category struct {
+	ID        uint   `gorm:"primaryKey"`
+	ParentID  *uint  `gorm:"index"`
+	Name      string `gorm:"not null"`
+	Slug      string `gorm:"uniqueIndex;not null"`
+	Position  int    `gorm:"not null;default:0"` // kardeşler arası sıra
+	CreatedAt time.Time
+	UpdatedAt time.Time
 }
 
 
 type User struct {
 	ID               uint       `gorm:"primaryKey"`
 	Email            string     `gorm:"uniqueIndex;not null"`
-	Password         string     `gorm:"column:password"`        // İstersen tutma ama map’li olsun
 	PasswordHash     string     `gorm:"column:password_hash"`   // BUNA bakacağız
 	Verified         bool       `gorm:"column:verified;not null;default:false"`
 	VerifiedAt       *time.Time `gorm:"column:verified_at"`
 	VerifyCode       *string    `gorm:"column:verify_code"`
 	VerifyExpiresAt  *time.Time `gorm:"column:verify_expires_at"`
+	// TOTP 2FA: secret enroll'da yazılır, ilk kod onaylanınca TOTPEnabled=true olur.
+	TOTPSecret       *string    `gorm:"column:totp_secret"`
+	TOTPEnabled      bool       `gorm:"column:totp_enabled;not null;default:false"`
+	TOTPLastStep     int64      `gorm:"column:totp_last_step;not null;default:0"` // replay engeli
+	PendingEmail     *string    `gorm:"column:pending_email"` // e-posta değişikliği kod ile onaylanana kadar
+	PasswordChangedAt *time.Time `gorm:"column:password_changed_at"` // bundan önce açılmış oturumlar geçersiz
+	IsAdmin          bool       `gorm:"column:is_admin;not null;default:false"` // SQL ile verilir
+	CreatedAt        time.Time
+	UpdatedAt        time.Time
 }
 
 func (User) TableName() string { return "users" }
 
+// UserIdentity harici (OIDC) kimliği kullanıcıya bağlar.
+type UserIdentity struct {
+	ID        uint   `gorm:"primaryKey"`
+	UserID    uint   `gorm:"index;not null"`
+	Provider  string `gorm:"uniqueIndex:idx_identity_provider_subject;not null"`
+	Subject   string `gorm:"uniqueIndex:idx_identity_provider_subject;not null"`
+	Email     string
+	CreatedAt time.Time
+}
+
+// MagicLink şifresiz giriş linki; JWT'nin jti'sinin hash'i tutulur, used_at ile tek kullanımlık.
+type MagicLink struct {
+	ID        uint   `gorm:"primaryKey"`
+	UserID    uint   `gorm:"index;not null"`
+	JTIHash   string `gorm:"column:jti_hash;uniqueIndex;not null"`
+	ExpiresAt time.Time
+	UsedAt    *time.Time
+	CreatedAt time.Time
+}
+
+// RecoveryCode 2FA kurtarma kodu; sadece SHA-256 hash'i saklanır, tek kullanımlık.
+// Tekillik kullanıcı başına: iki kullanıcıya aynı kod düşebilir.
+type RecoveryCode struct {
+	ID        uint   `gorm:"primaryKey"`
+	UserID    uint   `gorm:"not null;uniqueIndex:idx_recovery_user_code"`
+	CodeHash  string `gorm:"not null;uniqueIndex:idx_recovery_user_code"`
+	UsedAt    *time.Time
+	CreatedAt time.Time
+}
+
+// MFAChallenge bir "mfa" token'ıyla yapılan ikinci adım denemeleri (jti hash'i ile).
+// Deneme sınırı dolunca ya da başarılı girişte (UsedAt) token bir daha kullanılamaz.
+type MFAChallenge struct {
+	JTIHash   string `gorm:"column:jti_hash;primaryKey"`
+	UserID    uint   `gorm:"index;not null"`
+	Attempts  int    `gorm:"not null;default:0"`
+	UsedAt    *time.Time
+	ExpiresAt time.Time
+	CreatedAt time.Time
+}
+
 type CartItem struct {
 	ID        uint `gorm:"primaryKey"`
 	UserID    uint `gorm:"index"`
 	ProductID uint
+	VariantID *uint `gorm:"index"` // varyantı olan ürünlerde zorunlu
 	Qty       int
-	CreatedAt time.Time
-	UpdatedAt time.Time
-	Product   Product
+	// eklenirken görülen birim fiyat (ürünün para biriminde); checkout değişikliği yakalar
+	AddedPriceCents int64
+	AddedCurrency   string `gorm:"size:3"`
+	CreatedAt       time.Time
+	UpdatedAt       time.Time
+	Product         Product
+	Variant         *ProductVariant `json:",omitempty"`
+	// gösterim para biriminde birim fiyat ve satır toplamı (servis doldurur)
+	UnitPrice *money.Money `gorm:"-" json:",omitempty"`
+	LineTotal *money.Money `gorm:"-" json:",omitempty"`
+}
+
+// AddedPrice sepete eklenirken görülen fiyat; eski kayıtlarda yok (ok=false).
+func (it CartItem) AddedPrice() (m money.Money, ok bool) {
+	if it.AddedCurrency == "" {
+		return m, false
+	}
+	return money.New(it.AddedPriceCents, money.Currency(it.AddedCurrency)), true
+}
+
+// BaseUnitPrice varyant varsa onun, yoksa ürünün fiyatı (ürünün para biriminde).
+func (it CartItem) BaseUnitPrice() money.Money {
+	if it.Variant != nil {
+		return it.Variant.BasePrice(it.Product)
+	}
+	return it.Product.BasePrice()
 }
 
+// Sipariş durumları. Kısmi iade durumu değiştirmez (RefundedCents > 0);
+// her şey iade edilince refunded olur.
+const (
+	OrderPlaced    = "placed"
+	OrderShipped   = "shipped"
+	OrderCancelled = "cancelled"
+	OrderRefunded  = "refunded"
+)
+
+// Order tutarları: TotalCents = SubtotalCents (net) + ShippingCents (net) + TaxCents.
+// TaxIncluded sipariş anında katalog fiyatlarının vergi dahil olup olmadığı.
 type Order struct {
-	ID         uint `gorm:"primaryKey"`
-	UserID     uint `gorm:"index"`
-	TotalCents int64
-	CreatedAt  time.Time
-	UpdatedAt  time.Time
-	Items      []OrderItem
+	ID              uint   `gorm:"primaryKey"`
+	UserID          uint   `gorm:"index"`
+	Status          string `gorm:"size:16;not null;default:'placed';index"`
+	SubtotalCents   int64
+	TaxCents        int64
+	TotalCents      int64
+	Currency        string `gorm:"size:3;not null;default:'TRY'"` // siparişin tüm tutarları bu birimde
+	TaxIncluded     bool   `gorm:"not null;default:false"`
+	TaxJurisdiction string `gorm:"size:2"`
+	// kargo: net tutar + vergisi (TaxCents'e dahil, fatura dökümü için ayrıca)
+	ShippingMethod         string `gorm:"size:32"` // ShippingMethod.Code snapshot'ı
+	ShippingCents          int64
+	ShippingTaxCents       int64
+	ShippingTaxBasisPoints int // fatura KDV dökümü için
+	// teslimat adresi snapshot'ı: adres defteri sonradan değişse de sipariş değişmez
+	ShipTo    PostalAddress `gorm:"embedded;embeddedPrefix:ship_"`
+	// indirimler katalog modunda (vergi dahil/hariç) ve satırlara dağıtılmış halde
+	DiscountCents int64
+	RefundedCents int64 // iade edilen toplam (vergi dahil)
+	ShippedAt     *time.Time
+	CancelledAt   *time.Time
+	CreatedAt     time.Time
+	UpdatedAt     time.Time
+	Items         []OrderItem
+	Discounts     []OrderDiscount `json:",omitempty"`
+	Refunds       []Refund        `json:",omitempty"`
 }
 
 type OrderItem struct {
 	ID         uint `gorm:"primaryKey"`
 	OrderID    uint `gorm:"index"`
 	ProductID  uint
+	VariantID  *uint           // bilgi amaçlı; varyant silinse de satır kalır
+	SKU        string          `gorm:"column:sku"`
+	Options    SelectedOptions `gorm:"type:jsonb;not null;default:'[]'"`
 	Name       string
-	PriceCents int64
+	PriceCents int64 // katalogdaki gibi: TaxIncluded ise vergi dahil birim fiyat
+	Currency   string `gorm:"size:3;not null;default:'TRY'"`
 	Qty        int
-	CreatedAt  time.Time
-	UpdatedAt  time.Time
+	// vergi snapshot'ı: oran sonradan değişse de fatura tutar
+	TaxClass       string `gorm:"size:32"`
+	TaxBasisPoints int    // 2000 = %20
+	DiscountCents  int64  // satıra düşen indirim (vergi bundan sonra hesaplanır)
+	NetCents       int64  // satır neti (indirim sonrası)
+	TaxCents       int64  // satır vergisi
+	RefundedQty    int    `gorm:"not null;default:0"`
+	RefundedCents  int64  `gorm:"not null;default:0"`
+	CreatedAt      time.Time
+	UpdatedAt      time.Time
+}
+
+func (o Order) Total() money.Money    { return money.New(o.TotalCents, money.Currency(o.Currency)) }
+func (o Order) Subtotal() money.Money { return money.New(o.SubtotalCents, money.Currency(o.Currency)) }
+func (o Order) Tax() money.Money      { return money.New(o.TaxCents, money.Currency(o.Currency)) }
+
+func (o Order) Refunded() money.Money { return money.New(o.RefundedCents, money.Currency(o.Currency)) }
+
+func (oi OrderItem) UnitPrice() money.Money {
+	return money.New(oi.PriceCents, money.Currency(oi.Currency))
+}
+
+func (oi OrderItem) LineTotal() money.Money { return oi.UnitPrice().Mul(int64(oi.Qty)) }
+
+// Gross satırın vergi dahil tutarı (net + vergi).
+func (oi OrderItem) Gross() money.Money {
+	return money.New(oi.NetCents+oi.TaxCents, money.Currency(oi.Currency))
+}
+
+// TaxRate bir vergi bölgesinde (ülke kodu) bir vergi sınıfının oranı.
+// Oran on binde bir cinsinden tam sayı: float yok, KDV %1/%10/%20 → 100/1000/2000.
+type TaxRate struct {
+	ID           uint   `gorm:"primaryKey"`
+	Jurisdiction string `gorm:"size:2;not null;uniqueIndex:idx_tax_rate_class"`
+	Class        string `gorm:"size:32;not null;uniqueIndex:idx_tax_rate_class"`
+	Name         string `gorm:"not null"` // faturada görünen ad ("KDV %20")
+	BasisPoints  int    `gorm:"not null"`
+	CreatedAt    time.Time
+	UpdatedAt    time.Time
+}
+
+// ExchangeRate admin'in girdiği kur: 1 From = Rate To. Ters yön ayrıca
+// girilmemişse 1/Rate kullanılır.
+type ExchangeRate struct {
+	ID        uint   `gorm:"primaryKey"`
+	From      string `gorm:"column:from_currency;size:3;not null;uniqueIndex:idx_rate_pair"`
+	To        string `gorm:"column:to_currency;size:3;not null;uniqueIndex:idx_rate_pair"`
+	Rate      string `gorm:"type:numeric(20,10);not null"` // float yok: big.Rat ile okunur
+	CreatedAt time.Time
+	UpdatedAt time.Time
+}
+
+// PostalAddress adres defterinde ve sipariş snapshot'ında ortak alanlar.
+type PostalAddress struct {
+	FullName   string `gorm:"size:100"`
+	Phone      string `gorm:"size:32"`
+	Line1      string `gorm:"size:200"`
+	Line2      string `gorm:"size:200"`
+	District   string `gorm:"size:100"` // ilçe
+	City       string `gorm:"size:100"`
+	PostalCode string `gorm:"size:16"`
+	Country    string `gorm:"size:2"` // ISO 3166-1 alpha-2; vergi bölgesi de buradan
+}
+
+// Address kullanıcının adres defteri kaydı. Kullanıcı başına en fazla bir
+// IsDefault (kısmi unique index, migrate.go).
+type Address struct {
+	ID        uint   `gorm:"primaryKey"`
+	UserID    uint   `gorm:"index;not null" json:"-"`
+	Label     string `gorm:"size:50"` // "Ev", "İş"
+	PostalAddress
+	IsDefault bool `gorm:"not null;default:false"`
+	CreatedAt time.Time
+	UpdatedAt time.Time
+}
+
+// Kargo ücret kuralları.
+const (
+	ShippingFlat     = "flat"      // her siparişe FeeCents
+	ShippingFreeOver = "free_over" // ara toplam FreeOverCents'e ulaşırsa ücretsiz, yoksa FeeCents
+	ShippingWeight   = "weight"    // FeeCents + başlayan her kg için PerKgCents
+)
+
+// ShippingMethod checkout'ta seçilebilen kargo seçeneği; tutarlar Currency'de.
+type ShippingMethod struct {
+	ID            uint   `gorm:"primaryKey"`
+	Code          string `gorm:"size:32;uniqueIndex;not null"`
+	Name          string `gorm:"not null"`
+	Kind          string `gorm:"size:16;not null"`
+	Currency      string `gorm:"size:3;not null;default:'TRY'"`
+	FeeCents      int64  `gorm:"not null;default:0"`
+	FreeOverCents int64  `gorm:"not null;default:0"` // free_over eşiği (vergi dahil ara toplam)
+	PerKgCents    int64  `gorm:"not null;default:0"`
+	Countries     string // virgülle ayrılmış ülke kodları; boşsa her yer
+	TaxClass      string `gorm:"size:32;not null;default:'standard'"`
+	Active        bool   `gorm:"not null"`
+	Position      int    `gorm:"not null;default:0"`
+	CreatedAt     time.Time
+	UpdatedAt     time.Time
+}
+
+// Promosyon türleri.
+const (
+	PromoPercent  = "percent"     // uygun satırlarda PercentBP kadar
+	PromoFixed    = "fixed"       // uygun satırlardan toplam AmountCents kadar
+	PromoBuyXGetY = "buy_x_get_y" // her BuyQty+GetQty uygun üründen en ucuz GetQty bedava
+)
+
+// Promotion kupon (Code dolu) ya da otomatik kampanya (Code nil).
+// Products ve Categories boşsa tüm ürünlerde geçerli; kategoriler alt
+// kategorileri de kapsar. Kullanım sınırları sadece kuponlarda.
+type Promotion struct {
+	ID            uint    `gorm:"primaryKey"`
+	Name          string  `gorm:"not null"`
+	Code          *string `gorm:"size:32;uniqueIndex"` // büyük harfle saklanır
+	Kind          string  `gorm:"size:16;not null"`
+	PercentBP     int     `gorm:"column:percent_bp;not null;default:0"` // 1000 = %10
+	AmountCents   int64   `gorm:"not null;default:0"`
+	Currency      string  `gorm:"size:3;not null;default:'TRY'"` // AmountCents ve MinOrderCents birimi
+	BuyQty        int     `gorm:"not null;default:0"`
+	GetQty        int     `gorm:"not null;default:0"`
+	MinOrderCents int64   `gorm:"not null;default:0"` // indirim öncesi sepet toplamı
+	UsageLimit    int     `gorm:"not null;default:0"`  // 0 → sınırsız
+	PerUserLimit  int     `gorm:"not null;default:0"`
+	UsedCount     int     `gorm:"not null;default:0"`
+	StartsAt      *time.Time
+	EndsAt        *time.Time
+	Active        bool       `gorm:"not null"`
+	Products      []Product  `gorm:"many2many:promotion_products" json:",omitempty"`
+	Categories    []Category `gorm:"many2many:promotion_categories" json:",omitempty"`
+	CreatedAt     time.Time
+	UpdatedAt     time.Time
+}
+
+// PromotionRedemption siparişte kullanılan promosyon; kişi başı sınır buradan sayılır.
+type PromotionRedemption struct {
+	ID          uint `gorm:"primaryKey"`
+	PromotionID uint `gorm:"index;not null"`
+	UserID      uint `gorm:"index;not null"`
+	OrderID     uint `gorm:"index;not null"`
+	CreatedAt   time.Time
+}
+
+// CartCoupon kullanıcının sepetine uygulanmış kupon (sepet başına bir tane).
+type CartCoupon struct {
+	UserID      uint `gorm:"primaryKey;autoIncrement:false"`
+	PromotionID uint `gorm:"not null"`
+	CreatedAt   time.Time
+}
+
+// OrderDiscount siparişte uygulanan indirim satırı (snapshot: promosyon silinse de kalır).
+type OrderDiscount struct {
+	ID          uint `gorm:"primaryKey"`
+	OrderID     uint `gorm:"index;not null"`
+	PromotionID uint
+	Code        string `gorm:"size:32"`
+	Name        string
+	AmountCents int64
+	Currency    string `gorm:"size:3;not null;default:'TRY'"`
+	CreatedAt   time.Time
+}
+
+// Refund bir iade hareketi. OrderItemID nil → kargo bedeli iadesi.
+// Tutarlar siparişin para biriminde, vergi dahil; TaxCents bunun vergi payı.
+type Refund struct {
+	ID          uint  `gorm:"primaryKey"`
+	OrderID     uint  `gorm:"index;not null"`
+	OrderItemID *uint `gorm:"index"`
+	Qty         int
+	AmountCents int64
+	TaxCents    int64
+	Currency    string `gorm:"size:3;not null"`
+	Reason      string `gorm:"size:255"`
+	Restocked   bool   `gorm:"not null;default:false"`
+	CreatedAt   time.Time
+}
+
+// İade talebi (RMA) durumları: requested → approved → received → refunded,
+// ya da requested → rejected.
+const (
+	ReturnRequested = "requested"
+	ReturnApproved  = "approved"
+	ReturnRejected  = "rejected"
+	ReturnReceived  = "received"
+	ReturnRefunded  = "refunded"
+)
+
+// ReturnRequest kargolanmış bir siparişin satırları için kullanıcının açtığı iade talebi.
+type ReturnRequest struct {
+	ID            uint   `gorm:"primaryKey"`
+	OrderID       uint   `gorm:"index;not null"`
+	UserID        uint   `gorm:"index"`
+	Status        string `gorm:"size:16;not null;default:'requested';index"`
+	Reason        string `gorm:"size:500;not null"`
+	Restocked     bool   `gorm:"not null;default:false"` // teslim alınınca stoğa eklendi mi
+	RefundedCents int64
+	CreatedAt     time.Time
+	UpdatedAt     time.Time
+	Items         []ReturnItem
+	History       []ReturnEvent `gorm:"foreignKey:ReturnRequestID"`
+}
+
+type ReturnItem struct {
+	ID              uint `gorm:"primaryKey"`
+	ReturnRequestID uint `gorm:"index;not null"`
+	OrderItemID     uint `gorm:"not null"`
+	Qty             int  `gorm:"not null"`
+}
+
+// ReturnEvent durum geçmişi; her geçiş bir satır (not: admin açıklaması).
+type ReturnEvent struct {
+	ID              uint   `gorm:"primaryKey"`
+	ReturnRequestID uint   `gorm:"index;not null"`
+	Status          string `gorm:"size:16;not null"`
+	Note            string `gorm:"size:500"`
+	CreatedAt       time.Time
+}
+
+// Invoice bir siparişin faturası. Numara seri içinde boşluksuz artar
+// (InvoiceCounter checkout tx'inde kilitlenir); PDF depoda Key altında.
+type Invoice struct {
+	ID        uint   `gorm:"primaryKey"`
+	OrderID   uint   `gorm:"uniqueIndex;not null"`
+	Series    string `gorm:"size:16;not null;uniqueIndex:idx_invoice_seq"`
+	Seq       int64  `gorm:"not null;uniqueIndex:idx_invoice_seq"`
+	Number    string `gorm:"size:32;not null;uniqueIndex"` // "INV-2026-000042"
+	Key       string `json:"-"`                            // storage anahtarı; boş → henüz üretilmedi
+	IssuedAt  time.Time
+	CreatedAt time.Time
+}
+
+// InvoiceCounter seri başına son verilen numara.
+type InvoiceCounter struct {
+	Series string `gorm:"primaryKey;size:16"`
+	Last   int64  `gorm:"not null;default:0"`
 }
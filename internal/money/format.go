@@ -0,0 +1,79 @@
+package money
+
+import (
+	"strconv"
+	"strings"
+)
+
+// locale ayırıcılar ve sembol yerleşimi.
+type localeInfo struct {
+	decimal, group string
+	symbolAfter    bool // "19,99 ₺" / "$19.99"
+}
+
+var locales = map[string]localeInfo{
+	"tr-TR": {",", ".", true},
+	"en-US": {".", ",", false},
+	"en-GB": {".", ",", false},
+	"de-DE": {",", ".", true},
+	"fr-FR": {",", " ", true},
+}
+
+// kısa etiketler ("tr", "en") → bölgesel varsayılan
+var localeAliases = map[string]string{"tr": "tr-TR", "en": "en-US", "de": "de-DE", "fr": "fr-FR"}
+
+// DefaultLocale tanınmayan locale'ler için.
+const DefaultLocale = "tr-TR"
+
+// Format tutarı locale'e göre yazar: Format(New(123456, "TRY"), "tr-TR") → "1.234,56 ₺".
+// Sadece tam sayı/string işlemi; float yuvarlama hatası olmaz.
+func (m Money) Format(locale string) string {
+	l, ok := locales[locale]
+	if !ok {
+		l, ok = locales[localeAliases[locale]]
+	}
+	if !ok {
+		l = locales[DefaultLocale]
+	}
+	num := decimal(m.Amount, m.Currency.Exp(), l.decimal, l.group)
+	sign := ""
+	if strings.HasPrefix(num, "-") {
+		sign, num = "-", num[1:]
+	}
+	if l.symbolAfter {
+		return sign + num + " " + m.Currency.Symbol()
+	}
+	return sign + m.Currency.Symbol() + num
+}
+
+// decimal 123456, exp 2 → "1.234,56" (ayırıcılara göre).
+func decimal(amount int64, exp int, dec, group string) string {
+	neg := amount < 0
+	u := uint64(amount)
+	if neg {
+		u = uint64(-amount)
+	}
+	digits := []byte(strings.Repeat("0", exp+1))
+	if s := strconv.FormatUint(u, 10); len(s) > exp {
+		digits = []byte(s)
+	} else {
+		copy(digits[len(digits)-len(s):], s)
+	}
+	intPart, frac := string(digits[:len(digits)-exp]), string(digits[len(digits)-exp:])
+
+	var b strings.Builder
+	if neg {
+		b.WriteByte('-')
+	}
+	for i, ch := range intPart {
+		if i > 0 && (len(intPart)-i)%3 == 0 {
+			b.WriteString(group)
+		}
+		b.WriteRune(ch)
+	}
+	if exp > 0 {
+		b.WriteString(dec)
+		b.WriteString(frac)
+	}
+	return b.String()
+}
@@ -0,0 +1,131 @@
+// Package money para tutarlarını float kullanmadan taşır: tutar her zaman
+// para biriminin en küçük biriminde (kuruş, cent) int64, yanında ISO 4217 kodu.
+package money
+
+import (
+	"errors"
+	"fmt"
+	"math/big"
+	"strings"
+)
+
+var (
+	ErrUnknownCurrency  = errors.New("unknown currency")
+	ErrCurrencyMismatch = errors.New("currency mismatch")
+	ErrInvalidRate      = errors.New("invalid exchange rate")
+)
+
+// Currency ISO 4217 kodu ("TRY", "USD").
+type Currency string
+
+type currencyInfo struct {
+	exp    int    // küsurat hanesi (JPY 0)
+	symbol string // gösterim
+}
+
+var currencies = map[Currency]currencyInfo{
+	"TRY": {2, "₺"},
+	"USD": {2, "$"},
+	"EUR": {2, "€"},
+	"GBP": {2, "£"},
+	"JPY": {0, "¥"},
+}
+
+// ParseCurrency büyük/küçük harf duyarsız; desteklenmeyen kod hata.
+func ParseCurrency(code string) (Currency, error) {
+	c := Currency(strings.ToUpper(strings.TrimSpace(code)))
+	if _, ok := currencies[c]; !ok {
+		return "", fmt.Errorf("%w: %q", ErrUnknownCurrency, code)
+	}
+	return c, nil
+}
+
+// Exp küsurat hanesi sayısı.
+func (c Currency) Exp() int { return currencies[c].exp }
+
+func (c Currency) Symbol() string {
+	if s := currencies[c].symbol; s != "" {
+		return s
+	}
+	return string(c)
+}
+
+// Money tutar (en küçük birim) + para birimi.
+type Money struct {
+	Amount   int64    `json:"amount"`
+	Currency Currency `json:"currency"`
+}
+
+func New(amount int64, c Currency) Money { return Money{Amount: amount, Currency: c} }
+
+func (m Money) IsZero() bool { return m.Amount == 0 }
+
+// Add farklı para birimlerini toplamaz.
+func (m Money) Add(o Money) (Money, error) {
+	if m.Currency != o.Currency {
+		return m, fmt.Errorf("%w: %s + %s", ErrCurrencyMismatch, m.Currency, o.Currency)
+	}
+	return Money{Amount: m.Amount + o.Amount, Currency: m.Currency}, nil
+}
+
+func (m Money) Sub(o Money) (Money, error) {
+	return m.Add(Money{Amount: -o.Amount, Currency: o.Currency})
+}
+
+// Mul birim fiyat × adet.
+func (m Money) Mul(n int64) Money { return Money{Amount: m.Amount * n, Currency: m.Currency} }
+
+// String "19.99 TRY" (log/debug için; kullanıcıya Format).
+func (m Money) String() string {
+	return fmt.Sprintf("%s %s", decimal(m.Amount, m.Currency.Exp(), ".", ""), m.Currency)
+}
+
+// ParseRate "34.2512" gibi ondalık kuru tam kesir olarak okur (float yok).
+func ParseRate(s string) (*big.Rat, error) {
+	r, ok := new(big.Rat).SetString(strings.TrimSpace(s))
+	if !ok || r.Sign() <= 0 {
+		return nil, ErrInvalidRate
+	}
+	return r, nil
+}
+
+// Convert 1 birim m.Currency = rate birim to olacak şekilde çevirir;
+// sonuç hedefin en küçük birimine yarım yukarı (sıfırdan uzağa) yuvarlanır.
+func Convert(m Money, to Currency, rate *big.Rat) Money {
+	if m.Currency == to {
+		return m
+	}
+	v := new(big.Rat).Mul(new(big.Rat).SetInt64(m.Amount), rate)
+	// küsurat hanesi farkı: 100 kuruş → 1 JPY birimi vb.
+	if d := to.Exp() - m.Currency.Exp(); d != 0 {
+		scale := new(big.Rat).SetInt(new(big.Int).Exp(big.NewInt(10), big.NewInt(int64(abs(d))), nil))
+		if d > 0 {
+			v.Mul(v, scale)
+		} else {
+			v.Quo(v, scale)
+		}
+	}
+	return Money{Amount: RoundHalfUp(v), Currency: to}
+}
+
+// RoundHalfUp kesri en yakın tam sayıya, .5'i sıfırdan uzağa yuvarlar.
+func RoundHalfUp(v *big.Rat) int64 {
+	num, den := new(big.Int).Set(v.Num()), v.Denom()
+	neg := num.Sign() < 0
+	num.Abs(num)
+	q, r := new(big.Int).QuoRem(num, den, new(big.Int))
+	if r.Mul(r, big.NewInt(2)).Cmp(den) >= 0 {
+		q.Add(q, big.NewInt(1))
+	}
+	if neg {
+		q.Neg(q)
+	}
+	return q.Int64()
+}
+
+func abs(n int) int {
+	if n < 0 {
+		return -n
+	}
+	return n
+}
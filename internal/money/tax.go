@@ -0,0 +1,24 @@
+package money
+
+import "math/big"
+
+// SplitTax satır tutarını net + vergi olarak ayırır. basisPoints oranın
+// on binde biri (KDV %20 → 2000). inclusive ise line vergi dahil brüttür,
+// değilse net tutardır. Vergi satır başına yarım yukarı yuvarlanır, diğer
+// parça farktan bulunur; böylece net + vergi her zaman brüte eşittir.
+func SplitTax(line Money, basisPoints int, inclusive bool) (net, tax Money) {
+	bp := big.NewInt(int64(basisPoints))
+	amount := new(big.Int).SetInt64(line.Amount)
+	var v *big.Rat
+	if inclusive {
+		// brüt × r / (1 + r)
+		v = new(big.Rat).SetFrac(new(big.Int).Mul(amount, bp), new(big.Int).Add(big.NewInt(10000), bp))
+	} else {
+		v = new(big.Rat).SetFrac(new(big.Int).Mul(amount, bp), big.NewInt(10000))
+	}
+	tax = Money{Amount: RoundHalfUp(v), Currency: line.Currency}
+	if inclusive {
+		return Money{Amount: line.Amount - tax.Amount, Currency: line.Currency}, tax
+	}
+	return line, tax
+}
@@ -0,0 +1,211 @@
+// Package pdf fatura gibi basit belgeler için küçük bir PDF yazıcısı:
+// A4 sayfa, standart Helvetica (normal/kalın), metin ve çizgi. Font gömülmez;
+// kodlama WinAnsi + Differences: WinAnsi'de olmayan Türkçe harfler (Ğ, İ, Ş,
+// ğ, ı, ş) Latin-5'teki (ISO-8859-9) yerlerine, Helvetica'nın kendi glifleriyle
+// (Gbreve, Idotaccent, ...) yazılır. O yerlerdeki İzlanda harfleri düşer.
+package pdf
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+	"unicode/utf16"
+)
+
+// A4 boyutu (pt).
+const (
+	PageWidth  = 595.28
+	PageHeight = 841.89
+)
+
+type Font int
+
+const (
+	Regular Font = iota
+	Bold
+)
+
+// Document sayfaları sırayla biriktirir; Bytes tek seferde dosyayı üretir.
+type Document struct {
+	pages []*bytes.Buffer
+	title string
+}
+
+func New(title string) *Document {
+	d := &Document{title: title}
+	d.AddPage()
+	return d
+}
+
+// AddPage yeni sayfa açar; sonraki çizimler ona gider.
+func (d *Document) AddPage() { d.pages = append(d.pages, &bytes.Buffer{}) }
+
+func (d *Document) page() *bytes.Buffer { return d.pages[len(d.pages)-1] }
+
+// Text (x, y) sol-alt köşeden; y yukarı doğru artar.
+func (d *Document) Text(x, y float64, f Font, size float64, s string) {
+	fmt.Fprintf(d.page(), "BT /F%d %.2f Tf %.2f %.2f Td (%s) Tj ET\n", f+1, size, x, y, escape(encode(s)))
+}
+
+// TextRight metni sağ kenarı x'e gelecek şekilde yazar (tutar sütunları).
+func (d *Document) TextRight(x, y float64, f Font, size float64, s string) {
+	d.Text(x-Width(f, size, s), y, f, size, s)
+}
+
+func (d *Document) Line(x1, y1, x2, y2, width float64) {
+	fmt.Fprintf(d.page(), "%.2f w %.2f %.2f m %.2f %.2f l S\n", width, x1, y1, x2, y2)
+}
+
+// Width metnin pt cinsinden genişliği.
+func Width(f Font, size float64, s string) float64 {
+	w := 0
+	for _, c := range encode(s) {
+		w += glyphWidth(f, c)
+	}
+	return float64(w) * size / 1000
+}
+
+// Bytes xref tablosuyla birlikte tam PDF dosyası.
+func (d *Document) Bytes() []byte {
+	var b bytes.Buffer
+	var offsets []int
+	obj := func(body string) {
+		offsets = append(offsets, b.Len())
+		fmt.Fprintf(&b, "%d 0 obj\n%s\nendobj\n", len(offsets), body)
+	}
+	b.WriteString("%PDF-1.4\n%\xe2\xe3\xcf\xd3\n")
+
+	// 1 katalog, 2 sayfa ağacı, 3-4 fontlar, 5 bilgi; sonra sayfa + içerik çiftleri
+	n := len(d.pages)
+	kids := make([]string, n)
+	for i := range d.pages {
+		kids[i] = fmt.Sprintf("%d 0 R", 6+2*i)
+	}
+	obj("<< /Type /Catalog /Pages 2 0 R >>")
+	obj(fmt.Sprintf("<< /Type /Pages /Kids [%s] /Count %d >>", strings.Join(kids, " "), n))
+	obj("<< /Type /Font /Subtype /Type1 /BaseFont /Helvetica /Encoding " + fontEncoding + " >>")
+	obj("<< /Type /Font /Subtype /Type1 /BaseFont /Helvetica-Bold /Encoding " + fontEncoding + " >>")
+	obj(fmt.Sprintf("<< /Title %s /Producer (ecom-go) >>", textString(d.title)))
+	for i, p := range d.pages {
+		obj(fmt.Sprintf("<< /Type /Page /Parent 2 0 R /MediaBox [0 0 %.2f %.2f] /Resources << /Font << /F1 3 0 R /F2 4 0 R >> >> /Contents %d 0 R >>",
+			PageWidth, PageHeight, 7+2*i))
+		obj(fmt.Sprintf("<< /Length %d >>\nstream\n%sendstream", p.Len(), p.Bytes()))
+	}
+
+	xref := b.Len()
+	fmt.Fprintf(&b, "xref\n0 %d\n0000000000 65535 f \n", len(offsets)+1)
+	for _, off := range offsets {
+		fmt.Fprintf(&b, "%010d 00000 n \n", off)
+	}
+	fmt.Fprintf(&b, "trailer\n<< /Size %d /Root 1 0 R /Info 5 0 R >>\nstartxref\n%d\n%%%%EOF\n", len(offsets)+1, xref)
+	return b.Bytes()
+}
+
+// textString /Info gibi font dışı metinler: UTF-16BE hex string (BOM ile).
+func textString(s string) string {
+	var b strings.Builder
+	b.WriteString("<FEFF")
+	for _, u := range utf16.Encode([]rune(s)) {
+		fmt.Fprintf(&b, "%04X", u)
+	}
+	b.WriteString(">")
+	return b.String()
+}
+
+// escape PDF string literal'inde özel karakterler.
+func escape(s []byte) string {
+	var b strings.Builder
+	for _, c := range s {
+		switch c {
+		case '(', ')', '\\':
+			b.WriteByte('\\')
+			b.WriteByte(c)
+		default:
+			b.WriteByte(c)
+		}
+	}
+	return b.String()
+}
+
+// turkish Latin-5 yerleri; fontEncoding'in Differences'ı bu kodlara glif adlarını verir.
+var turkish = map[rune]byte{
+	'Ğ': 0xd0, 'İ': 0xdd, 'Ş': 0xde, 'ğ': 0xf0, 'ı': 0xfd, 'ş': 0xfe,
+}
+
+// fontEncoding iki font için ortak kodlama sözlüğü.
+const fontEncoding = "<< /Type /Encoding /BaseEncoding /WinAnsiEncoding " +
+	"/Differences [208 /Gbreve 221 /Idotaccent /Scedilla 240 /gbreve 253 /dotlessi /scedilla] >>"
+
+// Kodlamada karşılığı olmayan harfler için yakın karşılık (Türkçe harflere
+// verilen yerlerdeki İzlanda harfleri dahil).
+var fallback = map[rune]string{
+	'Ð': "D", 'Ý': "Y", 'Þ': "Th", 'ð': "d", 'ý': "y", 'þ': "th",
+	'₺': "TL", '’': "'", '‘': "'", '“': "\"", '”': "\"", '–': "-", '—': "-", '→': "->",
+}
+
+// encode UTF-8 → font kodlaması (WinAnsi + Türkçe harfler) baytları.
+func encode(s string) []byte {
+	out := make([]byte, 0, len(s))
+	for _, r := range s {
+		switch {
+		case r == '€':
+			out = append(out, 0x80)
+		case turkish[r] != 0:
+			out = append(out, turkish[r])
+		case fallback[r] != "":
+			out = append(out, fallback[r]...)
+		case r >= 0x20 && r < 0x7f, r >= 0xa0 && r <= 0xff:
+			out = append(out, byte(r))
+		case r == '\t' || r == '\n':
+			out = append(out, ' ')
+		default:
+			out = append(out, '?')
+		}
+	}
+	return out
+}
+
+// Helvetica / Helvetica-Bold AFM genişlikleri, ' ' (32) … '~' (126).
+var widths = [2][95]int{
+	{
+		278, 278, 355, 556, 556, 889, 667, 191, 333, 333, 389, 584, 278, 333, 278, 278,
+		556, 556, 556, 556, 556, 556, 556, 556, 556, 556, 278, 278, 584, 584, 584, 556,
+		1015, 667, 667, 722, 722, 667, 611, 778, 722, 278, 500, 667, 556, 833, 722, 778,
+		667, 778, 722, 667, 611, 722, 667, 944, 667, 667, 611, 278, 278, 278, 469, 556,
+		333, 556, 556, 500, 556, 556, 278, 556, 556, 222, 222, 500, 222, 833, 556, 556,
+		556, 556, 333, 500, 278, 556, 500, 722, 500, 500, 500, 334, 260, 334, 584,
+	},
+	{
+		278, 333, 474, 556, 556, 889, 722, 238, 333, 333, 389, 584, 278, 333, 278, 278,
+		556, 556, 556, 556, 556, 556, 556, 556, 556, 556, 333, 333, 584, 584, 584, 611,
+		975, 722, 722, 722, 722, 667, 611, 778, 722, 278, 556, 722, 611, 833, 722, 778,
+		667, 778, 722, 667, 611, 722, 667, 944, 667, 667, 611, 333, 278, 333, 584, 556,
+		333, 556, 611, 556, 611, 556, 333, 611, 611, 278, 278, 556, 278, 889, 611, 611,
+		611, 611, 389, 556, 333, 611, 556, 778, 556, 556, 500, 389, 280, 389, 584,
+	},
+}
+
+// Latin-1 üst yarısı: aksanlı harf genişliği taban harfinkiyle aynı.
+var latinBase = map[byte]byte{
+	0xc7: 'C', 0xd6: 'O', 0xdc: 'U', 0xe7: 'c', 0xf6: 'o', 0xfc: 'u',
+	0xc2: 'A', 0xe2: 'a', 0xce: 'I', 0xee: 'i', 0xdb: 'U', 0xfb: 'u',
+}
+
+// Differences glifleri (AFM); dotlessi taban 'i'den geniş olduğu için ayrı tablo.
+var turkishWidths = [2]map[byte]int{
+	{0xd0: 778, 0xdd: 278, 0xde: 667, 0xf0: 556, 0xfd: 278, 0xfe: 500},
+	{0xd0: 778, 0xdd: 278, 0xde: 667, 0xf0: 611, 0xfd: 278, 0xfe: 556},
+}
+
+func glyphWidth(f Font, c byte) int {
+	if w, ok := turkishWidths[f][c]; ok {
+		return w
+	}
+	if b, ok := latinBase[c]; ok {
+		c = b
+	}
+	if c >= 32 && c <= 126 {
+		return widths[f][c-32]
+	}
+	return 556
+}
@@ -0,0 +1,218 @@
+package service
+
+import (
+	"strings"
+	"time"
+
+	"gorm.io/gorm"
+
+	"example.com/ecom-go/internal/model"
+	"example.com/ecom-go/internal/validate"
+)
+
+// Profile /api/me cevabı; hassas kolonlar (hash, kodlar, TOTP secret) yok.
+type Profile struct {
+	ID           uint       `json:"id"`
+	Email        string     `json:"email"`
+	Verified     bool       `json:"verified"`
+	VerifiedAt   *time.Time `json:"verified_at,omitempty"`
+	PendingEmail *string    `json:"pending_email,omitempty"`
+	TOTPEnabled  bool       `json:"totp_enabled"`
+	CreatedAt    time.Time  `json:"created_at"`
+}
+
+type AccountService interface {
+	Profile(userID uint) (Profile, error)
+	// ChangePassword şifresiz hesapta ilk şifreyi de koyar (yeni giriş şartıyla); eski oturumlar düşer.
+	ChangePassword(userID uint, current, next string, authAt time.Time) error
+	// RequestEmailChange yeni adrese 6 haneli kod yollar; adres ConfirmEmailChange'e kadar değişmez.
+	// authAt oturumun açıldığı an: şifresiz hesaplarda şifre yerine yeni giriş aranır.
+	RequestEmailChange(userID uint, newEmail, password string, authAt time.Time) error
+	ConfirmEmailChange(userID uint, code string) error
+	// Delete hesabı siler; siparişler muhasebe için kalır ama kullanıcıdan koparılır.
+	Delete(userID uint, password string, authAt time.Time) error
+}
+
+// reauthWindow şifresiz (OIDC / magic link) hesapta hassas işlem için girişin tazeliği.
+const reauthWindow = 10 * time.Minute
+
+type accountService struct {
+	db     *gorm.DB
+	email  EmailService
+	policy PasswordPolicy
+	hasher PasswordHasher
+}
+
+func NewAccountService(db *gorm.DB, email EmailService) AccountService {
+	return &accountService{db: db, email: email, policy: NewPasswordPolicy(), hasher: NewPasswordHasher()}
+}
+
+func (s *accountService) Profile(userID uint) (Profile, error) {
+	var u model.User
+	if err := s.db.First(&u, userID).Error; err != nil {
+		return Profile{}, err
+	}
+	return Profile{
+		ID:           u.ID,
+		Email:        u.Email,
+		Verified:     u.Verified,
+		VerifiedAt:   u.VerifiedAt,
+		PendingEmail: u.PendingEmail,
+		TOTPEnabled:  u.TOTPEnabled,
+		CreatedAt:    u.CreatedAt,
+	}, nil
+}
+
+// reauth hassas işlemden önce kimliği tekrar doğrular: şifresi olan hesapta
+// şifre (hata field alanında), şifresi olmayanda son reauthWindow içinde açılmış
+// oturum. Hesap işlemleri ve 2FA yönetimi (mfa.go) ortak kullanır.
+func reauth(h PasswordHasher, u *model.User, field, password string, authAt time.Time) error {
+	if u.PasswordHash != "" {
+		if password == "" {
+			return validate.Errors{field: "is required"}
+		}
+		if ok, _ := h.Verify(u.PasswordHash, password); !ok {
+			return validate.Errors{field: "is incorrect"}
+		}
+		return nil
+	}
+	if authAt.IsZero() || time.Since(authAt) > reauthWindow {
+		return ErrReauthRequired
+	}
+	return nil
+}
+
+func (s *accountService) ChangePassword(userID uint, current, next string, authAt time.Time) error {
+	var u model.User
+	if err := s.db.First(&u, userID).Error; err != nil {
+		return err
+	}
+	// OIDC / magic link hesabında şifre yok: ilk şifre current yerine yeni girişle konur
+	if err := reauth(s.hasher, &u, "current_password", current, authAt); err != nil {
+		return err
+	}
+	if err := s.policy.Check("new_password", next, u.Email); err != nil {
+		return err
+	}
+	if current == next {
+		return validate.Errors{"new_password": "must differ from the current password"}
+	}
+	hash, err := s.hasher.Hash(next)
+	if err != nil {
+		return err
+	}
+	// iat saniye hassasiyetinde: aynı saniyede verilen yeni oturum geçerli kalsın
+	return s.db.Model(&model.User{}).Where("id = ?", userID).Updates(map[string]any{
+		"password_hash":       hash,
+		"password_changed_at": time.Now().Truncate(time.Second),
+	}).Error
+}
+
+func (s *accountService) emailTaken(email string, exceptID uint) (bool, error) {
+	var n int64
+	err := s.db.Model(&model.User{}).
+		Where("LOWER(email) = LOWER(?) AND id <> ?", email, exceptID).
+		Count(&n).Error
+	return n > 0, err
+}
+
+func (s *accountService) RequestEmailChange(userID uint, newEmail, password string, authAt time.Time) error {
+	var u model.User
+	if err := s.db.First(&u, userID).Error; err != nil {
+		return err
+	}
+	if err := reauth(s.hasher, &u, "password", password, authAt); err != nil {
+		return err
+	}
+	if strings.EqualFold(u.Email, newEmail) {
+		return validate.Errors{"email": "is already your email"}
+	}
+	taken, err := s.emailTaken(newEmail, u.ID)
+	if err != nil {
+		return err
+	}
+	if taken {
+		return ErrEmailTaken
+	}
+
+	code, err := gen6()
+	if err != nil {
+		return err
+	}
+	if err := s.db.Model(&model.User{}).Where("id = ?", u.ID).Updates(map[string]any{
+		"pending_email":     newEmail,
+		"verify_code":       code,
+		"verify_expires_at": time.Now().Add(15 * time.Minute),
+	}).Error; err != nil {
+		return err
+	}
+	return s.email.Send(newEmail, "E-posta Doğrulama Kodun", codeMailHTML(code))
+}
+
+func (s *accountService) ConfirmEmailChange(userID uint, code string) error {
+	code = strings.TrimSpace(code)
+
+	var u model.User
+	if err := s.db.First(&u, userID).Error; err != nil {
+		return err
+	}
+	if u.PendingEmail == nil || u.VerifyCode == nil || u.VerifyExpiresAt == nil {
+		return ErrNoPendingEmail
+	}
+	if time.Now().After(*u.VerifyExpiresAt) {
+		return ErrCodeExpired
+	}
+	if code != *u.VerifyCode {
+		return ErrInvalidCode
+	}
+	// kod beklerken başkası almış olabilir
+	taken, err := s.emailTaken(*u.PendingEmail, u.ID)
+	if err != nil {
+		return err
+	}
+	if taken {
+		return ErrEmailTaken
+	}
+
+	// yeni adres kodla kanıtlandı → doğrulanmış say
+	return s.db.Model(&model.User{}).Where("id = ?", u.ID).Updates(map[string]any{
+		"email":             *u.PendingEmail,
+		"pending_email":     nil,
+		"verified":          true,
+		"verified_at":       time.Now(),
+		"verify_code":       nil,
+		"verify_expires_at": nil,
+	}).Error
+}
+
+func (s *accountService) Delete(userID uint, password string, authAt time.Time) error {
+	var u model.User
+	if err := s.db.First(&u, userID).Error; err != nil {
+		return err
+	}
+	if err := reauth(s.hasher, &u, "password", password, authAt); err != nil {
+		return err
+	}
+	return s.db.Transaction(func(tx *gorm.DB) error {
+		// siparişler silinmez; user_id=0 ile anonim kalır
+		if err := tx.Model(&model.Order{}).Where("user_id = ?", userID).Update("user_id", 0).Error; err != nil {
+			return err
+		}
+		if err := tx.Model(&model.ReturnRequest{}).Where("user_id = ?", userID).Update("user_id", 0).Error; err != nil {
+			return err
+		}
+		if err := tx.Where("user_id = ?", userID).Delete(&model.CartItem{}).Error; err != nil {
+			return err
+		}
+		if err := tx.Where("user_id = ?", userID).Delete(&model.Address{}).Error; err != nil {
+			return err
+		}
+		if err := tx.Where("user_id = ?", userID).Delete(&model.RecoveryCode{}).Error; err != nil {
+			return err
+		}
+		if err := tx.Where("user_id = ?", userID).Delete(&model.UserIdentity{}).Error; err != nil {
+			return err
+		}
+		return tx.Delete(&model.User{}, userID).Error
+	})
+}
@@ -0,0 +1,146 @@
+package service
+
+import (
+	"errors"
+
+	"gorm.io/gorm"
+
+	"example.com/ecom-go/internal/model"
+)
+
+// MaxAddresses kullanıcı başına adres defteri sınırı.
+const MaxAddresses = 20
+
+type AddressInput struct {
+	Label string
+	model.PostalAddress
+	IsDefault bool
+}
+
+type AddressService interface {
+	// List varsayılan adres önce.
+	List(userID uint) ([]model.Address, error)
+	Get(userID, id uint) (model.Address, error)
+	// Default kullanıcının varsayılan adresi; yoksa ErrAddressNotFound.
+	Default(userID uint) (model.Address, error)
+	// Create ilk adres kendiliğinden varsayılan olur.
+	Create(userID uint, in AddressInput) (model.Address, error)
+	Update(userID, id uint, in AddressInput) (model.Address, error)
+	// Delete varsayılan silinirse en son eklenen adres varsayılan olur.
+	Delete(userID, id uint) error
+	SetDefault(userID, id uint) error
+}
+
+type addressService struct{ db *gorm.DB }
+
+func NewAddressService(db *gorm.DB) AddressService { return &addressService{db: db} }
+
+func (s *addressService) List(userID uint) ([]model.Address, error) {
+	var as []model.Address
+	return as, s.db.Where("user_id = ?", userID).Order("is_default desc, id desc").Find(&as).Error
+}
+
+func (s *addressService) Get(userID, id uint) (model.Address, error) {
+	return findAddress(s.db, userID, id)
+}
+
+func findAddress(tx *gorm.DB, userID, id uint) (model.Address, error) {
+	var a model.Address
+	err := tx.Where("id = ? AND user_id = ?", id, userID).First(&a).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return a, ErrAddressNotFound
+	}
+	return a, err
+}
+
+func (s *addressService) Default(userID uint) (model.Address, error) {
+	var a model.Address
+	err := s.db.Where("user_id = ? AND is_default", userID).First(&a).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return a, ErrAddressNotFound
+	}
+	return a, err
+}
+
+func (s *addressService) Create(userID uint, in AddressInput) (model.Address, error) {
+	a := model.Address{UserID: userID, Label: in.Label, PostalAddress: in.PostalAddress}
+	err := s.db.Transaction(func(tx *gorm.DB) error {
+		var n int64
+		if err := tx.Model(&model.Address{}).Where("user_id = ?", userID).Count(&n).Error; err != nil {
+			return err
+		}
+		if n >= MaxAddresses {
+			return ErrAddressLimit
+		}
+		if n == 0 || in.IsDefault {
+			if err := clearDefault(tx, userID); err != nil {
+				return err
+			}
+			a.IsDefault = true
+		}
+		return tx.Create(&a).Error
+	})
+	return a, err
+}
+
+func (s *addressService) Update(userID, id uint, in AddressInput) (model.Address, error) {
+	var a model.Address
+	err := s.db.Transaction(func(tx *gorm.DB) error {
+		var err error
+		if a, err = findAddress(tx, userID, id); err != nil {
+			return err
+		}
+		a.Label, a.PostalAddress = in.Label, in.PostalAddress
+		// varsayılanlık Update ile kaldırılmaz (başka adres SetDefault ile seçilir)
+		if in.IsDefault && !a.IsDefault {
+			if err := clearDefault(tx, userID); err != nil {
+				return err
+			}
+			a.IsDefault = true
+		}
+		return tx.Save(&a).Error
+	})
+	return a, err
+}
+
+func (s *addressService) Delete(userID, id uint) error {
+	return s.db.Transaction(func(tx *gorm.DB) error {
+		a, err := findAddress(tx, userID, id)
+		if err != nil {
+			return err
+		}
+		if err := tx.Delete(&a).Error; err != nil {
+			return err
+		}
+		if !a.IsDefault {
+			return nil
+		}
+		var next model.Address
+		err = tx.Where("user_id = ?", userID).Order("id desc").First(&next).Error
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		return tx.Model(&next).Update("is_default", true).Error
+	})
+}
+
+func (s *addressService) SetDefault(userID, id uint) error {
+	return s.db.Transaction(func(tx *gorm.DB) error {
+		a, err := findAddress(tx, userID, id)
+		if err != nil {
+			return err
+		}
+		if err := clearDefault(tx, userID); err != nil {
+			return err
+		}
+		return tx.Model(&a).Update("is_default", true).Error
+	})
+}
+
+// clearDefault kısmi unique index'e takılmamak için yeni varsayılandan önce çağrılır.
+func clearDefault(tx *gorm.DB, userID uint) error {
+	return tx.Model(&model.Address{}).Where("user_id = ? AND is_default", userID).Update("is_default", false).Error
+}
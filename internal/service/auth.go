@@ -6,12 +6,11 @@ import (
 	"fmt"
 	"log"
 	"math/big"
-	"os"
+	"strconv"
 	"strings"
 	"time"
 
 	"github.com/golang-jwt/jwt/v5"
-	"golang.org/x/crypto/bcrypt"
 	"gorm.io/gorm"
 
 	"example.com/ecom-go/internal/model"
@@ -21,18 +20,44 @@ type AuthService interface {
 	Register(email, password string) error
 	VerifyCode(email, code string) error
 	ResendCode(email string) error
-	Login(email, password string) (string, error) // returns JWT
-	ParseToken(token string) (uint, error)        // returns userID
-	VerifyEmail(token string) error               // legacy
+	Login(email, password string) (LoginResult, error)
+	VerifyMFA(mfaToken, code string) (string, error) // returns session JWT
+	ParseToken(token string) (uint, error)           // returns userID
+	// ParseSession userID'yi ve oturumun açıldığı anı (iat) döner; eski token'da iat yoksa sıfır.
+	// Şifre değişikliğinden önce açılmış oturumlar reddedilir.
+	ParseSession(token string) (uint, time.Time, error)
+	// NewSession şifre değişikliği sonrası mevcut istemciye yeni oturum verir.
+	NewSession(userID uint) (string, error)
+	VerifyEmail(token string) error                  // legacy
+
+	RequestMagicLink(email string) error
+	ConsumeMagicLink(token string) (LoginResult, error)
+
+	// EnrollTOTP / DisableTOTP şifre (şifresiz hesapta yeni giriş, authAt) ister.
+	EnrollTOTP(userID uint, password string, authAt time.Time) (secret, uri string, err error)
+	ConfirmTOTP(userID uint, code string) (recoveryCodes []string, err error)
+	DisableTOTP(userID uint, password, code string, authAt time.Time) error
+}
+
+// LoginResult: 2FA kapalıysa Token session JWT'dir; açıksa MFARequired=true ve
+// Token kısa ömürlü "mfa" token'ıdır (VerifyMFA ile takas edilir).
+type LoginResult struct {
+	Token       string
+	MFARequired bool
 }
 
 type authService struct {
-	db *gorm.DB
+	db     *gorm.DB
+	policy PasswordPolicy
+	hasher PasswordHasher
+	keys   *KeySet
+	email  EmailService
 }
 
-func NewAuthService(db *gorm.DB) AuthService { return &authService{db: db} }
+func NewAuthService(db *gorm.DB, keys *KeySet, email EmailService) AuthService {
+	return &authService{db: db, policy: NewPasswordPolicy(), hasher: NewPasswordHasher(), keys: keys, email: email}
+}
 
-func jwtSecret() []byte { return []byte(os.Getenv("JWT_SECRET")) }
 
 // 6 haneli doğrulama kodu (000000–999999)
 func gen6() (string, error) {
@@ -44,6 +69,21 @@ func gen6() (string, error) {
 	return fmt.Sprintf("%06d", n.Int64()), nil
 }
 
+// codeMailHTML 6 haneli kod e-postasının gövdesi (kayıt ve e-posta değişikliği).
+func codeMailHTML(code string) string {
+	return fmt.Sprintf(`
+<!doctype html>
+<html><body style="font-family:Arial,sans-serif">
+  <h2>Doğrulama Kodun</h2>
+  <p>Merhaba,</p>
+  <p>Aşağıdaki 6 haneli kodu 15 dakika içinde sitedeki doğrulama kutusuna gir:</p>
+  <div style="font-size:28px;font-weight:700;letter-spacing:4px;margin:16px 0">%s</div>
+  <p>Bu işlemi siz başlatmadıysanız, e-postayı yok sayabilirsiniz.</p>
+  <hr>
+  <p style="color:#888;font-size:12px">Bu e-posta cakarokko.com tarafından gönderildi.</p>
+</body></html>`, code)
+}
+
 // Kullanıcıya yeni kod üretir, DB'ye yazar ve e-posta gönderir.
 func (a *authService) generateAndSendCode(u *model.User) error {
 	code, err := gen6()
@@ -62,20 +102,7 @@ func (a *authService) generateAndSendCode(u *model.User) error {
 		return err
 	}
 
-	subject := "E-posta Doğrulama Kodun"
-	html := fmt.Sprintf(`
-<!doctype html>
-<html><body style="font-family:Arial,sans-serif">
-  <h2>Doğrulama Kodun</h2>
-  <p>Merhaba,</p>
-  <p>Aşağıdaki 6 haneli kodu 15 dakika içinde sitedeki doğrulama kutusuna gir:</p>
-  <div style="font-size:28px;font-weight:700;letter-spacing:4px;margin:16px 0">%s</div>
-  <p>Bu işlemi siz başlatmadıysanız, e-postayı yok sayabilirsiniz.</p>
-  <hr>
-  <p style="color:#888;font-size:12px">Bu e-posta cakarokko.com tarafından gönderildi.</p>
-</body></html>`, code)
-
-	if err := NewEmailService().Send(u.Email, subject, html); err != nil {
+	if err := a.email.Send(u.Email, "E-posta Doğrulama Kodun", codeMailHTML(code)); err != nil {
 		return err
 	}
 	return nil
@@ -88,7 +115,7 @@ func (a *authService) Register(email, password string) error {
 	var existed model.User
 	err := a.db.
 		Select("id, email, verified").
-		Where("email = ?", email).
+		Where("LOWER(email) = LOWER(?)", email).
 		First(&existed).Error
 
 	if err == nil {
@@ -106,10 +133,13 @@ func (a *authService) Register(email, password string) error {
 	}
 
 	// yeni kullanıcı oluştur
-	hash, _ := bcrypt.GenerateFromPassword([]byte(password), bcrypt.DefaultCost)
+	hash, err := a.hashPassword(password, email)
+	if err != nil {
+		return err
+	}
 	u := model.User{
 		Email:        email,
-		PasswordHash: string(hash), // <-- kritik
+		PasswordHash: hash, // <-- kritik
 		Verified:     false,
 	}
 	if err := a.db.Create(&u).Error; err != nil {
@@ -123,6 +153,19 @@ func (a *authService) Register(email, password string) error {
 	return nil
 }
 
+// hashPassword politikayı uygular ve seçili algoritmayla hash üretir.
+// Kayıt, şifre değiştirme ve sıfırlama hep buradan geçmeli.
+func (a *authService) hashPassword(password, email string) (string, error) {
+	if err := a.policy.Check("password", password, email); err != nil {
+		return "", err
+	}
+	hash, err := a.hasher.Hash(password)
+	if err != nil {
+		return "", fmt.Errorf("hash password: %w", err)
+	}
+	return hash, nil
+}
+
 // ---------------------------------------------------
 // VerifyCode
 // ---------------------------------------------------
@@ -130,7 +173,7 @@ func (a *authService) VerifyCode(email, code string) error {
 	code = strings.TrimSpace(code)
 
 	var u model.User
-	if err := a.db.Where("email = ?", email).First(&u).Error; err != nil {
+	if err := a.db.Where("LOWER(email) = LOWER(?)", email).First(&u).Error; err != nil {
 		return err
 	}
 	if u.Verified {
@@ -162,7 +205,7 @@ func (a *authService) VerifyCode(email, code string) error {
 // ---------------------------------------------------
 func (a *authService) ResendCode(email string) error {
 	var u model.User
-	if err := a.db.Where("email = ?", email).First(&u).Error; err != nil {
+	if err := a.db.Where("LOWER(email) = LOWER(?)", email).First(&u).Error; err != nil {
 		// enumeration engelle: kullanıcı yoksa sessiz dön
 		return nil
 	}
@@ -176,97 +219,127 @@ func (a *authService) ResendCode(email string) error {
 // (Legacy) VerifyEmail by JWT — kullanılmıyor ama interface dursun
 // ---------------------------------------------------
 func (a *authService) VerifyEmail(token string) error {
-	claims := jwt.MapClaims{}
-	_, err := jwt.ParseWithClaims(token, claims, func(t *jwt.Token) (interface{}, error) {
-		return jwtSecret(), nil
-	})
+	uid, err := a.parseTyped(token, "verify")
 	if err != nil {
 		return err
 	}
-	if claims["typ"] != "verify" {
-		return errors.New("invalid token type")
-	}
-	idFloat, ok := claims["sub"].(float64)
-	if !ok {
-		return errors.New("invalid sub")
-	}
 	return a.db.Model(&model.User{}).
-		Where("id = ?", uint(idFloat)).
+		Where("id = ?", uid).
 		Update("verified", true).Error
 }
 // ---------------------------------------------------
-// ResendVerification
-// ---------------------------------------------------
-func (s *AuthService) ResendVerification(ctx context.Context, email string) error {
-    // 6 haneli güvenli kod
-    n, _ := rand.Int(rand.Reader, big.NewInt(1000000))
-    code := fmt.Sprintf("%06d", n.Int64())
-    expires := time.Now().Add(15 * time.Minute)
-
-    // Sadece doğrulanmamış hesaplarda ayarla (Postgres)
-    // GORM ile '?' placeholder çalışır.
-    // Kullanıcı varsa günceller; yoksa RowsAffected=0 olur ama hata değildir.
-    tx := s.DB.WithContext(ctx).Exec(`
-        UPDATE users
-        SET verify_code = ?, verify_expires_at = ?
-        WHERE email = ? AND (verified IS DISTINCT FROM TRUE)
-    `, code, expires, email)
-
-    return tx.Error // hata yoksa nil
-}
-// ---------------------------------------------------
 // Login
 // ---------------------------------------------------
-func (a *authService) Login(email, password string) (string, error) {
+func (a *authService) Login(email, password string) (LoginResult, error) {
 	var u model.User
 	if err := a.db.
-		Select("id, email, verified, password_hash, password").
-		Where("email = ?", email).
+		Select("id, email, verified, password_hash, totp_enabled").
+		Where("LOWER(email) = LOWER(?)", email).
 		First(&u).Error; err != nil {
-		return "", err
+		return LoginResult{}, err
 	}
 
-	// Öncelik password_hash’te
-	pwdHash := u.PasswordHash
-	if pwdHash == "" {
-		pwdHash = u.Password // geriye uyumluluk
-	}
-	if pwdHash == "" {
-		return "", errors.New("invalid credentials")
+	ok, rehash := a.hasher.Verify(u.PasswordHash, password)
+	if !ok {
+		return LoginResult{}, errors.New("invalid credentials")
 	}
-
-	if err := bcrypt.CompareHashAndPassword([]byte(pwdHash), []byte(password)); err != nil {
-		return "", errors.New("invalid credentials")
+	// eski algoritma / düşük maliyet → şeffaf upgrade (best-effort)
+	if rehash {
+		if h, err := a.hasher.Hash(password); err == nil {
+			if err := a.db.Model(&model.User{}).Where("id = ? AND password_hash = ?", u.ID, u.PasswordHash).
+				Update("password_hash", h).Error; err != nil {
+				log.Printf("rehash user %d: %v", u.ID, err)
+			}
+		}
 	}
 	if !u.Verified {
-		return "", errors.New("email not verified")
+		return LoginResult{}, errors.New("email not verified")
 	}
 
-	t := jwt.NewWithClaims(jwt.SigningMethodHS256, jwt.MapClaims{
-		"sub": u.ID,
-		"typ": "session",
-		"exp": time.Now().Add(7 * 24 * time.Hour).Unix(),
-	})
-	return t.SignedString(jwtSecret())
+	return loginResultFor(a.keys, &u)
+}
+
+// loginResultFor birinci faktörü geçmiş kullanıcı için session ya da (2FA açıksa)
+// "mfa" token'ı üretir. Şifreli login ve OIDC aynı yoldan geçer.
+func loginResultFor(keys *KeySet, u *model.User) (LoginResult, error) {
+	if u.TOTPEnabled {
+		// jti: deneme sayısı token başına tutulur (VerifyMFA)
+		jti, err := randURLSafe(16)
+		if err != nil {
+			return LoginResult{}, err
+		}
+		tok, err := keys.Sign(jwt.MapClaims{"sub": signSubject(u.ID), "typ": "mfa", "jti": jti}, mfaTokenTTL)
+		return LoginResult{Token: tok, MFARequired: true}, err
+	}
+	tok, err := signUserToken(keys, u.ID, "session", sessionTTL)
+	return LoginResult{Token: tok}, err
+}
+
+const sessionTTL = 7 * 24 * time.Hour
+
+func (a *authService) sessionToken(uid uint) (string, error) {
+	return signUserToken(a.keys, uid, "session", sessionTTL)
+}
+
+func signSubject(uid uint) string { return strconv.FormatUint(uint64(uid), 10) }
+
+func signUserToken(keys *KeySet, uid uint, typ string, ttl time.Duration) (string, error) {
+	return keys.Sign(jwt.MapClaims{
+		"sub": signSubject(uid),
+		"typ": typ,
+	}, ttl)
 }
 
 // ---------------------------------------------------
 // ParseToken
 // ---------------------------------------------------
 func (a *authService) ParseToken(token string) (uint, error) {
-	claims := jwt.MapClaims{}
-	_, err := jwt.ParseWithClaims(token, claims, func(t *jwt.Token) (interface{}, error) {
-		return jwtSecret(), nil
-	})
+	uid, _, err := a.ParseSession(token)
+	return uid, err
+}
+
+// session token'ı sadece girişte imzalanır (yenileme yok): iat = giriş anı.
+func (a *authService) ParseSession(token string) (uint, time.Time, error) {
+	uid, claims, err := a.parseTypedClaims(token, "session")
 	if err != nil {
-		return 0, err
+		return 0, time.Time{}, err
 	}
-	if claims["typ"] != "session" {
-		return 0, errors.New("invalid token type")
+	var at time.Time
+	if iat, _ := claims.GetIssuedAt(); iat != nil {
+		at = iat.Time
 	}
-	idFloat, ok := claims["sub"].(float64)
-	if !ok {
-		return 0, errors.New("invalid sub")
+	var u model.User
+	if err := a.db.Select("id, password_changed_at").First(&u, uid).Error; err != nil {
+		return 0, time.Time{}, err
+	}
+	if u.PasswordChangedAt != nil && at.Before(*u.PasswordChangedAt) {
+		return 0, time.Time{}, errors.New("session predates password change")
+	}
+	return uid, at, nil
+}
+
+func (a *authService) NewSession(userID uint) (string, error) {
+	return a.sessionToken(userID)
+}
+
+// parseTyped imzayı/süreyi doğrular ve typ claim'inin beklenen değer olduğunu kontrol eder.
+func (a *authService) parseTyped(token, typ string) (uint, error) {
+	uid, _, err := a.parseTypedClaims(token, typ)
+	return uid, err
+}
+
+func (a *authService) parseTypedClaims(token, typ string) (uint, jwt.MapClaims, error) {
+	claims, err := a.keys.Parse(token)
+	if err != nil {
+		return 0, nil, err
+	}
+	if claims["typ"] != typ {
+		return 0, nil, errors.New("invalid token type")
+	}
+	sub, _ := claims["sub"].(string)
+	id, err := strconv.ParseUint(sub, 10, 64)
+	if err != nil || id == 0 {
+		return 0, nil, errors.New("invalid sub")
 	}
-	return uint(idFloat), nil
+	return uint(id), claims, nil
 }
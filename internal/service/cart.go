@@ -2,42 +2,260 @@ package service
 
 import (
 	"errors"
+	"fmt"
+	"strings"
+	"time"
 
 	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
 
 	"example.com/ecom-go/internal/model"
+	"example.com/ecom-go/internal/money"
+	"example.com/ecom-go/internal/validate"
 )
 
 type CartService interface {
-	Add(userID uint, productID uint, qty int) error
-	Get(userID uint) ([]model.CartItem, error)
+	// Add variantID 0 → varyantsız ürün; varyantı olan ürünlerde zorunlu.
+	Add(userID uint, productID uint, variantID uint, qty int) error
+	// Get UnitPrice/LineTotal cur'da doldurulmuş olarak döner.
+	Get(userID uint, cur money.Currency) ([]model.CartItem, error)
 	Clear(userID uint) error
+	// ApplyCoupon kuponu şu anki sepete uygulanabiliyorsa kaydeder (öncekinin yerine).
+	ApplyCoupon(userID uint, code string, in SummaryInput) (CartSummary, error)
+	RemoveCoupon(userID uint) error
+	// Summary checkout'un kullandığı fiyatlamayla sepet toplamları + kargo tahmini.
+	Summary(userID uint, in SummaryInput) (CartSummary, error)
 }
 
-type cartService struct{ db *gorm.DB }
+type cartService struct{ db *gorm.DB; fx CurrencyService; tax TaxService; ship ShippingService; pricing pricing }
 
-func NewCartService(db *gorm.DB) CartService { return &cartService{db: db} }
+func NewCartService(db *gorm.DB, fx CurrencyService, tax TaxService, ship ShippingService) CartService {
+	return &cartService{db: db, fx: fx, tax: tax, ship: ship, pricing: pricing{fx: fx, tax: tax}}
+}
 
-func (s *cartService) Add(userID uint, productID uint, qty int) error {
+func (s *cartService) Add(userID uint, productID uint, variantID uint, qty int) error {
 	if qty <= 0 { return errors.New("qty must be > 0") }
 
+	// olmayan ürün sepete girmesin
+	var product model.Product
+	if err := s.db.First(&product, productID).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) { return ErrProductNotFound }
+		return err
+	}
+
+	// varyant: ürüne ait olmalı, varyantı olan ürün varyantsız eklenemez
+	var variant *model.ProductVariant
+	if variantID != 0 {
+		var v model.ProductVariant
+		if err := s.db.Where("id = ? AND product_id = ?", variantID, productID).First(&v).Error; err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) { return ErrVariantNotFound }
+			return err
+		}
+		variant = &v
+	} else {
+		var n int64
+		if err := s.db.Model(&model.ProductVariant{}).Where("product_id = ?", productID).Count(&n).Error; err != nil {
+			return err
+		}
+		if n > 0 { return ErrVariantRequired }
+	}
+
 	var it model.CartItem
-	err := s.db.Where("user_id = ? AND product_id = ?", userID, productID).First(&it).Error
+	q := s.db.Where("user_id = ? AND product_id = ?", userID, productID)
+	if variant != nil {
+		q = q.Where("variant_id = ?", variant.ID)
+	} else {
+		q = q.Where("variant_id IS NULL")
+	}
+	err := q.First(&it).Error
 	if errors.Is(err, gorm.ErrRecordNotFound) {
-		it = model.CartItem{UserID: userID, ProductID: productID, Qty: qty}
-		return s.db.Create(&it).Error
+		it = model.CartItem{UserID: userID, ProductID: productID, Qty: 0}
+		if variant != nil { it.VariantID = &variant.ID }
 	} else if err != nil {
 		return err
 	}
+	if it.Qty+qty > validate.MaxQty {
+		return fmt.Errorf("%w: at most %d per product", ErrQtyLimit, validate.MaxQty)
+	}
+	// stok kesin olarak checkout'ta düşülür; burada erken uyarı
+	if variant != nil && it.Qty+qty > variant.Stock {
+		return fmt.Errorf("%w: %d left", ErrOutOfStock, variant.Stock)
+	}
 	it.Qty += qty
-	return s.db.Save(&it).Error
+	// kullanıcının şu an gördüğü fiyat; checkout bununla karşılaştırır
+	it.Product, it.Variant = product, variant
+	seen := it.BaseUnitPrice()
+	it.AddedPriceCents, it.AddedCurrency = seen.Amount, string(seen.Currency)
+	return s.db.Omit("Product", "Variant").Save(&it).Error
 }
 
-func (s *cartService) Get(userID uint) ([]model.CartItem, error) {
-	var items []model.CartItem
-	return items, s.db.Preload("Product").Where("user_id = ?", userID).Order("id asc").Find(&items).Error
+func (s *cartService) Get(userID uint, cur money.Currency) ([]model.CartItem, error) {
+	items, err := loadCart(s.db, userID)
+	if err != nil {
+		return nil, err
+	}
+	if cur == "" { cur = s.fx.Default() }
+	conv := s.fx.Converter(cur)
+	for i := range items {
+		unit, err := conv(items[i].BaseUnitPrice())
+		if err != nil { return nil, err }
+		line := unit.Mul(int64(items[i].Qty))
+		items[i].UnitPrice, items[i].LineTotal = &unit, &line
+	}
+	return items, nil
 }
 
 func (s *cartService) Clear(userID uint) error {
+	if err := s.db.Where("user_id = ?", userID).Delete(&model.CartCoupon{}).Error; err != nil {
+		return err
+	}
 	return s.db.Where("user_id = ?", userID).Delete(&model.CartItem{}).Error
 }
+
+// NormalizeCoupon kodlar büyük harfle saklanır ve karşılaştırılır.
+func NormalizeCoupon(code string) string { return strings.ToUpper(strings.TrimSpace(code)) }
+
+// SummaryInput sepet özeti için checkout'ta seçilecek bilgiler; hepsi opsiyonel.
+type SummaryInput struct {
+	Currency       money.Currency // boş → varsayılan
+	AddressID      uint           // 0 → varsayılan adres (o da yoksa mağaza ülkesi)
+	ShippingMethod string         // boş → adrese gönderen ilk yöntemle tahmin
+}
+
+// CartSummary sepetin sunucuda hesaplanmış hali; checkout ile aynı fiyatlama.
+// Tutarlar katalog modunda (TaxIncluded ise vergi dahil), Subtotal net.
+
+type CartSummary struct {
+	Currency    money.Currency   `json:"currency"`
+	TaxIncluded bool             `json:"tax_included"`
+	Lines       []SummaryLine    `json:"lines"`
+	ItemsTotal  money.Money      `json:"items_total"`
+	Coupon      string           `json:"coupon,omitempty"`
+	Discounts   []DiscountLine   `json:"discounts"`
+	Discount    money.Money      `json:"discount"`
+	Subtotal    money.Money      `json:"subtotal"`
+	Shipping    *SummaryShipping `json:"shipping"` // adrese gönderen yöntem yoksa null
+	Tax         money.Money      `json:"tax"`
+	Total       money.Money      `json:"total"`
+	Warnings    []Warning        `json:"warnings"`
+
+	// CartVersion fiyat değişikliği onayı için checkout'a geri gönderilir.
+	CartVersion  string        `json:"cart_version"`
+	PriceChanges []PriceChange `json:"price_changes"`
+}
+
+type SummaryLine struct {
+	ID        uint                  `json:"id"`
+	ProductID uint                  `json:"product_id"`
+	VariantID *uint                 `json:"variant_id,omitempty"`
+	Name      string                `json:"name"`
+	SKU       string                `json:"sku,omitempty"`
+	Options   model.SelectedOptions `json:"options,omitempty"`
+	ImageURL  string                `json:"image_url,omitempty"`
+	Qty       int                   `json:"qty"`
+	UnitPrice money.Money           `json:"unit_price"`
+	LineTotal money.Money           `json:"line_total"`
+	Discount  money.Money           `json:"discount"`
+	Tax       money.Money           `json:"tax"`
+}
+
+type SummaryShipping struct {
+	Method string      `json:"method"`
+	Name   string      `json:"name"`
+	Amount money.Money `json:"amount"` // katalog modunda
+	Tax    money.Money `json:"tax"`
+}
+func (s *cartService) quote(userID uint, in SummaryInput, coupon *model.Promotion) (Quote, *model.ShippingMethod, error) {
+	cur := in.Currency
+	if cur == "" { cur = s.fx.Default() }
+	items, err := loadCart(s.db, userID)
+	if err != nil { return Quote{}, nil, err }
+
+	country := ""
+	var addr model.Address
+	if in.AddressID != 0 {
+		if addr, err = findAddress(s.db, userID, in.AddressID); err != nil { return Quote{}, nil, err }
+		country = addr.Country
+	} else if err := s.db.Where("user_id = ? AND is_default", userID).First(&addr).Error; err == nil {
+		country = addr.Country
+	} else if !errors.Is(err, gorm.ErrRecordNotFound) {
+		return Quote{}, nil, err
+	}
+	jurisdiction, err := s.tax.JurisdictionFor(country)
+	if err != nil { return Quote{}, nil, err }
+
+	var method *model.ShippingMethod
+	if in.ShippingMethod != "" {
+		m, err := s.ship.Find(in.ShippingMethod, country)
+		if err != nil { return Quote{}, nil, err }
+		method = &m
+	} else {
+		ms, err := s.ship.Methods(country)
+		if err != nil { return Quote{}, nil, err }
+		if len(ms) > 0 { method = &ms[0] }
+	}
+	q, err := s.pricing.Price(s.db, items, PriceInput{
+		UserID: userID, Currency: cur, Jurisdiction: jurisdiction,
+		Method: method, Coupon: coupon, Now: time.Now(),
+	})
+	return q, method, err
+}
+
+func summarize(q Quote, method *model.ShippingMethod, coupon *model.Promotion) CartSummary {
+	cs := CartSummary{
+		Currency: q.Currency, TaxIncluded: q.TaxIncluded, Lines: []SummaryLine{},
+		ItemsTotal: q.Items, Discounts: q.Discounts, Discount: q.Discount, Subtotal: q.Subtotal,
+		Tax: q.Tax, Total: q.Total, Warnings: q.Warnings,
+		CartVersion: q.Version, PriceChanges: q.PriceChanges,
+	}
+	if cs.Discounts == nil { cs.Discounts = []DiscountLine{} }
+	if cs.Warnings == nil { cs.Warnings = []Warning{} }
+	if cs.PriceChanges == nil { cs.PriceChanges = []PriceChange{} }
+	if coupon != nil && coupon.Code != nil { cs.Coupon = *coupon.Code }
+	for _, l := range q.Lines {
+		it := l.Item
+		sl := SummaryLine{
+			ID: it.ID, ProductID: it.ProductID, VariantID: it.VariantID, Name: it.Product.Name,
+			ImageURL: it.Product.ImageURL, Qty: it.Qty,
+			UnitPrice: l.Unit, LineTotal: l.Line, Discount: l.Discount, Tax: l.Tax.Tax,
+		}
+		if it.Variant != nil {
+			sl.SKU, sl.Options = it.Variant.SKU, it.Variant.Selected()
+			if it.Variant.ImageURL != "" { sl.ImageURL = it.Variant.ImageURL }
+		}
+		cs.Lines = append(cs.Lines, sl)
+	}
+	if method != nil && q.Shipping != nil {
+		amount := q.Shipping.Net
+		if q.TaxIncluded { amount = q.Shipping.Gross() }
+		cs.Shipping = &SummaryShipping{Method: method.Code, Name: method.Name, Amount: amount, Tax: q.Shipping.Tax}
+	}
+	return cs
+}
+
+func (s *cartService) Summary(userID uint, in SummaryInput) (CartSummary, error) {
+	coupon, err := cartCoupon(s.db, userID)
+	if err != nil { return CartSummary{}, err }
+	q, method, err := s.quote(userID, in, coupon)
+	if err != nil { return CartSummary{}, err }
+	return summarize(q, method, coupon), nil
+}
+
+func (s *cartService) ApplyCoupon(userID uint, code string, in SummaryInput) (CartSummary, error) {
+	var p model.Promotion
+	err := s.db.Preload("Products").Preload("Categories").Where("code = ?", NormalizeCoupon(code)).First(&p).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) { return CartSummary{}, ErrCouponNotFound }
+	if err != nil { return CartSummary{}, err }
+	q, method, err := s.quote(userID, in, &p)
+	if err != nil { return CartSummary{}, err }
+	if q.CouponErr != nil { return CartSummary{}, q.CouponErr }
+	err = s.db.Clauses(clause.OnConflict{
+		Columns:   []clause.Column{{Name: "user_id"}},
+		DoUpdates: clause.AssignmentColumns([]string{"promotion_id", "created_at"}),
+	}).Create(&model.CartCoupon{UserID: userID, PromotionID: p.ID}).Error
+	return summarize(q, method, &p), err
+}
+
+func (s *cartService) RemoveCoupon(userID uint) error {
+	return s.db.Where("user_id = ?", userID).Delete(&model.CartCoupon{}).Error
+}
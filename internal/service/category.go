@@ -0,0 +1,238 @@
+package service
+
+import (
+	"errors"
+	"regexp"
+	"sort"
+	"strings"
+
+	"gorm.io/gorm"
+
+	"example.com/ecom-go/internal/model"
+	"example.com/ecom-go/internal/validate"
+)
+
+// CategoryNode GET /api/categories ağacındaki bir düğüm.
+type CategoryNode struct {
+	ID       uint            `json:"id"`
+	Name     string          `json:"name"`
+	Slug     string          `json:"slug"`
+	Position int             `json:"position"`
+	Children []*CategoryNode `json:"children"`
+}
+
+type CategoryInput struct {
+	Name     string
+	Slug     string // boşsa isimden üretilir
+	ParentID *uint
+	Position int
+}
+
+type CategoryService interface {
+	Tree() ([]*CategoryNode, error)
+	Create(in CategoryInput) (model.Category, error)
+	Update(id uint, in CategoryInput) (model.Category, error)
+	Delete(id uint) error
+	SetProductCategories(productID uint, categoryIDs []uint) error
+}
+
+type categoryService struct{ db *gorm.DB }
+
+func NewCategoryService(db *gorm.DB) CategoryService { return &categoryService{db: db} }
+
+var (
+	slugRe    = regexp.MustCompile(`^[a-z0-9]+(-[a-z0-9]+)*$`)
+	slugStrip = regexp.MustCompile(`[^a-z0-9]+`)
+	trFold    = strings.NewReplacer("ç", "c", "ğ", "g", "ı", "i", "ö", "o", "ş", "s", "ü", "u")
+)
+
+// ValidSlug küçük harf, rakam ve tek tire.
+func ValidSlug(s string) bool { return len(s) <= 100 && slugRe.MatchString(s) }
+
+// Slugify "Erkek Giyim & Ayakkabı" → "erkek-giyim-ayakkabi".
+func Slugify(name string) string {
+	s := trFold.Replace(strings.ToLower(strings.ReplaceAll(name, "İ", "i")))
+	return strings.Trim(slugStrip.ReplaceAllString(s, "-"), "-")
+}
+
+func (s *categoryService) Tree() ([]*CategoryNode, error) {
+	var cats []model.Category
+	if err := s.db.Order("position asc, name asc, id asc").Find(&cats).Error; err != nil {
+		return nil, err
+	}
+	nodes := make(map[uint]*CategoryNode, len(cats))
+	for _, c := range cats {
+		nodes[c.ID] = &CategoryNode{ID: c.ID, Name: c.Name, Slug: c.Slug, Position: c.Position, Children: []*CategoryNode{}}
+	}
+	roots := []*CategoryNode{}
+	// cats sıralı geldiği için çocuklar da sıralı eklenir
+	for _, c := range cats {
+		n := nodes[c.ID]
+		if c.ParentID == nil || nodes[*c.ParentID] == nil {
+			roots = append(roots, n)
+			continue
+		}
+		p := nodes[*c.ParentID]
+		p.Children = append(p.Children, n)
+	}
+	return roots, nil
+}
+
+func (s *categoryService) Create(in CategoryInput) (model.Category, error) {
+	c := model.Category{Name: in.Name, Slug: in.Slug, ParentID: in.ParentID, Position: in.Position}
+	if c.Slug == "" {
+		c.Slug = Slugify(c.Name)
+	}
+	if !ValidSlug(c.Slug) {
+		return c, validate.Errors{"slug": "use lowercase letters, digits and single dashes"}
+	}
+	err := s.db.Transaction(func(tx *gorm.DB) error {
+		if err := checkCategoryRefs(tx, 0, c.Slug, c.ParentID); err != nil {
+			return err
+		}
+		return tx.Create(&c).Error
+	})
+	return c, err
+}
+
+func (s *categoryService) Update(id uint, in CategoryInput) (model.Category, error) {
+	var c model.Category
+	err := s.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.First(&c, id).Error; err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				return ErrCategoryNotFound
+			}
+			return err
+		}
+		slug := in.Slug
+		if slug == "" {
+			slug = c.Slug // isim değişse de mevcut linkler kırılmasın
+		}
+		if !ValidSlug(slug) {
+			return validate.Errors{"slug": "use lowercase letters, digits and single dashes"}
+		}
+		if err := checkCategoryRefs(tx, id, slug, in.ParentID); err != nil {
+			return err
+		}
+		c.Name, c.Slug, c.ParentID, c.Position = in.Name, slug, in.ParentID, in.Position
+		return tx.Save(&c).Error
+	})
+	return c, err
+}
+
+// checkCategoryRefs slug tekliğini ve parent'ın varlığını kontrol eder;
+// id != 0 ise (taşıma) parent'ın kendisi ya da bir alt kategorisi olmasını engeller.
+func checkCategoryRefs(tx *gorm.DB, id uint, slug string, parentID *uint) error {
+	var n int64
+	if err := tx.Model(&model.Category{}).Where("slug = ? AND id <> ?", slug, id).Count(&n).Error; err != nil {
+		return err
+	}
+	if n > 0 {
+		return ErrSlugTaken
+	}
+	if parentID == nil {
+		return nil
+	}
+	if err := tx.Model(&model.Category{}).Where("id = ?", *parentID).Count(&n).Error; err != nil {
+		return err
+	}
+	if n == 0 {
+		return ErrCategoryNotFound
+	}
+	if id == 0 {
+		return nil
+	}
+	ids, err := descendantIDs(tx, id)
+	if err != nil {
+		return err
+	}
+	for _, d := range ids {
+		if d == *parentID {
+			return ErrCategoryCycle
+		}
+	}
+	return nil
+}
+
+// descendantIDs kategorinin kendisi + tüm alt kategorileri.
+func descendantIDs(tx *gorm.DB, id uint) ([]uint, error) {
+	var ids []uint
+	err := tx.Raw(`WITH RECURSIVE sub AS (
+			SELECT id FROM categories WHERE id = ?
+			UNION
+			SELECT c.id FROM categories c JOIN sub ON c.parent_id = sub.id
+		) SELECT id FROM sub`, id).Scan(&ids).Error
+	return ids, err
+}
+
+func (s *categoryService) Delete(id uint) error {
+	return s.db.Transaction(func(tx *gorm.DB) error {
+		var n int64
+		if err := tx.Model(&model.Category{}).Where("parent_id = ?", id).Count(&n).Error; err != nil {
+			return err
+		}
+		if n > 0 {
+			return ErrCategoryHasChildren
+		}
+		if err := tx.Exec("DELETE FROM product_categories WHERE category_id = ?", id).Error; err != nil {
+			return err
+		}
+		if err := tx.Exec("DELETE FROM promotion_categories WHERE category_id = ?", id).Error; err != nil {
+			return err
+		}
+		res := tx.Delete(&model.Category{}, id)
+		if res.Error != nil {
+			return res.Error
+		}
+		if res.RowsAffected == 0 {
+			return ErrCategoryNotFound
+		}
+		return nil
+	})
+}
+
+func (s *categoryService) SetProductCategories(productID uint, categoryIDs []uint) error {
+	return s.db.Transaction(func(tx *gorm.DB) error {
+		var p model.Product
+		if err := tx.First(&p, productID).Error; err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				return ErrProductNotFound
+			}
+			return err
+		}
+		var cats []model.Category
+		if len(categoryIDs) > 0 {
+			if err := tx.Where("id IN ?", categoryIDs).Find(&cats).Error; err != nil {
+				return err
+			}
+		}
+		if len(cats) != len(uniqueIDs(categoryIDs)) {
+			return ErrCategoryNotFound
+		}
+		assoc := tx.Model(&p).Association("Categories")
+		var err error
+		if len(cats) == 0 {
+			err = assoc.Clear()
+		} else {
+			err = assoc.Replace(cats)
+		}
+		if err != nil {
+			return err
+		}
+		// ürün listesi ETag'i updated_at'e bakar
+		return tx.Model(&p).Update("updated_at", gorm.Expr("now()")).Error
+	})
+}
+
+func uniqueIDs(ids []uint) []uint {
+	out := append([]uint(nil), ids...)
+	sort.Slice(out, func(i, j int) bool { return out[i] < out[j] })
+	n := 0
+	for i, v := range out {
+		if i == 0 || v != out[n-1] {
+			out[n] = v
+			n++
+		}
+	}
+	return out[:n]
+}
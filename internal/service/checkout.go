@@ -1,56 +1,180 @@
 package service
 
 import (
+	"context"
+	"errors"
 	"fmt"
+	"log"
+	"time"
 
 	"gorm.io/gorm"
 
 	"example.com/ecom-go/internal/model"
+	"example.com/ecom-go/internal/money"
 )
 
+type CheckoutInput struct {
+	Currency       money.Currency // boş → varsayılan
+	AddressID      uint           // 0 → varsayılan adres
+	ShippingMethod string         // ShippingMethod.Code
+	// CartVersion kullanıcının onayladığı sepet sürümü (CartSummary.CartVersion);
+	// eklendiğinden beri fiyatı değişen satır varsa zorunlu.
+	CartVersion string
+}
+
+// PricesChangedError sepete eklendiğinden beri fiyatı değişen satırlar; istemci
+// değişiklikleri gösterip Version ile tekrar göndermeli.
+type PricesChangedError struct {
+	Version string
+	Changes []PriceChange
+}
+
+func (e *PricesChangedError) Error() string {
+	return fmt.Sprintf("%v: %d item(s)", ErrPricesChanged, len(e.Changes))
+}
+
+func (e *PricesChangedError) Unwrap() error { return ErrPricesChanged }
+
 type CheckoutService interface {
-	Checkout(userID uint) (model.Order, error)
+	Checkout(userID uint, in CheckoutInput) (model.Order, error)
 }
 
-type checkoutService struct{ db *gorm.DB; email EmailService }
+type checkoutService struct{ db *gorm.DB; email EmailService; tax TaxService; ship ShippingService; invoices InvoiceService; pricing pricing }
 
-func NewCheckoutService(db *gorm.DB, email EmailService) CheckoutService {
-	return &checkoutService{db: db, email: email}
+func NewCheckoutService(db *gorm.DB, email EmailService, fx CurrencyService, tax TaxService, ship ShippingService, invoices InvoiceService) CheckoutService {
+	return &checkoutService{db: db, email: email, tax: tax, ship: ship, invoices: invoices, pricing: pricing{fx: fx, tax: tax}}
 }
 
-func (s *checkoutService) Checkout(userID uint) (model.Order, error) {
-	// sepeti yükle
-	var items []model.CartItem
-	if err := s.db.Preload("Product").Where("user_id = ?", userID).Find(&items).Error; err != nil {
-		return model.Order{}, err
+func (s *checkoutService) Checkout(userID uint, in CheckoutInput) (model.Order, error) {
+	cur := in.Currency
+	if cur == "" { cur = s.pricing.fx.Default() }
+
+	// adres: verilmediyse varsayılan; vergi bölgesi ve kargo seçenekleri ülkeye bağlı
+	var addr model.Address
+	var err error
+	if in.AddressID != 0 {
+		addr, err = findAddress(s.db, userID, in.AddressID)
+	} else if err = s.db.Where("user_id = ? AND is_default", userID).First(&addr).Error; errors.Is(err, gorm.ErrRecordNotFound) {
+		err = ErrAddressRequired
 	}
-	if len(items) == 0 { return model.Order{}, fmt.Errorf("cart empty") }
-
-	// sipariş oluştur
-	var total int64
-	var oitems []model.OrderItem
-	for _, it := range items {
-		total += it.Product.PriceCents * int64(it.Qty)
-		oitems = append(oitems, model.OrderItem{
-			ProductID:  it.ProductID,
-			Name:       it.Product.Name,
-			PriceCents: it.Product.PriceCents,
-			Qty:        it.Qty,
+	if err != nil { return model.Order{}, err }
+	method, err := s.ship.Find(in.ShippingMethod, addr.Country)
+	if err != nil { return model.Order{}, err }
+	jurisdiction, err := s.tax.JurisdictionFor(addr.Country)
+	if err != nil { return model.Order{}, err }
+
+	var order model.Order
+	err = s.db.Transaction(func(tx *gorm.DB) error {
+		items, err := loadCart(tx, userID)
+		if err != nil { return err }
+		if len(items) == 0 { return fmt.Errorf("cart empty") }
+		coupon, err := cartCoupon(tx, userID)
+		if err != nil { return err }
+
+		// fiyatlar sepet özetiyle aynı fonksiyondan: çeviri, indirim, satır başı vergi, kargo
+		q, err := s.pricing.Price(tx, items, PriceInput{
+			UserID: userID, Currency: cur, Jurisdiction: jurisdiction,
+			Method: &method, Coupon: coupon, Now: time.Now(),
 		})
-	}
-	order := model.Order{UserID: userID, TotalCents: total}
-	if err := s.db.Create(&order).Error; err != nil { return model.Order{}, err }
-	for i := range oitems { oitems[i].OrderID = order.ID }
-	if err := s.db.Create(&oitems).Error; err != nil { return model.Order{}, err }
+		if err != nil { return err }
+		// uygulanamayan kupon sessizce düşmesin: kullanıcı kaldırsın ya da sepeti düzeltsin
+		if q.CouponErr != nil { return q.CouponErr }
+		// fiyat değiştiyse kullanıcı güncel sürümü görmüş olmalı
+		if len(q.PriceChanges) > 0 && in.CartVersion != q.Version {
+			return &PricesChangedError{Version: q.Version, Changes: q.PriceChanges}
+		}
+
+		// sipariş oluştur; seçenekler snapshot'lanır (varyant sonradan değişebilir)
+		var oitems []model.OrderItem
+		for _, l := range q.Lines {
+			it := l.Item
+			oi := model.OrderItem{
+				ProductID:  it.ProductID,
+				Name:       it.Product.Name,
+				PriceCents: l.Unit.Amount,
+				Currency:   string(cur),
+				Qty:        it.Qty,
+				TaxClass:   l.Tax.Class, TaxBasisPoints: l.Tax.BasisPoints,
+				DiscountCents: l.Discount.Amount, NetCents: l.Tax.Net.Amount, TaxCents: l.Tax.Tax.Amount,
+			}
+			if it.Variant != nil {
+				// stok yarışına karşı koşullu düşüm
+				res := tx.Model(&model.ProductVariant{}).
+					Where("id = ? AND stock >= ?", it.Variant.ID, it.Qty).
+					Update("stock", gorm.Expr("stock - ?", it.Qty))
+				if res.Error != nil { return res.Error }
+				if res.RowsAffected == 0 {
+					return fmt.Errorf("%w: %s", ErrOutOfStock, it.Variant.SKU)
+				}
+				oi.VariantID = it.VariantID
+				oi.SKU = it.Variant.SKU
+				oi.Options = it.Variant.Selected()
+			}
+			oitems = append(oitems, oi)
+		}
+
+		order = model.Order{
+			UserID: userID, Currency: string(cur),
+			SubtotalCents: q.Subtotal.Amount, TaxCents: q.Tax.Amount, DiscountCents: q.Discount.Amount,
+			ShippingMethod: method.Code, ShippingCents: q.Shipping.Net.Amount, ShippingTaxCents: q.Shipping.Tax.Amount,
+			ShippingTaxBasisPoints: q.Shipping.BasisPoints,
+			TotalCents: q.Total.Amount,
+			TaxIncluded: q.TaxIncluded, TaxJurisdiction: jurisdiction,
+			ShipTo: addr.PostalAddress,
+		}
+		if err := tx.Create(&order).Error; err != nil { return err }
+		for i := range oitems { oitems[i].OrderID = order.ID }
+		if err := tx.Create(&oitems).Error; err != nil { return err }
+		order.Items = oitems
+
+		for _, d := range q.Discounts {
+			if err := redeem(tx, d, coupon, userID, order.ID); err != nil { return err }
+			od := model.OrderDiscount{OrderID: order.ID, PromotionID: d.PromotionID, Code: d.Code, Name: d.Name, AmountCents: d.Amount.Amount, Currency: string(cur)}
+			if err := tx.Create(&od).Error; err != nil { return err }
+			order.Discounts = append(order.Discounts, od)
+		}
+
+		// fatura numarası siparişle aynı tx'te: sipariş geri alınırsa numara da boşa gitmez
+		if _, err := s.invoices.Issue(tx, order.ID, order.CreatedAt); err != nil { return err }
 
-	// sepeti temizle
-	_ = s.db.Where("user_id = ?", userID).Delete(&model.CartItem{}).Error
+		// sepeti temizle
+		if err := tx.Where("user_id = ?", userID).Delete(&model.CartCoupon{}).Error; err != nil { return err }
+		return tx.Where("user_id = ?", userID).Delete(&model.CartItem{}).Error
+	})
+	if err != nil { return model.Order{}, err }
 
-	// mail (best-effort)
+	// mail (best-effort); fatura üretilemezse ek olmadan gider, sonradan indirilebilir
 	var u model.User
 	_ = s.db.First(&u, userID).Error
+	var atts []Attachment
+	if b, inv, err := s.invoices.PDF(context.Background(), userID, order.ID); err == nil {
+		atts = append(atts, Attachment{Name: inv.Number + ".pdf", ContentType: "application/pdf", Data: b})
+	} else {
+		log.Printf("invoice for order %d: %v", order.ID, err)
+	}
 	_ = s.email.Send(u.Email, "Order confirmation",
-		fmt.Sprintf("Thanks! Your order #%d total %.2f received.", order.ID, float64(order.TotalCents)/100.0))
+		fmt.Sprintf("Thanks! Your order #%d total %s (incl. tax %s) received.", order.ID,
+			order.Total().Format(money.DefaultLocale), order.Tax().Format(money.DefaultLocale)), atts...)
 
 	return order, nil
 }
+
+// redeem promosyon kullanımını sayar. Kupon limiti koşullu UPDATE ile düşülür;
+// satır kilidi tx sonuna kadar tutulduğu için kişi başı sayım da yarışsız.
+func redeem(tx *gorm.DB, d DiscountLine, coupon *model.Promotion, userID, orderID uint) error {
+	res := tx.Model(&model.Promotion{}).
+		Where("id = ? AND (usage_limit = 0 OR used_count < usage_limit)", d.PromotionID).
+		Update("used_count", gorm.Expr("used_count + 1"))
+	if res.Error != nil { return res.Error }
+	if res.RowsAffected == 0 { return ErrCouponExhausted }
+	if coupon != nil && coupon.ID == d.PromotionID && coupon.PerUserLimit > 0 {
+		var n int64
+		if err := tx.Model(&model.PromotionRedemption{}).Where("promotion_id = ? AND user_id = ?", d.PromotionID, userID).Count(&n).Error; err != nil {
+			return err
+		}
+		if n >= int64(coupon.PerUserLimit) {
+			return fmt.Errorf("%w: already used", ErrCouponExhausted)
+		}
+	}
+	return tx.Create(&model.PromotionRedemption{PromotionID: d.PromotionID, UserID: userID, OrderID: orderID}).Error
+}
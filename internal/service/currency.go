@@ -0,0 +1,104 @@
+package service
+
+import (
+	"errors"
+	"math/big"
+	"os"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+
+	"example.com/ecom-go/internal/model"
+	"example.com/ecom-go/internal/money"
+)
+
+type CurrencyService interface {
+	// Default mağazanın varsayılan para birimi (DEFAULT_CURRENCY, TRY).
+	Default() money.Currency
+	Rates() ([]model.ExchangeRate, error)
+	SetRate(from, to money.Currency, rate string) (model.ExchangeRate, error)
+	Convert(m money.Money, to money.Currency) (money.Money, error)
+	// Rate from→to kuru; çeviriyi SQL'de yapan sorgular için (ürün listesi fiyat filtresi).
+	Rate(from, to money.Currency) (*big.Rat, error)
+	// Converter liste çevirileri için: kurları çağrı boyunca bir kez okur.
+	Converter(to money.Currency) func(money.Money) (money.Money, error)
+}
+
+type currencyService struct {
+	db  *gorm.DB
+	def money.Currency
+}
+
+func NewCurrencyService(db *gorm.DB) CurrencyService {
+	def, err := money.ParseCurrency(os.Getenv("DEFAULT_CURRENCY"))
+	if err != nil {
+		def = "TRY"
+	}
+	return &currencyService{db: db, def: def}
+}
+
+func (s *currencyService) Default() money.Currency { return s.def }
+
+func (s *currencyService) Rates() ([]model.ExchangeRate, error) {
+	var rs []model.ExchangeRate
+	return rs, s.db.Order("from_currency asc, to_currency asc").Find(&rs).Error
+}
+
+func (s *currencyService) SetRate(from, to money.Currency, rate string) (model.ExchangeRate, error) {
+	r, err := money.ParseRate(rate)
+	if err != nil {
+		return model.ExchangeRate{}, err
+	}
+	er := model.ExchangeRate{From: string(from), To: string(to), Rate: r.FloatString(10)}
+	err = s.db.Clauses(clause.OnConflict{
+		Columns:   []clause.Column{{Name: "from_currency"}, {Name: "to_currency"}},
+		DoUpdates: clause.AssignmentColumns([]string{"rate", "updated_at"}),
+	}).Create(&er).Error
+	return er, err
+}
+
+// Rate from→to: doğrudan kur, yoksa ters kurun tersi.
+func (s *currencyService) Rate(from, to money.Currency) (*big.Rat, error) {
+	var er model.ExchangeRate
+	err := s.db.Where("from_currency = ? AND to_currency = ?", from, to).First(&er).Error
+	if err == nil {
+		return money.ParseRate(er.Rate)
+	}
+	if !errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, err
+	}
+	err = s.db.Where("from_currency = ? AND to_currency = ?", to, from).First(&er).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, ErrNoExchangeRate
+	}
+	if err != nil {
+		return nil, err
+	}
+	r, err := money.ParseRate(er.Rate)
+	if err != nil {
+		return nil, err
+	}
+	return new(big.Rat).Inv(r), nil
+}
+
+func (s *currencyService) Convert(m money.Money, to money.Currency) (money.Money, error) {
+	return s.Converter(to)(m)
+}
+
+func (s *currencyService) Converter(to money.Currency) func(money.Money) (money.Money, error) {
+	rates := map[money.Currency]*big.Rat{}
+	return func(m money.Money) (money.Money, error) {
+		if m.Currency == to {
+			return m, nil
+		}
+		r, ok := rates[m.Currency]
+		if !ok {
+			var err error
+			if r, err = s.Rate(m.Currency, to); err != nil {
+				return m, err
+			}
+			rates[m.Currency] = r
+		}
+		return money.Convert(m, to, r), nil
+	}
+}
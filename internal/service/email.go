@@ -3,6 +3,8 @@ package service
 import (
 	"crypto/tls"
 	"fmt"
+	"html"
+	"io"
 	"os"
 	"strconv"
 	"strings"
@@ -12,7 +14,14 @@ import (
 )
 
 type EmailService interface {
-	Send(to, subject, htmlBody string) error
+	Send(to, subject, htmlBody string, attachments ...Attachment) error
+}
+
+// Attachment maile eklenecek dosya (ör. fatura PDF'i).
+type Attachment struct {
+	Name        string
+	ContentType string
+	Data        []byte
 }
 
 type emailService struct {
@@ -47,31 +56,17 @@ func NewEmailService() EmailService {
 	}
 }
 
-func (e *emailService) Send(to, subject, verifyLink string) error {
-    m := gomail.NewMessage()
-    e.fromName = os.Getenv("SMTP_FROM_NAME")
-
-    // From: (fromName varsa kullan; yoksa sade e.from)
-    if e.fromName != "" {
-        m.SetHeader("From", fmt.Sprintf("%s <%s>", e.fromName, e.from))
-    } else {
-        m.SetHeader("From", "Cakarokko <no-reply@cakarokko.com>")
-    }
-
-    m.SetHeader("To", to)
-    m.SetHeader("Subject", subject)
-
-    // ✅ Spam dostu, profesyonel HTML gövde
-    htmlBody := fmt.Sprintf(`
+// buttonMailHTML tek butonlu (link) e-posta gövdesi: doğrulama, giriş linki vb.
+func buttonMailHTML(heading, text, button, link string) string {
+    return fmt.Sprintf(`
     <html>
       <body style="font-family: Arial, sans-serif; color: #333; background-color:#f9f9f9; padding: 20px;">
-        <h2 style="color:#4CAF50;">Welcome to Cakarokko 🎉</h2>
-        <p>Thank you for registering with <strong>Cakarokko</strong>!</p>
-        <p>To complete your registration, please verify your email by clicking the button below:</p>
+        <h2 style="color:#4CAF50;">%s</h2>
+        <p>%s</p>
 
         <p style="margin: 30px 0;">
           <a href="%s" style="display:inline-block;background:#4CAF50;color:white;padding:14px 24px;text-decoration:none;border-radius:5px;">
-            ✅ Verify My Email
+            %s
           </a>
         </p>
 
@@ -83,19 +78,31 @@ func (e *emailService) Send(to, subject, verifyLink string) error {
         </p>
       </body>
     </html>
-    `, verifyLink)
+    `, html.EscapeString(heading), html.EscapeString(text), html.EscapeString(link), html.EscapeString(button))
+}
 
-    // HTML gövde
-    m.SetBody("text/html", htmlBody)
+func (e *emailService) Send(to, subject, htmlBody string, attachments ...Attachment) error {
+    m := gomail.NewMessage()
+    e.fromName = os.Getenv("SMTP_FROM_NAME")
 
-// Plain-text alternatif: link YOK
-//	plain := "Merhaba!\n\nAşağıdaki 6 haneli kodu 15 dakika içinde sitedeki doğrulama kutusuna gir:\n\n" +
-  //      	 "KOD: " + code + "\n\n" +
-    //  	 	  "Bu işlemi sen başlatmadıysan bu e-postayı yok sayabilirsin."
-//	m.AddAlternative("text/plain", plain)
+    // From: (fromName varsa kullan; yoksa sade e.from)
+    if e.fromName != "" {
+        m.SetHeader("From", fmt.Sprintf("%s <%s>", e.fromName, e.from))
+    } else {
+        m.SetHeader("From", "Cakarokko <no-reply@cakarokko.com>")
+    }
 
-    // ✅ Plain text alternatif (Gmail ve Outlook spam skoru için önemli!)
-//    m.AddAlternative("text/plain", "Merhaba! E-postanı doğrulamak için bu bağlantıya tıkla: "+verifyLink)
+    m.SetHeader("To", to)
+    m.SetHeader("Subject", subject)
+
+    // HTML gövde (çağıran hazırlar: codeMailHTML, buttonMailHTML, ...)
+    m.SetBody("text/html", htmlBody)
+    for _, a := range attachments {
+        data := a.Data
+        m.Attach(a.Name,
+            gomail.SetCopyFunc(func(w io.Writer) error { _, err := w.Write(data); return err }),
+            gomail.SetHeader(map[string][]string{"Content-Type": {a.ContentType}}))
+    }
 
     d := gomail.NewDialer(e.host, e.port, e.username, e.password)
 
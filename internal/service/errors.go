@@ -5,4 +5,72 @@ import "errors"
 var (
     ErrExistsVerified   = errors.New("exists-verified")
     ErrExistsUnverified = errors.New("exists-unverified")
+    ErrProductNotFound  = errors.New("product not found")
+    ErrQtyLimit         = errors.New("qty limit exceeded")
+    ErrInvalidCursor    = errors.New("invalid cursor")
+
+    ErrCategoryNotFound    = errors.New("category not found")
+    ErrCategoryCycle       = errors.New("category cannot be moved under itself")
+    ErrCategoryHasChildren = errors.New("category has subcategories")
+    ErrSlugTaken           = errors.New("slug already exists")
+
+    ErrVariantNotFound = errors.New("variant not found")
+    ErrVariantRequired = errors.New("choose a variant for this product")
+    ErrVariantExists   = errors.New("a variant with these options already exists")
+    ErrOptionNotFound  = errors.New("option not found")
+    ErrHasVariants     = errors.New("product already has variants")
+    ErrSKUTaken        = errors.New("sku already exists")
+    ErrOutOfStock      = errors.New("not enough stock")
+
+    ErrImageNotFound    = errors.New("image not found")
+    ErrImageTooLarge    = errors.New("image too large")
+    ErrUnsupportedImage = errors.New("unsupported image type")
+    ErrThumbSize        = errors.New("unsupported thumbnail size")
+
+    ErrNoExchangeRate = errors.New("no exchange rate for currency")
+    ErrNoTaxRate      = errors.New("no tax rate for class")
+
+    ErrAddressNotFound        = errors.New("address not found")
+    ErrAddressRequired        = errors.New("a shipping address is required")
+    ErrAddressLimit           = errors.New("address book is full")
+    ErrShippingMethodNotFound = errors.New("shipping method not available")
+    ErrShippingCodeTaken      = errors.New("shipping method code already exists")
+
+    ErrPromotionNotFound = errors.New("promotion not found")
+    ErrCouponNotFound    = errors.New("coupon not found")
+    ErrCouponInvalid     = errors.New("coupon cannot be applied")
+    ErrCouponExhausted   = errors.New("coupon usage limit reached")
+    ErrCouponCodeTaken   = errors.New("coupon code already exists")
+
+    ErrPricesChanged = errors.New("prices changed")
+
+    ErrOrderNotFound       = errors.New("order not found")
+    ErrOrderNotCancellable = errors.New("order can no longer be cancelled")
+    ErrOrderNotRefundable  = errors.New("order cannot be refunded")
+    ErrOrderNotShippable   = errors.New("order cannot be shipped")
+    ErrOrderNotReturnable  = errors.New("order cannot be returned")
+
+    ErrReturnNotFound = errors.New("return not found")
+    ErrReturnStatus   = errors.New("return is not in a state for this action")
+
+    ErrInvoiceNotFound = errors.New("invoice not found")
+
+    ErrEmailTaken       = errors.New("email already exists")
+    ErrInvalidCode      = errors.New("invalid code")
+    ErrCodeExpired      = errors.New("code expired")
+    ErrNoPendingEmail   = errors.New("no pending email change")
+    ErrReauthRequired   = errors.New("sign in again to confirm this action")
+
+    ErrMFAAlreadyEnabled = errors.New("two-factor already enabled")
+    ErrMFANotEnrolled    = errors.New("two-factor not enrolled")
+    ErrInvalidMFACode    = errors.New("invalid two-factor code")
+    ErrInvalidMFAToken   = errors.New("invalid or expired mfa token")
+    ErrMFALocked         = errors.New("too many two-factor attempts, try again later")
+
+    ErrUnknownProvider     = errors.New("unknown login provider")
+    ErrOIDCState           = errors.New("invalid or expired login state")
+    ErrOIDCToken           = errors.New("invalid id token")
+    ErrOIDCEmailUnverified = errors.New("provider did not return a verified email")
+
+    ErrInvalidMagicLink = errors.New("invalid or expired sign-in link")
 )
@@ -0,0 +1,301 @@
+package service
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"image"
+	_ "image/gif" // image.Decode için kayıt
+	"image/jpeg"
+	"image/png"
+	"io"
+	"log"
+	"net/http"
+	"os"
+	"path"
+	"strconv"
+	"strings"
+	"sync"
+
+	"golang.org/x/image/draw"
+	_ "golang.org/x/image/webp"
+	"gorm.io/gorm"
+
+	"example.com/ecom-go/internal/model"
+	"example.com/ecom-go/internal/storage"
+	"example.com/ecom-go/internal/validate"
+)
+
+// ThumbWidths izin verilen thumbnail genişlikleri (keyfi boyutla disk doldurulmasın).
+var ThumbWidths = []int{160, model.ListThumbWidth, 640}
+
+// Kabul edilen içerik tipleri (uzantıya değil içeriğe bakılır).
+var imageExts = map[string]string{
+	"image/jpeg": ".jpg",
+	"image/png":  ".png",
+	"image/gif":  ".gif",
+	"image/webp": ".webp",
+}
+
+// maxImagePixels decompression bomb'a karşı üst sınır (~40 MP).
+const maxImagePixels = 40_000_000
+
+type ImageService interface {
+	Upload(productID uint, r io.Reader) (model.ProductImage, error)
+	// Reorder ids ürünün tüm görsellerini yeni sırasıyla içermeli.
+	Reorder(productID uint, ids []uint) error
+	Delete(id uint) error
+	Open(ctx context.Context, id uint) (io.ReadCloser, storage.Info, error)
+	// Thumb ilk istekte üretip storage'a yazar, sonra oradan servis eder.
+	Thumb(ctx context.Context, id uint, width int) (io.ReadCloser, storage.Info, error)
+	MaxBytes() int64
+}
+
+type imageService struct {
+	db       *gorm.DB
+	store    storage.Storage
+	maxBytes int64
+	locks    sync.Map // thumbnail key → *sync.Mutex
+}
+
+// NewImageService IMAGE_MAX_BYTES (varsayılan 5 MB) yükleme sınırı.
+func NewImageService(db *gorm.DB, store storage.Storage) ImageService {
+	limit := int64(5 << 20)
+	if v, err := strconv.ParseInt(os.Getenv("IMAGE_MAX_BYTES"), 10, 64); err == nil && v > 0 {
+		limit = v
+	}
+	return &imageService{db: db, store: store, maxBytes: limit}
+}
+
+func (s *imageService) MaxBytes() int64 { return s.maxBytes }
+
+func (s *imageService) Upload(productID uint, r io.Reader) (model.ProductImage, error) {
+	var img model.ProductImage
+	if err := productExists(s.db, productID); err != nil {
+		return img, err
+	}
+	data, err := io.ReadAll(io.LimitReader(r, s.maxBytes+1))
+	if err != nil {
+		return img, err
+	}
+	if int64(len(data)) > s.maxBytes {
+		return img, ErrImageTooLarge
+	}
+	ctype := http.DetectContentType(data)
+	ext, ok := imageExts[ctype]
+	if !ok {
+		return img, ErrUnsupportedImage
+	}
+	// gerçekten çözülebiliyor mu + boyut
+	cfg, _, err := image.DecodeConfig(bytes.NewReader(data))
+	if err != nil {
+		return img, ErrUnsupportedImage
+	}
+	if cfg.Width*cfg.Height > maxImagePixels {
+		return img, ErrImageTooLarge
+	}
+
+	name, err := randURLSafe(12)
+	if err != nil {
+		return img, err
+	}
+	key := fmt.Sprintf("products/%d/%s%s", productID, name, ext)
+	ctx := context.Background()
+	if err := s.store.Put(ctx, key, bytes.NewReader(data), ctype); err != nil {
+		return img, err
+	}
+	err = s.db.Transaction(func(tx *gorm.DB) error {
+		var n int64
+		if err := tx.Model(&model.ProductImage{}).Where("product_id = ?", productID).Count(&n).Error; err != nil {
+			return err
+		}
+		img = model.ProductImage{
+			ProductID: productID, Key: key, ContentType: ctype,
+			Width: cfg.Width, Height: cfg.Height, Position: int(n),
+		}
+		if err := tx.Create(&img).Error; err != nil {
+			return err
+		}
+		return syncCover(tx, productID)
+	})
+	if err != nil {
+		_ = s.store.Delete(ctx, key)
+		return img, err
+	}
+	_ = img.AfterFind(nil)
+	return img, nil
+}
+
+// syncCover Product.ImageURL'i ilk görselin liste thumbnail'ine çeker;
+// görsel kalmadıysa ve URL bizim /media adresimizse temizler.
+func syncCover(tx *gorm.DB, productID uint) error {
+	var first model.ProductImage
+	err := tx.Where("product_id = ?", productID).Order("position asc, id asc").First(&first).Error
+	q := tx.Model(&model.Product{}).Where("id = ?", productID)
+	switch {
+	case errors.Is(err, gorm.ErrRecordNotFound):
+		return q.Where("image_url LIKE ?", "/media/%").Update("image_url", "").Error
+	case err != nil:
+		return err
+	}
+	return q.Update("image_url", model.ImageThumbURL(first.ID, model.ListThumbWidth)).Error
+}
+
+func (s *imageService) Reorder(productID uint, ids []uint) error {
+	return s.db.Transaction(func(tx *gorm.DB) error {
+		var have []uint
+		if err := tx.Model(&model.ProductImage{}).Where("product_id = ?", productID).Pluck("id", &have).Error; err != nil {
+			return err
+		}
+		if len(ids) != len(have) || comboKey(uniqueIDs(ids)) != comboKey(uniqueIDs(have)) {
+			return validate.Errors{"image_ids": "must list every image of the product exactly once"}
+		}
+		for pos, id := range ids {
+			if err := tx.Model(&model.ProductImage{}).Where("id = ?", id).Update("position", pos).Error; err != nil {
+				return err
+			}
+		}
+		return syncCover(tx, productID)
+	})
+}
+
+func (s *imageService) Delete(id uint) error {
+	var img model.ProductImage
+	err := s.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.First(&img, id).Error; err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				return ErrImageNotFound
+			}
+			return err
+		}
+		if err := tx.Delete(&img).Error; err != nil {
+			return err
+		}
+		// aradaki boşluğu kapat
+		if err := tx.Model(&model.ProductImage{}).
+			Where("product_id = ? AND position > ?", img.ProductID, img.Position).
+			Update("position", gorm.Expr("position - 1")).Error; err != nil {
+			return err
+		}
+		return syncCover(tx, img.ProductID)
+	})
+	if err != nil {
+		return err
+	}
+	// dosyalar best-effort: kayıt gittiyse URL zaten 404
+	ctx := context.Background()
+	keys := []string{img.Key}
+	for _, w := range ThumbWidths {
+		keys = append(keys, thumbKey(img.Key, w))
+	}
+	for _, k := range keys {
+		if err := s.store.Delete(ctx, k); err != nil {
+			log.Printf("image delete %s: %v", k, err)
+		}
+	}
+	return nil
+}
+
+func (s *imageService) find(id uint) (model.ProductImage, error) {
+	var img model.ProductImage
+	err := s.db.First(&img, id).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return img, ErrImageNotFound
+	}
+	return img, err
+}
+
+func (s *imageService) Open(ctx context.Context, id uint) (io.ReadCloser, storage.Info, error) {
+	img, err := s.find(id)
+	if err != nil {
+		return nil, storage.Info{}, err
+	}
+	rc, info, err := s.store.Get(ctx, img.Key)
+	if errors.Is(err, storage.ErrNotFound) {
+		return nil, info, ErrImageNotFound
+	}
+	info.ContentType = img.ContentType
+	return rc, info, err
+}
+
+// thumbKey "products/1/ab.webp" → "thumbs/320/products/1/ab.jpg".
+// Şeffaflık olabilen kaynaklar PNG, diğerleri JPEG'e küçültülür.
+func thumbKey(key string, w int) string {
+	ext := ".jpg"
+	if e := path.Ext(key); e == ".png" || e == ".gif" {
+		ext = ".png"
+	}
+	return fmt.Sprintf("thumbs/%d/%s%s", w, strings.TrimSuffix(key, path.Ext(key)), ext)
+}
+
+func (s *imageService) Thumb(ctx context.Context, id uint, width int) (io.ReadCloser, storage.Info, error) {
+	ok := false
+	for _, w := range ThumbWidths {
+		ok = ok || w == width
+	}
+	if !ok {
+		return nil, storage.Info{}, ErrThumbSize
+	}
+	img, err := s.find(id)
+	if err != nil {
+		return nil, storage.Info{}, err
+	}
+	key := thumbKey(img.Key, width)
+	if rc, info, err := s.store.Get(ctx, key); !errors.Is(err, storage.ErrNotFound) {
+		return rc, info, err
+	}
+
+	// aynı thumbnail'i eşzamanlı istekler bir kez üretsin
+	mu, _ := s.locks.LoadOrStore(key, &sync.Mutex{})
+	mu.(*sync.Mutex).Lock()
+	defer mu.(*sync.Mutex).Unlock()
+	if rc, info, err := s.store.Get(ctx, key); !errors.Is(err, storage.ErrNotFound) {
+		return rc, info, err
+	}
+
+	src, _, err := s.store.Get(ctx, img.Key)
+	if errors.Is(err, storage.ErrNotFound) {
+		return nil, storage.Info{}, ErrImageNotFound
+	}
+	if err != nil {
+		return nil, storage.Info{}, err
+	}
+	decoded, _, err := image.Decode(src)
+	src.Close()
+	if err != nil {
+		return nil, storage.Info{}, err
+	}
+
+	var buf bytes.Buffer
+	if err := encodeThumb(&buf, decoded, width, path.Ext(key)); err != nil {
+		return nil, storage.Info{}, err
+	}
+	ctype := "image/jpeg"
+	if path.Ext(key) == ".png" {
+		ctype = "image/png"
+	}
+	if err := s.store.Put(ctx, key, bytes.NewReader(buf.Bytes()), ctype); err != nil {
+		return nil, storage.Info{}, err
+	}
+	return s.store.Get(ctx, key)
+}
+
+// encodeThumb en-boy oranını koruyarak width'e küçültür (büyütmez).
+func encodeThumb(w io.Writer, src image.Image, width int, ext string) error {
+	b := src.Bounds()
+	dw, dh := b.Dx(), b.Dy()
+	if dw > width {
+		dh = max(1, dh*width/dw)
+		dw = width
+	}
+	if ext == ".png" {
+		dst := image.NewNRGBA(image.Rect(0, 0, dw, dh))
+		draw.CatmullRom.Scale(dst, dst.Bounds(), src, b, draw.Src, nil)
+		return png.Encode(w, dst)
+	}
+	dst := image.NewRGBA(image.Rect(0, 0, dw, dh))
+	draw.Draw(dst, dst.Bounds(), image.White, image.Point{}, draw.Src) // JPEG'de şeffaf alan beyaz
+	draw.CatmullRom.Scale(dst, dst.Bounds(), src, b, draw.Over, nil)
+	return jpeg.Encode(w, dst, &jpeg.Options{Quality: 82})
+}
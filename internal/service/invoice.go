@@ -0,0 +1,287 @@
+package service
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+
+	"example.com/ecom-go/internal/model"
+	"example.com/ecom-go/internal/money"
+	"example.com/ecom-go/internal/pdf"
+	"example.com/ecom-go/internal/storage"
+)
+
+// Seller faturada satıcı bilgisi (config'ten).
+type Seller struct {
+	Name      string
+	Address   string // satır sonları "\n" ya da "|" ile
+	TaxOffice string
+	TaxID     string
+	Email     string
+	Phone     string
+}
+
+type InvoiceService interface {
+	// Issue siparişe sıradaki numarayı verir. Checkout tx'i içinde çağrılır:
+	// tx geri alınırsa sayaç da geri alınır, numara boşluğu oluşmaz.
+	Issue(tx *gorm.DB, orderID uint, at time.Time) (model.Invoice, error)
+	// PDF sahibine (userID 0 → admin) faturayı döner; depoda yoksa üretip kaydeder.
+	PDF(ctx context.Context, userID, orderID uint) ([]byte, model.Invoice, error)
+}
+
+type invoiceService struct {
+	db     *gorm.DB
+	store  storage.Storage
+	seller Seller
+	prefix string
+}
+
+func NewInvoiceService(db *gorm.DB, store storage.Storage, seller Seller, prefix string) InvoiceService {
+	if prefix == "" {
+		prefix = "INV"
+	}
+	return &invoiceService{db: db, store: store, seller: seller, prefix: prefix}
+}
+
+func (s *invoiceService) Issue(tx *gorm.DB, orderID uint, at time.Time) (model.Invoice, error) {
+	// seri yıllık: INV-2026; sayaç satırı UPDATE ile kilitlenir, eşzamanlı checkout'lar sıralanır
+	series := fmt.Sprintf("%s-%d", s.prefix, at.Year())
+	if err := tx.Clauses(clause.OnConflict{DoNothing: true}).Create(&model.InvoiceCounter{Series: series}).Error; err != nil {
+		return model.Invoice{}, err
+	}
+	var seq int64
+	if err := tx.Raw("UPDATE invoice_counters SET last = last + 1 WHERE series = ? RETURNING last", series).Scan(&seq).Error; err != nil {
+		return model.Invoice{}, err
+	}
+	inv := model.Invoice{
+		OrderID: orderID, Series: series, Seq: seq,
+		Number: fmt.Sprintf("%s-%06d", series, seq), IssuedAt: at,
+	}
+	return inv, tx.Create(&inv).Error
+}
+
+func (s *invoiceService) PDF(ctx context.Context, userID, orderID uint) ([]byte, model.Invoice, error) {
+	o, err := loadOrder(s.db, userID, orderID, false)
+	if err != nil {
+		return nil, model.Invoice{}, err
+	}
+	var inv model.Invoice
+	if err := s.db.Where("order_id = ?", o.ID).First(&inv).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, inv, ErrInvoiceNotFound
+		}
+		return nil, inv, err
+	}
+	if inv.Key != "" {
+		rc, _, err := s.store.Get(ctx, inv.Key)
+		if err == nil {
+			defer rc.Close()
+			b, err := io.ReadAll(rc)
+			return b, inv, err
+		}
+		if !errors.Is(err, storage.ErrNotFound) {
+			return nil, inv, err
+		}
+	}
+	// ilk istek (ya da depo kaybı): aynı veriden aynı belge üretilir
+	b := renderInvoice(s.seller, inv, o)
+	key := "invoices/" + inv.Number + ".pdf"
+	if err := s.store.Put(ctx, key, bytes.NewReader(b), "application/pdf"); err != nil {
+		return nil, inv, err
+	}
+	if inv.Key != key {
+		inv.Key = key
+		if err := s.db.Model(&inv).Update("key", key).Error; err != nil {
+			return nil, inv, err
+		}
+	}
+	return b, inv, nil
+}
+
+// fatura yerleşimi (pt): sol/sağ kenar ve tablo sütunlarının sağ kenarları
+const (
+	invLeft  = 40.0
+	invRight = pdf.PageWidth - 40
+	invBreak = 110.0 // bunun altına satır yazılmaz, yeni sayfa
+)
+
+var invCols = []struct {
+	title string
+	right float64 // 0 → sola dayalı (ürün adı)
+}{
+	{"Ürün", 0}, {"Adet", 270}, {"Birim Fiyat", 345}, {"İndirim", 405}, {"KDV", 440}, {"Net", 495}, {"Tutar", invRight},
+}
+
+// renderInvoice tutarlar siparişteki snapshot'lardan; ürün/oran sonradan
+// değişse de fatura aynı kalır.
+func renderInvoice(seller Seller, inv model.Invoice, o model.Order) []byte {
+	cur := money.Currency(o.Currency)
+	amt := func(cents int64) string { return money.New(cents, cur).Format(money.DefaultLocale) }
+	d := pdf.New("Fatura " + inv.Number)
+
+	// başlık: satıcı solda, fatura bilgisi sağda
+	y := pdf.PageHeight - 50
+	d.Text(invLeft, y, pdf.Bold, 14, seller.Name)
+	d.TextRight(invRight, y, pdf.Bold, 18, "FATURA")
+	sy := y - 16
+	for _, l := range strings.FieldsFunc(seller.Address, func(r rune) bool { return r == '\n' || r == '|' }) {
+		d.Text(invLeft, sy, pdf.Regular, 9, strings.TrimSpace(l))
+		sy -= 12
+	}
+	if seller.TaxOffice != "" || seller.TaxID != "" {
+		d.Text(invLeft, sy, pdf.Regular, 9, strings.TrimSpace(fmt.Sprintf("Vergi Dairesi: %s  VKN: %s", seller.TaxOffice, seller.TaxID)))
+		sy -= 12
+	}
+	if contact := strings.Trim(seller.Email+"  "+seller.Phone, " "); contact != "" {
+		d.Text(invLeft, sy, pdf.Regular, 9, contact)
+		sy -= 12
+	}
+	my := y - 22
+	for _, kv := range [][2]string{
+		{"Fatura No", inv.Number},
+		{"Tarih", inv.IssuedAt.Format("02.01.2006")},
+		{"Sipariş No", fmt.Sprintf("#%d", o.ID)},
+		{"Para Birimi", o.Currency},
+	} {
+		d.TextRight(invRight-110, my, pdf.Bold, 9, kv[0])
+		d.TextRight(invRight, my, pdf.Regular, 9, kv[1])
+		my -= 12
+	}
+
+	// alıcı: teslimat adresi snapshot'ı
+	y = min(sy, my) - 14
+	d.Text(invLeft, y, pdf.Bold, 10, "Alıcı")
+	y -= 13
+	a := o.ShipTo
+	for _, l := range []string{
+		a.FullName, a.Line1, a.Line2,
+		strings.TrimSpace(strings.Join([]string{a.PostalCode, a.District, a.City}, " ")) + " / " + a.Country,
+		a.Phone,
+	} {
+		if strings.TrimSpace(l) == "" {
+			continue
+		}
+		d.Text(invLeft, y, pdf.Regular, 9, l)
+		y -= 12
+	}
+
+	// satırlar
+	y -= 12
+	header := func() {
+		for _, c := range invCols {
+			if c.right == 0 {
+				d.Text(invLeft, y, pdf.Bold, 9, c.title)
+			} else {
+				d.TextRight(c.right, y, pdf.Bold, 9, c.title)
+			}
+		}
+		d.Line(invLeft, y-4, invRight, y-4, 0.7)
+		y -= 16
+	}
+	row := func(name string, cells ...string) {
+		if y < invBreak {
+			d.AddPage()
+			y = pdf.PageHeight - 50
+			header()
+		}
+		d.Text(invLeft, y, pdf.Regular, 9, fit(name, invCols[1].right-35-invLeft))
+		for i, v := range cells {
+			d.TextRight(invCols[i+1].right, y, pdf.Regular, 9, v)
+		}
+		y -= 13
+	}
+	header()
+
+	type bucket struct{ net, tax int64 }
+	byRate := map[int]*bucket{}
+	add := func(bp int, net, tax int64) {
+		b := byRate[bp]
+		if b == nil {
+			b = &bucket{}
+			byRate[bp] = b
+		}
+		b.net += net
+		b.tax += tax
+	}
+	for _, it := range o.Items {
+		name := it.Name
+		if len(it.Options) > 0 {
+			opts := make([]string, len(it.Options))
+			for i, op := range it.Options {
+				opts[i] = op.Value
+			}
+			name += " (" + strings.Join(opts, ", ") + ")"
+		}
+		disc := ""
+		if it.DiscountCents != 0 {
+			disc = amt(-it.DiscountCents)
+		}
+		row(name, fmt.Sprint(it.Qty), amt(it.PriceCents), disc, percent(it.TaxBasisPoints), amt(it.NetCents), amt(it.NetCents+it.TaxCents))
+		add(it.TaxBasisPoints, it.NetCents, it.TaxCents)
+	}
+	if o.ShippingMethod != "" {
+		row("Kargo ("+o.ShippingMethod+")", "1", "", "", percent(o.ShippingTaxBasisPoints), amt(o.ShippingCents), amt(o.ShippingCents+o.ShippingTaxCents))
+		add(o.ShippingTaxBasisPoints, o.ShippingCents, o.ShippingTaxCents)
+	}
+	d.Line(invLeft, y+6, invRight, y+6, 0.7)
+
+	// KDV dökümü + toplamlar; sayfaya sığmıyorsa yeni sayfa
+	rates := make([]int, 0, len(byRate))
+	for bp := range byRate {
+		rates = append(rates, bp)
+	}
+	sort.Ints(rates)
+	if y-float64(len(rates)+6)*13 < 40 {
+		d.AddPage()
+		y = pdf.PageHeight - 50
+	}
+	y -= 12
+	total := func(label, v string, f pdf.Font) {
+		d.TextRight(invRight-110, y, f, 9, label)
+		d.TextRight(invRight, y, f, 9, v)
+		y -= 13
+	}
+	for _, bp := range rates {
+		b := byRate[bp]
+		total(fmt.Sprintf("KDV %s (matrah %s)", percent(bp), amt(b.net)), amt(b.tax), pdf.Regular)
+	}
+	if o.DiscountCents != 0 {
+		total("Uygulanan indirim", amt(-o.DiscountCents), pdf.Regular)
+	}
+	total("Ara toplam (KDV hariç)", amt(o.SubtotalCents+o.ShippingCents), pdf.Regular)
+	total("KDV toplamı", amt(o.TaxCents), pdf.Regular)
+	total("Genel toplam", amt(o.TotalCents), pdf.Bold)
+	if o.TaxIncluded {
+		d.Text(invLeft, 50, pdf.Regular, 8, "Birim fiyatlar KDV dahildir.")
+	}
+	return d.Bytes()
+}
+
+// percent 2000 → "%20", 150 → "%1,5".
+func percent(bp int) string {
+	s := fmt.Sprintf("%d", bp/100)
+	if r := bp % 100; r != 0 {
+		s += strings.TrimRight(fmt.Sprintf(",%02d", r), "0")
+	}
+	return "%" + s
+}
+
+// fit metni genişliğe sığacak şekilde "…" ile kısaltır.
+func fit(s string, width float64) string {
+	if pdf.Width(pdf.Regular, 9, s) <= width {
+		return s
+	}
+	r := []rune(s)
+	for len(r) > 0 && pdf.Width(pdf.Regular, 9, string(r)+"...") > width {
+		r = r[:len(r)-1]
+	}
+	return string(r) + "..."
+}
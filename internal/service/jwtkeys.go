@@ -0,0 +1,335 @@
+package service
+
+import (
+	"crypto"
+	"crypto/ed25519"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"math/big"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+// KeySet JWT imzalama/doğrulama anahtarları.
+//
+// Tek bir imzalama anahtarı (kid ile) + rotasyon için birden fazla doğrulama
+// anahtarı tutar. Asimetrik anahtar verilmezse JWT_SECRET ile HS256'ya düşer
+// (eski davranış); bu durumda JWKS boş döner. Asimetrik anahtar varken
+// JWT_SECRET sadece JWT_LEGACY_UNTIL'e kadar doğrulama anahtarı ("hs256") olarak
+// kalır: geçişten önce dağıtılmış oturumlar o ana kadar geçerli, sonra secret
+// ile imzalanmış hiçbir token kabul edilmez.
+//
+// ENV:
+//
+//	JWT_SIGNING_KEY_FILE  PEM özel anahtar (RSA → RS256, Ed25519 → EdDSA)
+//	JWT_SIGNING_KID       opsiyonel; yoksa public key'den türetilir
+//	JWT_VERIFY_KEY_FILES  virgüllü liste, "kid=path" veya "path" (eski/yeni anahtarlar)
+//	JWT_ISSUER            varsayılan "cakarokko.com"
+//	JWT_AUDIENCE          varsayılan "ecom-api"
+//	JWT_LEGACY_UNTIL      RFC 3339 zaman; boşsa eski HS256 token'lar (kid'siz ya da "hs256")
+//	                      asimetrik anahtarla birlikte hiç kabul edilmez
+type KeySet struct {
+	Issuer   string
+	Audience string
+
+	signKID    string
+	signMethod jwt.SigningMethod
+	signKey    any
+
+	verify map[string]verifyKey
+
+	// kid'siz eski HS256 token'ları (iss/aud yok, sub sayı) legacyUntil'e kadar
+	// kabul edilir. TODO: bir sürüm sonra legacy* alanları ve Parse'taki dal silinecek.
+	legacySecret []byte
+	legacyUntil  time.Time
+}
+
+type verifyKey struct {
+	method jwt.SigningMethod
+	key    any       // *rsa.PublicKey | ed25519.PublicKey | []byte (HS256)
+	until  time.Time // sıfır değilse anahtar bu andan sonra geçersiz, token exp'i de en geç bu an
+}
+
+const hsKID = "hs256"
+
+func LoadKeySet() (*KeySet, error) {
+	ks := &KeySet{
+		Issuer:   envOr("JWT_ISSUER", "cakarokko.com"),
+		Audience: envOr("JWT_AUDIENCE", "ecom-api"),
+		verify:   map[string]verifyKey{},
+	}
+
+	// kesim anı config'ten: süreç başlangıcından türetilirse her deploy pencereyi yeniden açar
+	var legacyUntil time.Time
+	if v := strings.TrimSpace(os.Getenv("JWT_LEGACY_UNTIL")); v != "" {
+		t, err := time.Parse(time.RFC3339, v)
+		if err != nil {
+			return nil, fmt.Errorf("jwt: JWT_LEGACY_UNTIL: %w", err)
+		}
+		legacyUntil = t
+	}
+	secret := []byte(os.Getenv("JWT_SECRET"))
+	if len(secret) > 0 && !legacyUntil.IsZero() {
+		ks.legacySecret, ks.legacyUntil = secret, legacyUntil
+	}
+
+	path := strings.TrimSpace(os.Getenv("JWT_SIGNING_KEY_FILE"))
+	if path == "" {
+		if len(secret) == 0 {
+			return nil, errors.New("jwt: neither JWT_SIGNING_KEY_FILE nor JWT_SECRET is set")
+		}
+		ks.signKID, ks.signMethod, ks.signKey = hsKID, jwt.SigningMethodHS256, secret
+		ks.verify[hsKID] = verifyKey{method: jwt.SigningMethodHS256, key: secret}
+		return ks, nil
+	}
+	// asimetrik imzaya geçildi: secret sadece kesim anına kadar doğrular
+	if len(secret) > 0 && time.Now().Before(legacyUntil) {
+		ks.verify[hsKID] = verifyKey{method: jwt.SigningMethodHS256, key: secret, until: legacyUntil}
+	}
+
+	priv, err := readPEMKey(path)
+	if err != nil {
+		return nil, err
+	}
+	signer, ok := priv.(crypto.Signer)
+	if !ok {
+		return nil, fmt.Errorf("jwt: %s is not a private key", path)
+	}
+	vk, err := newVerifyKey(signer.Public())
+	if err != nil {
+		return nil, err
+	}
+	kid := strings.TrimSpace(os.Getenv("JWT_SIGNING_KID"))
+	if kid == "" {
+		if kid, err = keyID(signer.Public()); err != nil {
+			return nil, err
+		}
+	}
+	ks.signKID, ks.signMethod, ks.signKey = kid, vk.method, priv
+	ks.verify[kid] = vk
+
+	for _, item := range strings.Split(os.Getenv("JWT_VERIFY_KEY_FILES"), ",") {
+		item = strings.TrimSpace(item)
+		if item == "" {
+			continue
+		}
+		kid, p, found := strings.Cut(item, "=")
+		if !found {
+			kid, p = "", item
+		}
+		k, err := readPEMKey(p)
+		if err != nil {
+			return nil, err
+		}
+		if s, ok := k.(crypto.Signer); ok {
+			k = s.Public()
+		}
+		vk, err := newVerifyKey(k)
+		if err != nil {
+			return nil, fmt.Errorf("jwt: %s: %w", p, err)
+		}
+		if kid == "" {
+			if kid, err = keyID(k); err != nil {
+				return nil, err
+			}
+		}
+		if _, dup := ks.verify[kid]; !dup {
+			ks.verify[kid] = vk
+		}
+	}
+	return ks, nil
+}
+
+func envOr(k, def string) string {
+	if v := strings.TrimSpace(os.Getenv(k)); v != "" {
+		return v
+	}
+	return def
+}
+
+func readPEMKey(path string) (any, error) {
+	raw, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("jwt: read key: %w", err)
+	}
+	block, _ := pem.Decode(raw)
+	if block == nil {
+		return nil, fmt.Errorf("jwt: %s: no PEM block", path)
+	}
+	switch block.Type {
+	case "PRIVATE KEY":
+		return x509.ParsePKCS8PrivateKey(block.Bytes)
+	case "RSA PRIVATE KEY":
+		return x509.ParsePKCS1PrivateKey(block.Bytes)
+	case "PUBLIC KEY":
+		return x509.ParsePKIXPublicKey(block.Bytes)
+	case "RSA PUBLIC KEY":
+		return x509.ParsePKCS1PublicKey(block.Bytes)
+	}
+	return nil, fmt.Errorf("jwt: %s: unsupported PEM type %q", path, block.Type)
+}
+
+func newVerifyKey(pub any) (verifyKey, error) {
+	switch k := pub.(type) {
+	case *rsa.PublicKey:
+		if k.N.BitLen() < 2048 {
+			return verifyKey{}, errors.New("jwt: RSA key must be at least 2048 bits")
+		}
+		return verifyKey{method: jwt.SigningMethodRS256, key: k}, nil
+	case ed25519.PublicKey:
+		return verifyKey{method: jwt.SigningMethodEdDSA, key: k}, nil
+	}
+	return verifyKey{}, fmt.Errorf("jwt: unsupported key type %T", pub)
+}
+
+// keyID public key DER'inin SHA-256'sından kısa, kararlı bir kid üretir.
+func keyID(pub any) (string, error) {
+	der, err := x509.MarshalPKIXPublicKey(pub)
+	if err != nil {
+		return "", err
+	}
+	sum := sha256.Sum256(der)
+	return hex.EncodeToString(sum[:8]), nil
+}
+
+// Sign standart claim'leri (iss, aud, iat, exp) ekleyip kid header'ıyla imzalar.
+func (ks *KeySet) Sign(claims jwt.MapClaims, ttl time.Duration) (string, error) {
+	now := time.Now()
+	claims["iss"] = ks.Issuer
+	claims["aud"] = ks.Audience
+	claims["iat"] = now.Unix()
+	claims["exp"] = now.Add(ttl).Unix()
+	t := jwt.NewWithClaims(ks.signMethod, claims)
+	t.Header["kid"] = ks.signKID
+	return t.SignedString(ks.signKey)
+}
+
+// Parse imza, alg (anahtarın algoritmasıyla birebir), iss, aud ve exp'i doğrular.
+func (ks *KeySet) Parse(token string) (jwt.MapClaims, error) {
+	if ks.isLegacy(token) {
+		return ks.parseLegacy(token)
+	}
+	methods := make([]string, 0, len(ks.verify))
+	seen := map[string]bool{}
+	for _, vk := range ks.verify {
+		if a := vk.method.Alg(); !seen[a] {
+			seen[a] = true
+			methods = append(methods, a)
+		}
+	}
+	claims := jwt.MapClaims{}
+	var used verifyKey
+	_, err := jwt.ParseWithClaims(token, claims, func(t *jwt.Token) (interface{}, error) {
+		kid, _ := t.Header["kid"].(string)
+		vk, ok := ks.verify[kid]
+		if !ok {
+			return nil, fmt.Errorf("unknown kid %q", kid)
+		}
+		if !vk.until.IsZero() && !time.Now().Before(vk.until) {
+			return nil, fmt.Errorf("kid %q retired", kid)
+		}
+		used = vk
+		// alg confusion engeli: token'ın alg'ı bu anahtarın alg'ı olmalı
+		if t.Method.Alg() != vk.method.Alg() {
+			return nil, fmt.Errorf("unexpected alg %q for kid %q", t.Method.Alg(), kid)
+		}
+		return vk.key, nil
+	},
+		jwt.WithValidMethods(methods),
+		jwt.WithIssuer(ks.Issuer),
+		jwt.WithAudience(ks.Audience),
+		jwt.WithExpirationRequired(),
+	)
+	if err != nil {
+		return nil, err
+	}
+	// emekli olacak anahtarla kesimden sonrasına uzanan token basılamasın
+	if !used.until.IsZero() {
+		if exp, _ := claims.GetExpirationTime(); exp == nil || exp.After(used.until) {
+			return nil, errors.New("token outlives its key")
+		}
+	}
+	return claims, nil
+}
+
+// isLegacy kid header'ı olmayan token, geçiş süresi içindeyse eski yoldan doğrulanır.
+func (ks *KeySet) isLegacy(token string) bool {
+	if ks.legacySecret == nil || !time.Now().Before(ks.legacyUntil) {
+		return false
+	}
+	t, _, err := jwt.NewParser().ParseUnverified(token, jwt.MapClaims{})
+	if err != nil {
+		return false
+	}
+	_, hasKID := t.Header["kid"]
+	return !hasKID
+}
+
+// parseLegacy eski ParseToken'ın kabul ettiği biçim: HS256, JWT_SECRET, exp zorunlu;
+// iss/aud yok. Sayısal sub, parseTypedClaims'in beklediği string'e çevrilir.
+func (ks *KeySet) parseLegacy(token string) (jwt.MapClaims, error) {
+	claims := jwt.MapClaims{}
+	_, err := jwt.ParseWithClaims(token, claims, func(t *jwt.Token) (interface{}, error) {
+		return ks.legacySecret, nil
+	},
+		jwt.WithValidMethods([]string{jwt.SigningMethodHS256.Alg()}),
+		jwt.WithExpirationRequired(),
+	)
+	if err != nil {
+		return nil, err
+	}
+	// geçiş sonrası imzalanmış kid'siz token olamaz: exp pencereyi aşamaz
+	if exp, _ := claims.GetExpirationTime(); exp == nil || exp.After(ks.legacyUntil) {
+		return nil, errors.New("legacy token outside migration window")
+	}
+	// eski sürüm sadece session token'ı imzalıyordu
+	if claims["typ"] != "session" {
+		return nil, errors.New("invalid token type")
+	}
+	if sub, ok := claims["sub"].(float64); ok {
+		claims["sub"] = strconv.FormatUint(uint64(sub), 10)
+	}
+	return claims, nil
+}
+
+// JWK /.well-known/jwks.json içindeki tek anahtar.
+type JWK struct {
+	Kty string `json:"kty"`
+	Kid string `json:"kid"`
+	Use string `json:"use"`
+	Alg string `json:"alg"`
+	N   string `json:"n,omitempty"`
+	E   string `json:"e,omitempty"`
+	Crv string `json:"crv,omitempty"`
+	X   string `json:"x,omitempty"`
+}
+
+// JWKS sadece asimetrik doğrulama anahtarlarını yayınlar (HS256 secret asla).
+func (ks *KeySet) JWKS() map[string][]JWK {
+	b64 := base64.RawURLEncoding
+	keys := []JWK{}
+	for kid, vk := range ks.verify {
+		switch k := vk.key.(type) {
+		case *rsa.PublicKey:
+			keys = append(keys, JWK{Kty: "RSA", Kid: kid, Use: "sig", Alg: "RS256",
+				N: b64.EncodeToString(k.N.Bytes()),
+				E: b64.EncodeToString(big.NewInt(int64(k.E)).Bytes())})
+		case ed25519.PublicKey:
+			keys = append(keys, JWK{Kty: "OKP", Kid: kid, Use: "sig", Alg: "EdDSA",
+				Crv: "Ed25519", X: b64.EncodeToString(k)})
+		}
+	}
+	sort.Slice(keys, func(i, j int) bool { return keys[i].Kid < keys[j].Kid })
+	return map[string][]JWK{"keys": keys}
+}
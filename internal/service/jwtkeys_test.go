@@ -0,0 +1,124 @@
+package service
+
+import (
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/pem"
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+// rsaKeySet RS256 ile imzalayan, JWT_SECRET'ı legacyUntil'e kadar doğrulayan KeySet.
+func rsaKeySet(t *testing.T, legacyUntil time.Time) (*KeySet, *rsa.PrivateKey) {
+	t.Helper()
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+	secret := []byte("legacy-secret")
+	ks := &KeySet{
+		Issuer: "test", Audience: "test",
+		signKID: "rsa-1", signMethod: jwt.SigningMethodRS256, signKey: key,
+		verify:       map[string]verifyKey{"rsa-1": {method: jwt.SigningMethodRS256, key: &key.PublicKey}},
+		legacySecret: secret, legacyUntil: legacyUntil,
+	}
+	if time.Now().Before(legacyUntil) {
+		ks.verify[hsKID] = verifyKey{method: jwt.SigningMethodHS256, key: secret, until: legacyUntil}
+	}
+	return ks, key
+}
+
+// rawToken header'daki kid'i (boşsa hiç koymadan) elle seçerek imzalar.
+func rawToken(t *testing.T, m jwt.SigningMethod, kid string, claims jwt.MapClaims, key any) string {
+	t.Helper()
+	tok := jwt.NewWithClaims(m, claims)
+	if kid != "" {
+		tok.Header["kid"] = kid
+	}
+	s, err := tok.SignedString(key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return s
+}
+
+func TestKeySetParse(t *testing.T) {
+	until := time.Now().Add(time.Hour)
+	ks, key := rsaKeySet(t, until)
+	pubPEM := func() []byte {
+		der, _ := x509.MarshalPKIXPublicKey(&key.PublicKey)
+		return pem.EncodeToMemory(&pem.Block{Type: "PUBLIC KEY", Bytes: der})
+	}()
+	std := func(exp time.Time) jwt.MapClaims {
+		c := jwt.MapClaims{"sub": "7", "typ": "session", "iss": "test", "aud": "test"}
+		if !exp.IsZero() {
+			c["exp"] = exp.Unix()
+		}
+		return c
+	}
+	legacy := func(exp time.Time, typ string) jwt.MapClaims {
+		return jwt.MapClaims{"sub": float64(7), "typ": typ, "exp": exp.Unix()}
+	}
+	signed, err := ks.Sign(jwt.MapClaims{"sub": "7", "typ": "session"}, time.Minute)
+	if err != nil {
+		t.Fatal(err)
+	}
+	inWindow := until.Add(-time.Minute)
+
+	for _, tc := range []struct {
+		name  string
+		token string
+		ok    bool
+	}{
+		{"signed", signed, true},
+		{"unknown kid", rawToken(t, jwt.SigningMethodRS256, "rsa-2", std(time.Now().Add(time.Minute)), key), false},
+		// alg confusion: public key'i HMAC secret'ı gibi kullanıp RSA kid'iyle HS256
+		{"hs256 with rsa kid", rawToken(t, jwt.SigningMethodHS256, "rsa-1", std(time.Now().Add(time.Minute)), pubPEM), false},
+		{"hs256 with rsa kid and secret", rawToken(t, jwt.SigningMethodHS256, "rsa-1", std(time.Now().Add(time.Minute)), ks.legacySecret), false},
+		{"missing exp", rawToken(t, jwt.SigningMethodRS256, "rsa-1", std(time.Time{}), key), false},
+		{"expired", rawToken(t, jwt.SigningMethodRS256, "rsa-1", std(time.Now().Add(-time.Minute)), key), false},
+		{"wrong audience", rawToken(t, jwt.SigningMethodRS256, "rsa-1", jwt.MapClaims{"iss": "test", "aud": "other", "exp": inWindow.Unix()}, key), false},
+		{"hs256 kid inside window", rawToken(t, jwt.SigningMethodHS256, hsKID, std(inWindow), ks.legacySecret), true},
+		{"hs256 kid exp at cutoff", rawToken(t, jwt.SigningMethodHS256, hsKID, std(until), ks.legacySecret), true},
+		{"hs256 kid outlives cutoff", rawToken(t, jwt.SigningMethodHS256, hsKID, std(until.Add(time.Second)), ks.legacySecret), false},
+		{"legacy inside window", rawToken(t, jwt.SigningMethodHS256, "", legacy(inWindow, "session"), ks.legacySecret), true},
+		{"legacy exp at cutoff", rawToken(t, jwt.SigningMethodHS256, "", legacy(until, "session"), ks.legacySecret), true},
+		{"legacy outlives cutoff", rawToken(t, jwt.SigningMethodHS256, "", legacy(until.Add(time.Second), "session"), ks.legacySecret), false},
+		{"legacy non-session", rawToken(t, jwt.SigningMethodHS256, "", legacy(inWindow, "mfa"), ks.legacySecret), false},
+		{"legacy wrong secret", rawToken(t, jwt.SigningMethodHS256, "", legacy(inWindow, "session"), []byte("other")), false},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			claims, err := ks.Parse(tc.token)
+			if (err == nil) != tc.ok {
+				t.Fatalf("Parse err = %v, want ok=%v", err, tc.ok)
+			}
+			if tc.ok && claims["sub"] != "7" {
+				t.Fatalf("sub = %#v, want \"7\"", claims["sub"])
+			}
+		})
+	}
+}
+
+func TestKeySetParseAfterLegacyCutoff(t *testing.T) {
+	// kesim geçti: secret ile imzalı hiçbir token (kid'li ya da kid'siz) kabul edilmez
+	until := time.Now().Add(-time.Second)
+	ks, _ := rsaKeySet(t, until)
+	exp := time.Now().Add(time.Minute)
+	for name, tok := range map[string]string{
+		"legacy":    rawToken(t, jwt.SigningMethodHS256, "", jwt.MapClaims{"sub": float64(7), "typ": "session", "exp": exp.Unix()}, ks.legacySecret),
+		"hs256 kid": rawToken(t, jwt.SigningMethodHS256, hsKID, jwt.MapClaims{"sub": "7", "typ": "session", "iss": "test", "aud": "test", "exp": exp.Unix()}, ks.legacySecret),
+	} {
+		if _, err := ks.Parse(tok); err == nil {
+			t.Fatalf("%s: token accepted after JWT_LEGACY_UNTIL", name)
+		}
+	}
+	// kesim anı geçmiş olsa bile hs256 doğrulama anahtarı hâlâ yüklüyse until onu emekli eder
+	ks.verify[hsKID] = verifyKey{method: jwt.SigningMethodHS256, key: ks.legacySecret, until: until}
+	tok := rawToken(t, jwt.SigningMethodHS256, hsKID, jwt.MapClaims{"sub": "7", "iss": "test", "aud": "test", "exp": until.Unix()}, ks.legacySecret)
+	if _, err := ks.Parse(tok); err == nil {
+		t.Fatal("retired hs256 kid accepted")
+	}
+}
@@ -0,0 +1,125 @@
+package service
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"log"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+	"gorm.io/gorm"
+
+	"example.com/ecom-go/internal/model"
+)
+
+const (
+	magicLinkTTL      = 15 * time.Minute
+	magicLinkThrottle = time.Minute // aynı kullanıcıya art arda link yollama
+)
+
+func hashJTI(jti string) string {
+	sum := sha256.Sum256([]byte(jti))
+	return hex.EncodeToString(sum[:])
+}
+
+// RequestMagicLink kayıtlı kullanıcıya tek kullanımlık giriş linki yollar.
+// Kullanıcı yoksa sessizce döner (enumeration engeli).
+func (a *authService) RequestMagicLink(email string) error {
+	var u model.User
+	if err := a.db.Select("id, email").Where("LOWER(email) = LOWER(?)", email).First(&u).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil
+		}
+		return err
+	}
+
+	var recent int64
+	if err := a.db.Model(&model.MagicLink{}).
+		Where("user_id = ? AND used_at IS NULL AND created_at > ?", u.ID, time.Now().Add(-magicLinkThrottle)).
+		Count(&recent).Error; err != nil {
+		return err
+	}
+	if recent > 0 {
+		return nil
+	}
+
+	jti, err := randURLSafe(24)
+	if err != nil {
+		return err
+	}
+	if err := a.db.Create(&model.MagicLink{
+		UserID:    u.ID,
+		JTIHash:   hashJTI(jti),
+		ExpiresAt: time.Now().Add(magicLinkTTL),
+	}).Error; err != nil {
+		return err
+	}
+	tok, err := a.keys.Sign(jwt.MapClaims{
+		"sub": signSubject(u.ID),
+		"typ": "magic",
+		"jti": jti,
+	}, magicLinkTTL)
+	if err != nil {
+		return err
+	}
+
+	link := strings.TrimRight(envOr("APP_BASE_URL", "http://localhost:8080"), "/") +
+		"/api/auth/magic?token=" + url.QueryEscape(tok)
+	body := buttonMailHTML("Cakarokko'ya giriş",
+		"Aşağıdaki buton ile 15 dakika içinde şifresiz giriş yapabilirsin. Link yalnızca bir kez çalışır.",
+		"Giriş yap", link)
+	if err := a.email.Send(u.Email, "Giriş linkin", body); err != nil {
+		log.Printf("magic link mail: %v", err)
+		return err
+	}
+	return nil
+}
+
+// ConsumeMagicLink linki tek seferlik tüketir; e-postaya erişim kanıtlandığı için
+// hesap doğrulanmamışsa doğrulanmış sayılır. Doğrulanmamış hesabın şifresi
+// silinir: adresi önceden sahiplenip şifre koyan biri hesapta kalamasın (OIDC linkUser gibi).
+func (a *authService) ConsumeMagicLink(token string) (LoginResult, error) {
+	uid, claims, err := a.parseTypedClaims(token, "magic")
+	if err != nil {
+		return LoginResult{}, ErrInvalidMagicLink
+	}
+	jti, _ := claims["jti"].(string)
+	if jti == "" {
+		return LoginResult{}, ErrInvalidMagicLink
+	}
+
+	var u model.User
+	err = a.db.Transaction(func(tx *gorm.DB) error {
+		// atomik "kullanıldı" işareti → replay engeli
+		res := tx.Model(&model.MagicLink{}).
+			Where("jti_hash = ? AND user_id = ? AND used_at IS NULL AND expires_at > ?", hashJTI(jti), uid, time.Now()).
+			Update("used_at", time.Now())
+		if res.Error != nil {
+			return res.Error
+		}
+		if res.RowsAffected == 0 {
+			return ErrInvalidMagicLink
+		}
+		if err := tx.First(&u, uid).Error; err != nil {
+			return err
+		}
+		if !u.Verified {
+			u.Verified = true
+			return tx.Model(&model.User{}).Where("id = ?", uid).Updates(map[string]any{
+				"verified":          true,
+				"verified_at":       gorm.Expr("COALESCE(verified_at, NOW())"),
+				"verify_code":       nil,
+				"verify_expires_at": nil,
+				"password_hash":     "",
+			}).Error
+		}
+		return nil
+	})
+	if err != nil {
+		return LoginResult{}, err
+	}
+	return loginResultFor(a.keys, &u)
+}
@@ -0,0 +1,80 @@
+package service
+
+import (
+	"errors"
+	"net/url"
+	"regexp"
+	"testing"
+	"time"
+
+	"example.com/ecom-go/internal/model"
+)
+
+// mailbox gönderilen son mailin gövdesini tutar.
+type mailbox struct{ body string }
+
+func (m *mailbox) Send(to, subject, htmlBody string, attachments ...Attachment) error {
+	m.body = htmlBody
+	return nil
+}
+
+var magicTokenRe = regexp.MustCompile(`token=([A-Za-z0-9._%-]+)`)
+
+// requestMagic linki ister ve maildeki token'ı döner.
+func requestMagic(t *testing.T, a *authService, box *mailbox, email string) string {
+	t.Helper()
+	if err := a.RequestMagicLink(email); err != nil {
+		t.Fatal(err)
+	}
+	m := magicTokenRe.FindStringSubmatch(box.body)
+	if m == nil {
+		t.Fatalf("no magic link in mail: %q", box.body)
+	}
+	tok, err := url.QueryUnescape(m[1])
+	if err != nil {
+		t.Fatal(err)
+	}
+	return tok
+}
+
+func TestConsumeMagicLinkRejectsReplay(t *testing.T) {
+	box := &mailbox{}
+	a := newTestAuth(t)
+	a.email = box
+	u := model.User{Email: "magic@example.com", PasswordHash: "squatter-hash"}
+	if err := a.db.Create(&u).Error; err != nil {
+		t.Fatal(err)
+	}
+	tok := requestMagic(t, a, box, "Magic@Example.com")
+
+	res, err := a.ConsumeMagicLink(tok)
+	if err != nil || res.Token == "" {
+		t.Fatalf("first use: %+v, %v", res, err)
+	}
+	if _, err := a.ConsumeMagicLink(tok); !errors.Is(err, ErrInvalidMagicLink) {
+		t.Fatalf("replay: err = %v, want ErrInvalidMagicLink", err)
+	}
+	// e-posta sahipliği kanıtlandı: hesap doğrulanır, önceden konmuş şifre silinir
+	var got model.User
+	a.db.First(&got, u.ID)
+	if !got.Verified || got.PasswordHash != "" {
+		t.Fatalf("verified = %v, password_hash = %q", got.Verified, got.PasswordHash)
+	}
+}
+
+func TestConsumeMagicLinkRejectsExpired(t *testing.T) {
+	box := &mailbox{}
+	a := newTestAuth(t)
+	a.email = box
+	u := model.User{Email: "expired@example.com", Verified: true}
+	if err := a.db.Create(&u).Error; err != nil {
+		t.Fatal(err)
+	}
+	tok := requestMagic(t, a, box, u.Email)
+	// JWT hâlâ geçerli ama kayıt süresi dolmuş
+	a.db.Model(&model.MagicLink{}).Where("user_id = ?", u.ID).Update("expires_at", time.Now().Add(-time.Second))
+
+	if _, err := a.ConsumeMagicLink(tok); !errors.Is(err, ErrInvalidMagicLink) {
+		t.Fatalf("err = %v, want ErrInvalidMagicLink", err)
+	}
+}
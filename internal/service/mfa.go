@@ -0,0 +1,284 @@
+package service
+
+import (
+	"crypto/hmac"
+	crand "crypto/rand"
+	"crypto/sha1"
+	"crypto/sha256"
+	"encoding/base32"
+	"encoding/binary"
+	"encoding/hex"
+	"fmt"
+	"net/url"
+	"os"
+	"strings"
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+
+	"example.com/ecom-go/internal/model"
+)
+
+// RFC 6238 varsayılanları (Google Authenticator vb. uyumlu)
+const (
+	totpPeriod = 30
+	totpDigits = 6
+	totpSkew   = 1 // ±1 adım saat kayması toleransı
+
+	mfaTokenTTL       = 5 * time.Minute
+	mfaMaxAttempts    = 5  // mfa token başına; dolunca yeniden şifreyle giriş gerekir
+	mfaUserMaxFailed  = 10 // kullanıcı başına mfaLockWindow içinde (yeni login → yeni token sınırı aşmasın)
+	mfaLockWindow     = 15 * time.Minute
+	recoveryCodeCount = 10
+)
+
+var b32 = base32.StdEncoding.WithPadding(base32.NoPadding)
+
+func totpIssuer() string {
+	if v := strings.TrimSpace(os.Getenv("TOTP_ISSUER")); v != "" {
+		return v
+	}
+	return "Cakarokko"
+}
+
+// totpCode verilen zaman adımı için 6 haneli kodu üretir.
+func totpCode(secret []byte, step int64) string {
+	var msg [8]byte
+	binary.BigEndian.PutUint64(msg[:], uint64(step))
+	m := hmac.New(sha1.New, secret)
+	m.Write(msg[:])
+	sum := m.Sum(nil)
+	off := sum[len(sum)-1] & 0x0f
+	n := binary.BigEndian.Uint32(sum[off:off+4]) & 0x7fffffff
+	return fmt.Sprintf("%0*d", totpDigits, n%1000000)
+}
+
+// totpMatch kodu ±skew penceresinde arar, eşleşen adımı döner (yoksa -1).
+func totpMatch(secretB32, code string, now time.Time) int64 {
+	secret, err := b32.DecodeString(strings.ToUpper(secretB32))
+	if err != nil {
+		return -1
+	}
+	cur := now.Unix() / totpPeriod
+	for d := int64(-totpSkew); d <= totpSkew; d++ {
+		if hmac.Equal([]byte(totpCode(secret, cur+d)), []byte(code)) {
+			return cur + d
+		}
+	}
+	return -1
+}
+
+func hashRecoveryCode(code string) string {
+	code = strings.ToLower(strings.ReplaceAll(strings.TrimSpace(code), "-", ""))
+	sum := sha256.Sum256([]byte(code))
+	return hex.EncodeToString(sum[:])
+}
+
+// ---------------------------------------------------
+// Enroll / Confirm / Disable
+// ---------------------------------------------------
+
+func (a *authService) EnrollTOTP(userID uint, password string, authAt time.Time) (string, string, error) {
+	var u model.User
+	if err := a.db.Select("id, email, password_hash, totp_enabled").First(&u, userID).Error; err != nil {
+		return "", "", err
+	}
+	if u.TOTPEnabled {
+		return "", "", ErrMFAAlreadyEnabled
+	}
+	// onaylanmamış eski kaydın secret'ı da ezilir: çalınmış oturum tek başına yetmesin
+	if err := reauth(a.hasher, &u, "password", password, authAt); err != nil {
+		return "", "", err
+	}
+	raw := make([]byte, 20)
+	if _, err := crand.Read(raw); err != nil {
+		return "", "", err
+	}
+	secret := b32.EncodeToString(raw)
+	if err := a.db.Model(&model.User{}).Where("id = ?", userID).
+		Updates(map[string]any{"totp_secret": secret, "totp_last_step": 0}).Error; err != nil {
+		return "", "", err
+	}
+
+	issuer := totpIssuer()
+	q := url.Values{}
+	q.Set("secret", secret)
+	q.Set("issuer", issuer)
+	q.Set("algorithm", "SHA1")
+	q.Set("digits", fmt.Sprint(totpDigits))
+	q.Set("period", fmt.Sprint(totpPeriod))
+	uri := "otpauth://totp/" + url.PathEscape(issuer+":"+u.Email) + "?" + q.Encode()
+	return secret, uri, nil
+}
+
+// ConfirmTOTP ilk kodu doğrular, 2FA'yı açar ve düz metin kurtarma kodlarını
+// (yalnızca bu sefer) döner.
+func (a *authService) ConfirmTOTP(userID uint, code string) ([]string, error) {
+	var u model.User
+	if err := a.db.First(&u, userID).Error; err != nil {
+		return nil, err
+	}
+	if u.TOTPEnabled {
+		return nil, ErrMFAAlreadyEnabled
+	}
+	if u.TOTPSecret == nil {
+		return nil, ErrMFANotEnrolled
+	}
+	step := totpMatch(*u.TOTPSecret, strings.TrimSpace(code), time.Now())
+	if step < 0 {
+		return nil, ErrInvalidMFACode
+	}
+
+	codes := make([]string, 0, recoveryCodeCount)
+	rows := make([]model.RecoveryCode, 0, recoveryCodeCount)
+	for i := 0; i < recoveryCodeCount; i++ {
+		raw := make([]byte, 5)
+		if _, err := crand.Read(raw); err != nil {
+			return nil, err
+		}
+		c := strings.ToLower(b32.EncodeToString(raw)) // 8 karakter
+		codes = append(codes, c[:4]+"-"+c[4:])
+		rows = append(rows, model.RecoveryCode{UserID: userID, CodeHash: hashRecoveryCode(c)})
+	}
+
+	err := a.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("user_id = ?", userID).Delete(&model.RecoveryCode{}).Error; err != nil {
+			return err
+		}
+		if err := tx.Create(&rows).Error; err != nil {
+			return err
+		}
+		return tx.Model(&model.User{}).Where("id = ?", userID).
+			Updates(map[string]any{"totp_enabled": true, "totp_last_step": step}).Error
+	})
+	if err != nil {
+		return nil, err
+	}
+	return codes, nil
+}
+
+// DisableTOTP şifre (ya da yeni giriş) + geçerli bir TOTP veya kurtarma kodu ister.
+func (a *authService) DisableTOTP(userID uint, password, code string, authAt time.Time) error {
+	var u model.User
+	if err := a.db.First(&u, userID).Error; err != nil {
+		return err
+	}
+	if !u.TOTPEnabled {
+		return ErrMFANotEnrolled
+	}
+	if err := reauth(a.hasher, &u, "password", password, authAt); err != nil {
+		return err
+	}
+	if err := a.checkSecondFactor(&u, code); err != nil {
+		return err
+	}
+	return a.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("user_id = ?", userID).Delete(&model.RecoveryCode{}).Error; err != nil {
+			return err
+		}
+		return tx.Model(&model.User{}).Where("id = ?", userID).
+			Updates(map[string]any{"totp_enabled": false, "totp_secret": nil, "totp_last_step": 0}).Error
+	})
+}
+
+// ---------------------------------------------------
+// İki adımlı login
+// ---------------------------------------------------
+
+// VerifyMFA Login'in döndüğü "mfa" token'ı + kod karşılığında session JWT üretir.
+// Token başına mfaMaxAttempts deneme; başarılı girişten sonra token tükenir.
+// Kullanıcı başına mfaLockWindow içinde mfaUserMaxFailed hatadan sonra kilit (ErrMFALocked).
+func (a *authService) VerifyMFA(mfaToken, code string) (string, error) {
+	uid, claims, err := a.parseTypedClaims(mfaToken, "mfa")
+	if err != nil {
+		return "", ErrInvalidMFAToken
+	}
+	jti, _ := claims["jti"].(string)
+	if jti == "" {
+		return "", ErrInvalidMFAToken
+	}
+	exp, err := claims.GetExpirationTime()
+	if err != nil || exp == nil {
+		return "", ErrInvalidMFAToken
+	}
+	h := hashJTI(jti)
+	// deneme hakkı kodu kontrol etmeden önce düşülür: paralel istekler sınırı aşamaz
+	if err := a.db.Clauses(clause.OnConflict{DoNothing: true}).
+		Create(&model.MFAChallenge{JTIHash: h, UserID: uid, ExpiresAt: exp.Time}).Error; err != nil {
+		return "", err
+	}
+	res := a.db.Model(&model.MFAChallenge{}).
+		Where("jti_hash = ? AND user_id = ? AND attempts < ? AND used_at IS NULL", h, uid, mfaMaxAttempts).
+		Update("attempts", gorm.Expr("attempts + 1"))
+	if res.Error != nil {
+		return "", res.Error
+	}
+	if res.RowsAffected == 0 {
+		return "", ErrInvalidMFAToken
+	}
+	// kullanıcı geneli: pencere içindeki tüm token'ların hatalı denemeleri (başarılı olan hariç);
+	// az önce düşülen deneme de toplamda, o yüzden ondan öncekiler sayılır
+	var tried int64
+	if err := a.db.Model(&model.MFAChallenge{}).
+		Where("user_id = ? AND created_at > ?", uid, time.Now().Add(-mfaLockWindow)).
+		Select("COALESCE(SUM(attempts - CASE WHEN used_at IS NULL THEN 0 ELSE 1 END), 0)").
+		Scan(&tried).Error; err != nil {
+		return "", err
+	}
+	if tried-1 >= mfaUserMaxFailed {
+		return "", ErrMFALocked
+	}
+
+	var u model.User
+	if err := a.db.First(&u, uid).Error; err != nil {
+		return "", err
+	}
+	if !u.TOTPEnabled {
+		return "", ErrMFANotEnrolled
+	}
+	if err := a.checkSecondFactor(&u, code); err != nil {
+		return "", err
+	}
+	if err := a.db.Model(&model.MFAChallenge{}).Where("jti_hash = ?", h).Update("used_at", time.Now()).Error; err != nil {
+		return "", err
+	}
+	// ikinci faktör kanıtlandı: kullanıcının hata sayacı sıfırlanır (kullanılmış token'lar replay için kalır)
+	a.db.Where("user_id = ? AND used_at IS NULL", uid).Delete(&model.MFAChallenge{})
+	// kilit penceresinden de eski kayıtlar (best-effort temizlik)
+	a.db.Where("expires_at < ?", time.Now().Add(-mfaLockWindow)).Delete(&model.MFAChallenge{})
+	return a.sessionToken(u.ID)
+}
+
+// checkSecondFactor önce TOTP'yi (replay korumalı), sonra kurtarma kodunu dener.
+func (a *authService) checkSecondFactor(u *model.User, code string) error {
+	code = strings.TrimSpace(code)
+	if u.TOTPSecret != nil && len(code) == totpDigits {
+		step := totpMatch(*u.TOTPSecret, code, time.Now())
+		if step < 0 {
+			return ErrInvalidMFACode
+		}
+		// aynı kod (veya daha eski adım) ikinci kez kullanılamaz
+		res := a.db.Model(&model.User{}).
+			Where("id = ? AND totp_last_step < ?", u.ID, step).
+			Update("totp_last_step", step)
+		if res.Error != nil {
+			return res.Error
+		}
+		if res.RowsAffected == 0 {
+			return ErrInvalidMFACode
+		}
+		return nil
+	}
+
+	res := a.db.Model(&model.RecoveryCode{}).
+		Where("user_id = ? AND code_hash = ? AND used_at IS NULL", u.ID, hashRecoveryCode(code)).
+		Update("used_at", time.Now())
+	if res.Error != nil {
+		return res.Error
+	}
+	if res.RowsAffected == 0 {
+		return ErrInvalidMFACode
+	}
+	return nil
+}
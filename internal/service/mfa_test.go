@@ -0,0 +1,129 @@
+package service
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"example.com/ecom-go/internal/model"
+)
+
+// RFC 6238 ek B: SHA1 anahtarı "12345678901234567890", T=59s → 94287082 (son 6 hane).
+var rfcTOTPSecret = b32.EncodeToString([]byte("12345678901234567890"))
+
+func TestTOTPMatch(t *testing.T) {
+	at59 := time.Unix(59, 0) // adım 1
+	for _, tc := range []struct {
+		name   string
+		secret string
+		code   string
+		now    time.Time
+		want   int64
+	}{
+		{"rfc vector", rfcTOTPSecret, "287082", at59, 1},
+		{"lowercase secret", "gezdgnbvgy3tqojqgezdgnbvgy3tqojq", "287082", at59, 1},
+		{"clock behind one step", rfcTOTPSecret, "287082", time.Unix(59+totpPeriod, 0), 1},
+		{"clock ahead one step", rfcTOTPSecret, "287082", time.Unix(59-totpPeriod, 0), 1},
+		{"two steps late", rfcTOTPSecret, "287082", time.Unix(59+2*totpPeriod, 0), -1},
+		{"wrong code", rfcTOTPSecret, "287083", at59, -1},
+		{"short code", rfcTOTPSecret, "28708", at59, -1},
+		{"bad secret", "not base32!", "287082", at59, -1},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			if got := totpMatch(tc.secret, tc.code, tc.now); got != tc.want {
+				t.Fatalf("totpMatch = %d, want %d", got, tc.want)
+			}
+		})
+	}
+}
+
+// mfaUser 2FA'sı açık test kullanıcısı; şimdiki adımın kodunu üretmek için secret'ı döner.
+func mfaUser(t *testing.T, a *authService, email string) (*model.User, []byte) {
+	t.Helper()
+	raw := []byte("0123456789abcdefghij")
+	secret := b32.EncodeToString(raw)
+	u := model.User{Email: email, Verified: true, TOTPSecret: &secret, TOTPEnabled: true}
+	if err := a.db.Create(&u).Error; err != nil {
+		t.Fatal(err)
+	}
+	return &u, raw
+}
+
+func newTestAuth(t *testing.T) *authService {
+	return &authService{db: testDB(t), policy: NewPasswordPolicy(), hasher: PasswordHasher{Algo: AlgoBcrypt, BcryptCost: 4}, keys: testKeySet()}
+}
+
+func nowCode(secret []byte) string { return totpCode(secret, time.Now().Unix()/totpPeriod) }
+
+func TestCheckSecondFactorRejectsTOTPReplay(t *testing.T) {
+	a := newTestAuth(t)
+	u, secret := mfaUser(t, a, "replay@example.com")
+	step := time.Now().Unix() / totpPeriod
+
+	if err := a.checkSecondFactor(u, totpCode(secret, step)); err != nil {
+		t.Fatalf("first use: %v", err)
+	}
+	var got model.User
+	a.db.Select("totp_last_step").First(&got, u.ID)
+	if got.TOTPLastStep != step {
+		t.Fatalf("totp_last_step = %d, want %d", got.TOTPLastStep, step)
+	}
+	for name, code := range map[string]string{
+		"same code":     totpCode(secret, step),
+		"previous step": totpCode(secret, step-1), // pencere içinde ama kullanılmış adımdan eski
+	} {
+		if err := a.checkSecondFactor(u, code); !errors.Is(err, ErrInvalidMFACode) {
+			t.Fatalf("%s: err = %v, want ErrInvalidMFACode", name, err)
+		}
+	}
+}
+
+func TestVerifyMFAAttemptExhaustion(t *testing.T) {
+	a := newTestAuth(t)
+	u, secret := mfaUser(t, a, "exhaust@example.com")
+	res, err := loginResultFor(a.keys, u)
+	if err != nil || !res.MFARequired {
+		t.Fatalf("loginResultFor = %+v, %v", res, err)
+	}
+	for i := 0; i < mfaMaxAttempts; i++ {
+		if _, err := a.VerifyMFA(res.Token, "000000"); !errors.Is(err, ErrInvalidMFACode) {
+			t.Fatalf("attempt %d: err = %v, want ErrInvalidMFACode", i+1, err)
+		}
+	}
+	// hak bitti: doğru kod da kabul edilmez, yeni login gerekir
+	if _, err := a.VerifyMFA(res.Token, nowCode(secret)); !errors.Is(err, ErrInvalidMFAToken) {
+		t.Fatalf("after %d failures: err = %v, want ErrInvalidMFAToken", mfaMaxAttempts, err)
+	}
+}
+
+func TestVerifyMFATokenSingleUse(t *testing.T) {
+	a := newTestAuth(t)
+	u, secret := mfaUser(t, a, "single@example.com")
+	res, _ := loginResultFor(a.keys, u)
+	if _, err := a.VerifyMFA(res.Token, nowCode(secret)); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := a.VerifyMFA(res.Token, "000000"); !errors.Is(err, ErrInvalidMFAToken) {
+		t.Fatalf("reused token: err = %v, want ErrInvalidMFAToken", err)
+	}
+}
+
+func TestVerifyMFALocksUserAcrossTokens(t *testing.T) {
+	a := newTestAuth(t)
+	u, secret := mfaUser(t, a, "lock@example.com")
+	// her yeni login yeni jti: token başına sınır tek başına yetmez
+	failed := 0
+	for failed < mfaUserMaxFailed {
+		res, _ := loginResultFor(a.keys, u)
+		for i := 0; i < mfaMaxAttempts && failed < mfaUserMaxFailed; i++ {
+			if _, err := a.VerifyMFA(res.Token, "000000"); !errors.Is(err, ErrInvalidMFACode) {
+				t.Fatalf("failure %d: err = %v", failed+1, err)
+			}
+			failed++
+		}
+	}
+	res, _ := loginResultFor(a.keys, u)
+	if _, err := a.VerifyMFA(res.Token, nowCode(secret)); !errors.Is(err, ErrMFALocked) {
+		t.Fatalf("err = %v, want ErrMFALocked", err)
+	}
+}
@@ -0,0 +1,423 @@
+package service
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	crand "crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math/big"
+	"net/http"
+	"net/url"
+	"os"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+	"gorm.io/gorm"
+
+	"example.com/ecom-go/internal/model"
+)
+
+// OIDCProvider tek bir OpenID Connect sağlayıcısı (Google, Keycloak, ...).
+//
+// ENV (NAME büyük harf):
+//
+//	OIDC_PROVIDERS             virgüllü liste, ör. "google,corp"
+//	OIDC_<NAME>_ISSUER         ör. https://accounts.google.com
+//	OIDC_<NAME>_CLIENT_ID
+//	OIDC_<NAME>_CLIENT_SECRET  public client ise boş (sadece PKCE)
+//	OIDC_<NAME>_SCOPES         varsayılan "openid email profile"
+//	APP_BASE_URL               callback adresi için, ör. https://cakarokko.com
+type OIDCProvider struct {
+	Name         string
+	Issuer       string
+	ClientID     string
+	ClientSecret string
+	Scopes       []string
+	RedirectURL  string
+
+	mu        sync.Mutex
+	discovery *oidcDiscovery
+	keys      map[string]any
+	keysAt    time.Time
+}
+
+type oidcDiscovery struct {
+	Issuer                string `json:"issuer"`
+	AuthorizationEndpoint string `json:"authorization_endpoint"`
+	TokenEndpoint         string `json:"token_endpoint"`
+	JWKSURI               string `json:"jwks_uri"`
+}
+
+type OIDCService interface {
+	Providers() []string
+	// Start sağlayıcıya yönlendirme URL'i ve state/nonce/PKCE verifier'ı taşıyan
+	// imzalı flow token'ı (kısa ömürlü cookie'ye yazılır) döner.
+	Start(provider, redirect string) (authURL, flowToken string, err error)
+	// Callback code'u takas eder, id_token'ı doğrular, kullanıcıyı bulur/oluşturur/bağlar.
+	Callback(provider, code, state, flowToken string) (res LoginResult, redirect string, err error)
+}
+
+type oidcService struct {
+	db        *gorm.DB
+	keys      *KeySet
+	providers map[string]*OIDCProvider
+	http      *http.Client
+}
+
+const oidcFlowTTL = 10 * time.Minute
+
+func NewOIDCService(db *gorm.DB, keys *KeySet) OIDCService {
+	return &oidcService{
+		db:        db,
+		keys:      keys,
+		providers: LoadOIDCProviders(),
+		http:      &http.Client{Timeout: 10 * time.Second},
+	}
+}
+
+func LoadOIDCProviders() map[string]*OIDCProvider {
+	base := strings.TrimRight(envOr("APP_BASE_URL", "http://localhost:8080"), "/")
+	out := map[string]*OIDCProvider{}
+	for _, name := range strings.Split(os.Getenv("OIDC_PROVIDERS"), ",") {
+		name = strings.ToLower(strings.TrimSpace(name))
+		if name == "" {
+			continue
+		}
+		up := "OIDC_" + strings.ToUpper(name) + "_"
+		p := &OIDCProvider{
+			Name:         name,
+			Issuer:       strings.TrimRight(os.Getenv(up+"ISSUER"), "/"),
+			ClientID:     os.Getenv(up + "CLIENT_ID"),
+			ClientSecret: os.Getenv(up + "CLIENT_SECRET"),
+			Scopes:       strings.Fields(envOr(up+"SCOPES", "openid email profile")),
+			RedirectURL:  base + "/api/auth/oidc/" + name + "/callback",
+		}
+		if p.Issuer == "" || p.ClientID == "" {
+			continue
+		}
+		out[name] = p
+	}
+	return out
+}
+
+func (s *oidcService) Providers() []string {
+	names := make([]string, 0, len(s.providers))
+	for n := range s.providers {
+		names = append(names, n)
+	}
+	sort.Strings(names)
+	return names
+}
+
+func randURLSafe(n int) (string, error) {
+	b := make([]byte, n)
+	if _, err := crand.Read(b); err != nil {
+		return "", err
+	}
+	return base64.RawURLEncoding.EncodeToString(b), nil
+}
+
+// safeRedirect sadece site içi yolları kabul eder (open redirect engeli).
+func safeRedirect(p string) string {
+	if !strings.HasPrefix(p, "/") || strings.HasPrefix(p, "//") || strings.Contains(p, "\\") {
+		return "/"
+	}
+	return p
+}
+
+func (s *oidcService) Start(provider, redirect string) (string, string, error) {
+	p, ok := s.providers[provider]
+	if !ok {
+		return "", "", ErrUnknownProvider
+	}
+	d, err := s.discover(p)
+	if err != nil {
+		return "", "", err
+	}
+	state, err := randURLSafe(24)
+	if err != nil {
+		return "", "", err
+	}
+	nonce, err := randURLSafe(24)
+	if err != nil {
+		return "", "", err
+	}
+	verifier, err := randURLSafe(48)
+	if err != nil {
+		return "", "", err
+	}
+	flow, err := s.keys.Sign(jwt.MapClaims{
+		"typ":      "oidc_flow",
+		"prv":      p.Name,
+		"state":    state,
+		"nonce":    nonce,
+		"verifier": verifier,
+		"redirect": safeRedirect(redirect),
+	}, oidcFlowTTL)
+	if err != nil {
+		return "", "", err
+	}
+
+	challenge := sha256.Sum256([]byte(verifier))
+	q := url.Values{}
+	q.Set("response_type", "code")
+	q.Set("client_id", p.ClientID)
+	q.Set("redirect_uri", p.RedirectURL)
+	q.Set("scope", strings.Join(p.Scopes, " "))
+	q.Set("state", state)
+	q.Set("nonce", nonce)
+	q.Set("code_challenge", base64.RawURLEncoding.EncodeToString(challenge[:]))
+	q.Set("code_challenge_method", "S256")
+	sep := "?"
+	if strings.Contains(d.AuthorizationEndpoint, "?") {
+		sep = "&"
+	}
+	return d.AuthorizationEndpoint + sep + q.Encode(), flow, nil
+}
+
+func (s *oidcService) Callback(provider, code, state, flowToken string) (LoginResult, string, error) {
+	p, ok := s.providers[provider]
+	if !ok {
+		return LoginResult{}, "", ErrUnknownProvider
+	}
+	flow, err := s.keys.Parse(flowToken)
+	if err != nil || flow["typ"] != "oidc_flow" || flow["prv"] != p.Name {
+		return LoginResult{}, "", ErrOIDCState
+	}
+	want, _ := flow["state"].(string)
+	if want == "" || state != want {
+		return LoginResult{}, "", ErrOIDCState
+	}
+	nonce, _ := flow["nonce"].(string)
+	verifier, _ := flow["verifier"].(string)
+	redirect, _ := flow["redirect"].(string)
+
+	d, err := s.discover(p)
+	if err != nil {
+		return LoginResult{}, "", err
+	}
+	rawID, err := s.exchange(p, d, code, verifier)
+	if err != nil {
+		return LoginResult{}, "", err
+	}
+	claims, err := s.verifyIDToken(p, d, rawID, nonce)
+	if err != nil {
+		return LoginResult{}, "", err
+	}
+
+	sub, _ := claims["sub"].(string)
+	email, _ := claims["email"].(string)
+	if sub == "" {
+		return LoginResult{}, "", fmt.Errorf("%w: missing sub", ErrOIDCToken)
+	}
+	u, err := s.linkUser(p.Name, sub, strings.ToLower(strings.TrimSpace(email)), emailVerified(claims))
+	if err != nil {
+		return LoginResult{}, "", err
+	}
+	res, err := loginResultFor(s.keys, u)
+	return res, safeRedirect(redirect), err
+}
+
+// email_verified bazı sağlayıcılarda string ("true") gelir.
+func emailVerified(c jwt.MapClaims) bool {
+	switch v := c["email_verified"].(type) {
+	case bool:
+		return v
+	case string:
+		return v == "true"
+	}
+	return false
+}
+
+// linkUser önce (provider, sub) kimliğine bakar; yoksa doğrulanmış e-posta ile
+// mevcut hesaba bağlar ya da yeni (şifresiz, verified) kullanıcı açar.
+func (s *oidcService) linkUser(provider, subject, email string, verified bool) (*model.User, error) {
+	var u model.User
+	err := s.db.Transaction(func(tx *gorm.DB) error {
+		var ident model.UserIdentity
+		err := tx.Where("provider = ? AND subject = ?", provider, subject).First(&ident).Error
+		if err == nil {
+			return tx.First(&u, ident.UserID).Error
+		}
+		if !errors.Is(err, gorm.ErrRecordNotFound) {
+			return err
+		}
+		// e-posta doğrulanmamışsa hesap eşleştirmek güvenli değil
+		if email == "" || !verified {
+			return ErrOIDCEmailUnverified
+		}
+
+		err = tx.Where("LOWER(email) = LOWER(?)", email).First(&u).Error
+		switch {
+		case err == nil:
+			upd := map[string]any{
+				"verified":          true,
+				"verified_at":       gorm.Expr("COALESCE(verified_at, NOW())"),
+				"verify_code":       nil,
+				"verify_expires_at": nil,
+			}
+			// doğrulanmamış hesabın şifresini e-posta sahibi koymamış olabilir (pre-hijack)
+			if !u.Verified {
+				upd["password_hash"] = ""
+			}
+			if err := tx.Model(&model.User{}).Where("id = ?", u.ID).Updates(upd).Error; err != nil {
+				return err
+			}
+			u.Verified = true
+		case errors.Is(err, gorm.ErrRecordNotFound):
+			now := time.Now()
+			u = model.User{Email: email, Verified: true, VerifiedAt: &now}
+			if err := tx.Create(&u).Error; err != nil {
+				return err
+			}
+		default:
+			return err
+		}
+		return tx.Create(&model.UserIdentity{UserID: u.ID, Provider: provider, Subject: subject, Email: email}).Error
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &u, nil
+}
+
+// ---------------------------------------------------
+// Sağlayıcı ile HTTP
+// ---------------------------------------------------
+
+func (s *oidcService) getJSON(u string, dst any) error {
+	resp, err := s.http.Get(u)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("oidc: GET %s: %s", u, resp.Status)
+	}
+	return json.NewDecoder(resp.Body).Decode(dst)
+}
+
+func (s *oidcService) discover(p *OIDCProvider) (*oidcDiscovery, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.discovery != nil {
+		return p.discovery, nil
+	}
+	var d oidcDiscovery
+	if err := s.getJSON(p.Issuer+"/.well-known/openid-configuration", &d); err != nil {
+		return nil, err
+	}
+	if strings.TrimRight(d.Issuer, "/") != p.Issuer {
+		return nil, fmt.Errorf("oidc: issuer mismatch %q != %q", d.Issuer, p.Issuer)
+	}
+	p.discovery = &d
+	return &d, nil
+}
+
+func (s *oidcService) exchange(p *OIDCProvider, d *oidcDiscovery, code, verifier string) (string, error) {
+	form := url.Values{}
+	form.Set("grant_type", "authorization_code")
+	form.Set("code", code)
+	form.Set("redirect_uri", p.RedirectURL)
+	form.Set("client_id", p.ClientID)
+	form.Set("code_verifier", verifier)
+	if p.ClientSecret != "" {
+		form.Set("client_secret", p.ClientSecret)
+	}
+	resp, err := s.http.PostForm(d.TokenEndpoint, form)
+	if err != nil {
+		return "", err
+	}
+	defer resp.Body.Close()
+	var out struct {
+		IDToken string `json:"id_token"`
+		Error   string `json:"error"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&out); err != nil {
+		return "", fmt.Errorf("oidc: token response: %w", err)
+	}
+	if resp.StatusCode != http.StatusOK || out.IDToken == "" {
+		return "", fmt.Errorf("%w: token endpoint %s %s", ErrOIDCToken, resp.Status, out.Error)
+	}
+	return out.IDToken, nil
+}
+
+func (s *oidcService) verifyIDToken(p *OIDCProvider, d *oidcDiscovery, raw, nonce string) (jwt.MapClaims, error) {
+	claims := jwt.MapClaims{}
+	_, err := jwt.ParseWithClaims(raw, claims, func(t *jwt.Token) (interface{}, error) {
+		kid, _ := t.Header["kid"].(string)
+		return s.providerKey(p, d, kid)
+	},
+		jwt.WithValidMethods([]string{"RS256", "ES256"}),
+		jwt.WithIssuer(d.Issuer),
+		jwt.WithAudience(p.ClientID),
+		jwt.WithExpirationRequired(),
+		jwt.WithLeeway(time.Minute),
+	)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrOIDCToken, err)
+	}
+	if got, _ := claims["nonce"].(string); got == "" || got != nonce {
+		return nil, fmt.Errorf("%w: nonce mismatch", ErrOIDCToken)
+	}
+	return claims, nil
+}
+
+// providerKey JWKS'i önbellekten verir; bilinmeyen kid'de (rotasyon) en fazla dakikada bir yeniler.
+func (s *oidcService) providerKey(p *OIDCProvider, d *oidcDiscovery, kid string) (any, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if k, ok := p.keys[kid]; ok {
+		return k, nil
+	}
+	if time.Since(p.keysAt) < time.Minute && p.keys != nil {
+		return nil, fmt.Errorf("unknown kid %q", kid)
+	}
+	var set struct {
+		Keys []struct {
+			Kty, Kid, Use, N, E, Crv, X, Y string
+		} `json:"keys"`
+	}
+	if err := s.getJSON(d.JWKSURI, &set); err != nil {
+		return nil, err
+	}
+	b64 := base64.RawURLEncoding
+	keys := map[string]any{}
+	for _, k := range set.Keys {
+		if k.Use != "" && k.Use != "sig" {
+			continue
+		}
+		switch k.Kty {
+		case "RSA":
+			n, err1 := b64.DecodeString(k.N)
+			e, err2 := b64.DecodeString(k.E)
+			if err1 != nil || err2 != nil {
+				continue
+			}
+			keys[k.Kid] = &rsa.PublicKey{N: new(big.Int).SetBytes(n), E: int(new(big.Int).SetBytes(e).Int64())}
+		case "EC":
+			if k.Crv != "P-256" {
+				continue
+			}
+			x, err1 := b64.DecodeString(k.X)
+			y, err2 := b64.DecodeString(k.Y)
+			if err1 != nil || err2 != nil {
+				continue
+			}
+			keys[k.Kid] = &ecdsa.PublicKey{Curve: elliptic.P256(), X: new(big.Int).SetBytes(x), Y: new(big.Int).SetBytes(y)}
+		}
+	}
+	p.keys, p.keysAt = keys, time.Now()
+	if k, ok := keys[kid]; ok {
+		return k, nil
+	}
+	return nil, fmt.Errorf("unknown kid %q", kid)
+}
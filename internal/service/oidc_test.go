@@ -0,0 +1,309 @@
+package service
+
+import (
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+	"gorm.io/driver/postgres"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+
+	"example.com/ecom-go/internal/model"
+)
+
+// stubIssuer discovery, JWKS ve token endpoint'i sunan sahte OIDC sağlayıcısı.
+// Test, code'u (PKCE challenge'ı ve id_token claim'leriyle) önceden kaydeder.
+type stubIssuer struct {
+	srv *httptest.Server
+	key *rsa.PrivateKey
+
+	mu    sync.Mutex
+	codes map[string]stubCode
+}
+
+type stubCode struct {
+	challenge string
+	claims    jwt.MapClaims
+}
+
+const stubClientID = "shop-client"
+
+func newStubIssuer(t *testing.T) *stubIssuer {
+	t.Helper()
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+	s := &stubIssuer{key: key, codes: map[string]stubCode{}}
+	mux := http.NewServeMux()
+	mux.HandleFunc("/.well-known/openid-configuration", func(w http.ResponseWriter, r *http.Request) {
+		json.NewEncoder(w).Encode(map[string]string{
+			"issuer":                 s.srv.URL,
+			"authorization_endpoint": s.srv.URL + "/authorize",
+			"token_endpoint":         s.srv.URL + "/token",
+			"jwks_uri":               s.srv.URL + "/jwks",
+		})
+	})
+	mux.HandleFunc("/jwks", func(w http.ResponseWriter, r *http.Request) {
+		b64 := base64.RawURLEncoding
+		json.NewEncoder(w).Encode(map[string]any{"keys": []map[string]string{{
+			"kty": "RSA", "kid": "stub-1", "use": "sig",
+			"n": b64.EncodeToString(key.N.Bytes()),
+			"e": b64.EncodeToString(big.NewInt(int64(key.E)).Bytes()),
+		}}})
+	})
+	mux.HandleFunc("/token", func(w http.ResponseWriter, r *http.Request) {
+		s.mu.Lock()
+		c, ok := s.codes[r.PostFormValue("code")]
+		delete(s.codes, r.PostFormValue("code"))
+		s.mu.Unlock()
+		sum := sha256.Sum256([]byte(r.PostFormValue("code_verifier")))
+		if !ok || base64.RawURLEncoding.EncodeToString(sum[:]) != c.challenge {
+			w.WriteHeader(http.StatusBadRequest)
+			json.NewEncoder(w).Encode(map[string]string{"error": "invalid_grant"})
+			return
+		}
+		json.NewEncoder(w).Encode(map[string]string{"id_token": s.idToken(t, c.claims)})
+	})
+	s.srv = httptest.NewServer(mux)
+	t.Cleanup(s.srv.Close)
+	return s
+}
+
+func (s *stubIssuer) idToken(t *testing.T, extra jwt.MapClaims) string {
+	claims := jwt.MapClaims{
+		"iss": s.srv.URL,
+		"aud": stubClientID,
+		"iat": time.Now().Unix(),
+		"exp": time.Now().Add(time.Hour).Unix(),
+	}
+	for k, v := range extra {
+		claims[k] = v
+	}
+	tok := jwt.NewWithClaims(jwt.SigningMethodRS256, claims)
+	tok.Header["kid"] = "stub-1"
+	raw, err := tok.SignedString(s.key)
+	if err != nil {
+		t.Error(err)
+	}
+	return raw
+}
+
+func (s *stubIssuer) issue(code, challenge string, claims jwt.MapClaims) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.codes[code] = stubCode{challenge: challenge, claims: claims}
+}
+
+func testKeySet() *KeySet {
+	secret := []byte("test-secret")
+	return &KeySet{
+		Issuer: "test", Audience: "test",
+		signKID: hsKID, signMethod: jwt.SigningMethodHS256, signKey: secret,
+		verify: map[string]verifyKey{hsKID: {method: jwt.SigningMethodHS256, key: secret}},
+	}
+}
+
+func newTestOIDC(t *testing.T, db *gorm.DB) (*oidcService, *stubIssuer) {
+	st := newStubIssuer(t)
+	svc := &oidcService{
+		db:   db,
+		keys: testKeySet(),
+		providers: map[string]*OIDCProvider{"stub": {
+			Name: "stub", Issuer: st.srv.URL, ClientID: stubClientID,
+			Scopes: []string{"openid", "email"}, RedirectURL: "http://shop.test/api/auth/oidc/stub/callback",
+		}},
+		http: st.srv.Client(),
+	}
+	return svc, st
+}
+
+// oidcFlow Start'ın ürettiği state/nonce/challenge ve flow token'ı.
+type oidcFlow struct {
+	state, nonce, challenge, token string
+}
+
+func startFlow(t *testing.T, svc *oidcService) oidcFlow {
+	t.Helper()
+	authURL, flow, err := svc.Start("stub", "/orders")
+	if err != nil {
+		t.Fatal(err)
+	}
+	u, err := url.Parse(authURL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	q := u.Query()
+	if q.Get("code_challenge_method") != "S256" {
+		t.Fatalf("code_challenge_method = %q", q.Get("code_challenge_method"))
+	}
+	return oidcFlow{state: q.Get("state"), nonce: q.Get("nonce"), challenge: q.Get("code_challenge"), token: flow}
+}
+
+// testDB TEST_DB_DSN'deki Postgres'e bağlanır; her test tek tx içinde çalışır ve geri alınır.
+func testDB(t *testing.T) *gorm.DB {
+	t.Helper()
+	dsn := os.Getenv("TEST_DB_DSN")
+	if dsn == "" {
+		t.Skip("TEST_DB_DSN not set")
+	}
+	db, err := gorm.Open(postgres.Open(dsn), &gorm.Config{Logger: logger.Default.LogMode(logger.Silent)})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := db.AutoMigrate(&model.User{}, &model.UserIdentity{}, &model.RecoveryCode{},
+		&model.MFAChallenge{}, &model.MagicLink{}, &model.Category{}, &model.Product{},
+		&model.ProductOption{}, &model.ProductOptionValue{}, &model.ProductVariant{}, &model.ExchangeRate{}); err != nil {
+		t.Fatal(err)
+	}
+	tx := db.Begin()
+	t.Cleanup(func() { tx.Rollback() })
+	return tx
+}
+
+func TestOIDCCallbackRejectsStateMismatch(t *testing.T) {
+	svc, st := newTestOIDC(t, nil)
+	f := startFlow(t, svc)
+	st.issue("code-1", f.challenge, jwt.MapClaims{"sub": "u1", "nonce": f.nonce, "email": "a@example.com", "email_verified": true})
+
+	if _, _, err := svc.Callback("stub", "code-1", "other-state", f.token); !errors.Is(err, ErrOIDCState) {
+		t.Fatalf("err = %v, want ErrOIDCState", err)
+	}
+	// başka bir akışın flow token'ı da kabul edilmez
+	other := startFlow(t, svc)
+	if _, _, err := svc.Callback("stub", "code-1", f.state, other.token); !errors.Is(err, ErrOIDCState) {
+		t.Fatalf("err = %v, want ErrOIDCState", err)
+	}
+}
+
+func TestOIDCCallbackRejectsNonceMismatch(t *testing.T) {
+	svc, st := newTestOIDC(t, nil)
+	f := startFlow(t, svc)
+	st.issue("code-1", f.challenge, jwt.MapClaims{"sub": "u1", "nonce": "replayed-nonce", "email": "a@example.com", "email_verified": true})
+
+	_, _, err := svc.Callback("stub", "code-1", f.state, f.token)
+	if !errors.Is(err, ErrOIDCToken) || !strings.Contains(err.Error(), "nonce") {
+		t.Fatalf("err = %v, want nonce mismatch", err)
+	}
+}
+
+func TestOIDCCallbackRejectsWrongPKCEVerifier(t *testing.T) {
+	svc, st := newTestOIDC(t, nil)
+	f := startFlow(t, svc)
+	st.issue("code-1", f.challenge, jwt.MapClaims{"sub": "u1", "nonce": f.nonce, "email": "a@example.com", "email_verified": true})
+
+	// aynı state/nonce, farklı verifier: sağlayıcı code'u vermemeli
+	forged, err := svc.keys.Sign(jwt.MapClaims{
+		"typ": "oidc_flow", "prv": "stub", "state": f.state, "nonce": f.nonce,
+		"verifier": "not-the-original-verifier", "redirect": "/",
+	}, oidcFlowTTL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, _, err := svc.Callback("stub", "code-1", f.state, forged); !errors.Is(err, ErrOIDCToken) {
+		t.Fatalf("err = %v, want ErrOIDCToken", err)
+	}
+}
+
+func TestOIDCCallbackRejectsUnverifiedEmail(t *testing.T) {
+	db := testDB(t)
+	svc, st := newTestOIDC(t, db)
+	f := startFlow(t, svc)
+	st.issue("code-1", f.challenge, jwt.MapClaims{"sub": "u-unverified", "nonce": f.nonce, "email": "unverified@example.com", "email_verified": false})
+
+	if _, _, err := svc.Callback("stub", "code-1", f.state, f.token); !errors.Is(err, ErrOIDCEmailUnverified) {
+		t.Fatalf("err = %v, want ErrOIDCEmailUnverified", err)
+	}
+	var n int64
+	db.Model(&model.User{}).Where("email = ?", "unverified@example.com").Count(&n)
+	if n != 0 {
+		t.Fatalf("user created for unverified email")
+	}
+}
+
+func TestOIDCCallbackCreatesUser(t *testing.T) {
+	db := testDB(t)
+	svc, st := newTestOIDC(t, db)
+	f := startFlow(t, svc)
+	st.issue("code-1", f.challenge, jwt.MapClaims{"sub": "u-new", "nonce": f.nonce, "email": "New@Example.com", "email_verified": "true"})
+
+	res, redirect, err := svc.Callback("stub", "code-1", f.state, f.token)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if res.Token == "" || res.MFARequired || redirect != "/orders" {
+		t.Fatalf("res = %+v, redirect = %q", res, redirect)
+	}
+	var u model.User
+	if err := db.Where("email = ?", "new@example.com").First(&u).Error; err != nil {
+		t.Fatal(err)
+	}
+	if !u.Verified || u.PasswordHash != "" {
+		t.Fatalf("user = %+v, want verified without password", u)
+	}
+	var ident model.UserIdentity
+	if err := db.Where("provider = ? AND subject = ?", "stub", "u-new").First(&ident).Error; err != nil {
+		t.Fatal(err)
+	}
+	if ident.UserID != u.ID {
+		t.Fatalf("identity user = %d, want %d", ident.UserID, u.ID)
+	}
+}
+
+func TestOIDCCallbackLinksExistingAccount(t *testing.T) {
+	for _, tc := range []struct {
+		name         string
+		verified     bool
+		wantPassword string
+	}{
+		{"verified keeps password", true, "$2a$12$existing"},
+		// adresi önceden alıp şifre koyan biri hesapta kalmamalı
+		{"unverified clears password", false, ""},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			db := testDB(t)
+			svc, st := newTestOIDC(t, db)
+			existing := model.User{Email: "owner@example.com", PasswordHash: "$2a$12$existing", Verified: tc.verified}
+			if err := db.Create(&existing).Error; err != nil {
+				t.Fatal(err)
+			}
+			f := startFlow(t, svc)
+			st.issue("code-1", f.challenge, jwt.MapClaims{"sub": "u-owner", "nonce": f.nonce, "email": "owner@example.com", "email_verified": true})
+
+			if _, _, err := svc.Callback("stub", "code-1", f.state, f.token); err != nil {
+				t.Fatal(err)
+			}
+			var u model.User
+			if err := db.First(&u, existing.ID).Error; err != nil {
+				t.Fatal(err)
+			}
+			if !u.Verified || u.PasswordHash != tc.wantPassword {
+				t.Fatalf("verified = %v, password_hash = %q, want %q", u.Verified, u.PasswordHash, tc.wantPassword)
+			}
+			var n int64
+			db.Model(&model.UserIdentity{}).Where("user_id = ? AND provider = ? AND subject = ?", existing.ID, "stub", "u-owner").Count(&n)
+			if n != 1 {
+				t.Fatalf("identity rows = %d, want 1", n)
+			}
+			var users int64
+			db.Model(&model.User{}).Where("LOWER(email) = ?", "owner@example.com").Count(&users)
+			if users != 1 {
+				t.Fatalf("users = %d, want existing account reused", users)
+			}
+		})
+	}
+}
@@ -0,0 +1,305 @@
+package service
+
+import (
+	"errors"
+	"fmt"
+	"html"
+	"math/big"
+	"strings"
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+
+	"example.com/ecom-go/internal/model"
+	"example.com/ecom-go/internal/money"
+	"example.com/ecom-go/internal/validate"
+)
+
+// RefundLine bir sipariş satırından iade edilecek adet.
+type RefundLine struct {
+	OrderItemID uint
+	Qty         int
+}
+
+type RefundInput struct {
+	Full     bool // kalan tüm satırlar + kargo (admin iadesinde açık iade talebindekiler hariç); Items/Shipping yok sayılır
+	Items    []RefundLine
+	Shipping bool // kargo bedeli de iade edilsin
+	Reason   string
+	Restock  bool // iade edilen adetler stoğa geri eklensin
+}
+
+type OrderService interface {
+	List(userID uint) ([]model.Order, error)
+	// Get sadece sahibine; userID 0 → admin (sahip kontrolü yok).
+	Get(userID, id uint) (model.Order, error)
+	// Cancel kargolanmamış siparişi iptal eder: tutarın tamamı iade, stok geri.
+	Cancel(userID, id uint) (model.Order, error)
+	Ship(id uint) (model.Order, error)
+	Refund(id uint, in RefundInput) (model.Order, error)
+}
+
+type orderService struct {
+	db    *gorm.DB
+	email EmailService
+}
+
+func NewOrderService(db *gorm.DB, email EmailService) OrderService {
+	return &orderService{db: db, email: email}
+}
+
+func (s *orderService) List(userID uint) ([]model.Order, error) {
+	var orders []model.Order
+	return orders, s.db.Preload("Items").Where("user_id = ?", userID).Order("id desc").Find(&orders).Error
+}
+
+func (s *orderService) Get(userID, id uint) (model.Order, error) {
+	return loadOrder(s.db, userID, id, false)
+}
+
+// loadOrder lock ise satır tx sonuna kadar kilitlenir (eşzamanlı iptal/iade).
+func loadOrder(tx *gorm.DB, userID, id uint, lock bool) (model.Order, error) {
+	var o model.Order
+	q := tx.Preload("Items", func(db *gorm.DB) *gorm.DB { return db.Order("id") }).
+		Preload("Discounts").Preload("Refunds", func(db *gorm.DB) *gorm.DB { return db.Order("id") })
+	if lock {
+		q = q.Clauses(clause.Locking{Strength: "UPDATE"})
+	}
+	if userID != 0 {
+		q = q.Where("user_id = ?", userID)
+	}
+	if err := q.First(&o, id).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return o, ErrOrderNotFound
+		}
+		return o, err
+	}
+	return o, nil
+}
+
+func (s *orderService) Cancel(userID, id uint) (model.Order, error) {
+	var o model.Order
+	var amount money.Money
+	err := s.db.Transaction(func(tx *gorm.DB) error {
+		var err error
+		if o, err = loadOrder(tx, userID, id, true); err != nil {
+			return err
+		}
+		if o.Status != model.OrderPlaced {
+			return ErrOrderNotCancellable
+		}
+		if amount, err = refundOrder(tx, &o, RefundInput{Full: true, Restock: true, Reason: "cancelled by customer"}, nil); err != nil {
+			return err
+		}
+		// kupon/kampanya kullanımı geri verilir
+		var ds []model.OrderDiscount
+		if err := tx.Where("order_id = ?", o.ID).Find(&ds).Error; err != nil {
+			return err
+		}
+		for _, d := range ds {
+			if err := tx.Model(&model.Promotion{}).Where("id = ? AND used_count > 0", d.PromotionID).
+				Update("used_count", gorm.Expr("used_count - 1")).Error; err != nil {
+				return err
+			}
+		}
+		if err := tx.Where("order_id = ?", o.ID).Delete(&model.PromotionRedemption{}).Error; err != nil {
+			return err
+		}
+		now := time.Now()
+		o.Status, o.CancelledAt = model.OrderCancelled, &now
+		return tx.Model(&o).Updates(map[string]any{"status": o.Status, "cancelled_at": now}).Error
+	})
+	if err != nil {
+		return model.Order{}, err
+	}
+	s.notify(o, fmt.Sprintf("Order #%d cancelled", o.ID),
+		fmt.Sprintf("Your order #%d has been cancelled. %s will be refunded.", o.ID, amount.Format(money.DefaultLocale)))
+	return o, nil
+}
+
+func (s *orderService) Ship(id uint) (model.Order, error) {
+	var o model.Order
+	err := s.db.Transaction(func(tx *gorm.DB) error {
+		var err error
+		if o, err = loadOrder(tx, 0, id, true); err != nil {
+			return err
+		}
+		if o.Status != model.OrderPlaced {
+			return fmt.Errorf("%w: order is %s", ErrOrderNotShippable, o.Status)
+		}
+		now := time.Now()
+		o.Status, o.ShippedAt = model.OrderShipped, &now
+		return tx.Model(&o).Updates(map[string]any{"status": o.Status, "shipped_at": now}).Error
+	})
+	if err != nil {
+		return model.Order{}, err
+	}
+	s.notify(o, fmt.Sprintf("Order #%d shipped", o.ID), fmt.Sprintf("Your order #%d is on its way.", o.ID))
+	return o, nil
+}
+
+func (s *orderService) Refund(id uint, in RefundInput) (model.Order, error) {
+	var o model.Order
+	var amount money.Money
+	err := s.db.Transaction(func(tx *gorm.DB) error {
+		var err error
+		if o, err = loadOrder(tx, 0, id, true); err != nil {
+			return err
+		}
+		if o.Status != model.OrderPlaced && o.Status != model.OrderShipped {
+			return ErrOrderNotRefundable
+		}
+		// açık iade talebindeki adetler o talep üzerinden iade edilir; burada iki kez ödenmesin
+		held, err := returnHeld(tx, o.ID)
+		if err != nil {
+			return err
+		}
+		amount, err = refundOrder(tx, &o, in, held)
+		return err
+	})
+	if err != nil {
+		return model.Order{}, err
+	}
+	s.notify(o, fmt.Sprintf("Refund for order #%d", o.ID),
+		fmt.Sprintf("We refunded %s for your order #%d.", amount.Format(money.DefaultLocale), o.ID))
+	return o, nil
+}
+
+// refundOrder iade satırlarını yazar, sipariş/satır sayaçlarını günceller.
+// Tutar adet oranıyla kümülatif hesaplanır: parça parça iadelerin toplamı
+// satırın tamamına eşit olur, kuruş kaybolmaz. Her şey iade edildiyse
+// durum refunded olur (iptalde çağıran cancelled'a çevirir). held'deki adetler
+// (açık iade talepleri) iade edilebilir miktardan düşülür.
+func refundOrder(tx *gorm.DB, o *model.Order, in RefundInput, held map[uint]int) (money.Money, error) {
+	cur := money.Currency(o.Currency)
+	total := money.New(0, cur)
+	shippingDone := false
+	for _, r := range o.Refunds {
+		if r.OrderItemID == nil {
+			shippingDone = true
+		}
+	}
+
+	want := map[uint]int{}
+	if in.Full {
+		for _, it := range o.Items {
+			if left := it.Qty - it.RefundedQty - held[it.ID]; left > 0 {
+				want[it.ID] = left
+			}
+		}
+		in.Shipping = !shippingDone && o.ShippingCents+o.ShippingTaxCents > 0
+	} else {
+		for _, l := range in.Items {
+			want[l.OrderItemID] += l.Qty
+		}
+	}
+
+	fe := validate.Errors{}
+	var refunds []model.Refund
+	restock := map[uint]int{} // variantID → adet
+	for i := range o.Items {
+		it := &o.Items[i]
+		k, ok := want[it.ID]
+		if !ok {
+			continue
+		}
+		delete(want, it.ID)
+		if left := it.Qty - it.RefundedQty - held[it.ID]; k <= 0 || k > left {
+			fe.Add("items", fmt.Sprintf("order item %d: at most %d can be refunded", it.ID, max(left, 0)))
+			continue
+		}
+		gross := it.NetCents + it.TaxCents
+		amt := share(gross, it.RefundedQty+k, it.Qty) - share(gross, it.RefundedQty, it.Qty)
+		tax := share(it.TaxCents, it.RefundedQty+k, it.Qty) - share(it.TaxCents, it.RefundedQty, it.Qty)
+		id := it.ID
+		refunds = append(refunds, model.Refund{OrderID: o.ID, OrderItemID: &id, Qty: k, AmountCents: amt, TaxCents: tax, Restocked: in.Restock && it.VariantID != nil})
+		it.RefundedQty += k
+		it.RefundedCents += amt
+		if in.Restock && it.VariantID != nil {
+			restock[*it.VariantID] += k
+		}
+	}
+	for id := range want {
+		fe.Add("items", fmt.Sprintf("order item %d not found", id))
+	}
+	if in.Shipping {
+		if shippingDone {
+			fe.Add("shipping", "already refunded")
+		} else {
+			refunds = append(refunds, model.Refund{OrderID: o.ID, AmountCents: o.ShippingCents + o.ShippingTaxCents, TaxCents: o.ShippingTaxCents})
+			shippingDone = true
+		}
+	}
+	if len(fe) == 0 && len(refunds) == 0 {
+		fe.Add("items", "nothing to refund")
+	}
+	if err := fe.Err(); err != nil {
+		return total, err
+	}
+
+	reason := strings.TrimSpace(in.Reason)
+	for i := range refunds {
+		refunds[i].Currency, refunds[i].Reason = string(cur), reason
+		total.Amount += refunds[i].AmountCents
+	}
+	if err := tx.Create(&refunds).Error; err != nil {
+		return total, err
+	}
+	for _, it := range o.Items {
+		if err := tx.Model(&model.OrderItem{}).Where("id = ?", it.ID).
+			Updates(map[string]any{"refunded_qty": it.RefundedQty, "refunded_cents": it.RefundedCents}).Error; err != nil {
+			return total, err
+		}
+	}
+	if err := restockVariants(tx, restock); err != nil {
+		return total, err
+	}
+
+	o.RefundedCents += total.Amount
+	o.Refunds = append(o.Refunds, refunds...)
+	done := shippingDone || o.ShippingCents+o.ShippingTaxCents == 0
+	for _, it := range o.Items {
+		done = done && it.RefundedQty == it.Qty
+	}
+	if done {
+		o.Status = model.OrderRefunded
+	}
+	return total, tx.Model(o).Updates(map[string]any{"refunded_cents": o.RefundedCents, "status": o.Status}).Error
+}
+
+// share total'ın k/n payı (half-up).
+func share(total int64, k, n int) int64 {
+	if n == 0 {
+		return 0
+	}
+	return money.RoundHalfUp(big.NewRat(total*int64(k), int64(n)))
+}
+
+// restockVariants iade/iade-teslim adetlerini stoğa ekler (variantID → adet).
+// Varyant silinmiş olabilir: o zaman geri eklenecek yer yok, UPDATE boşa düşer.
+func restockVariants(tx *gorm.DB, qty map[uint]int) error {
+	for vid, n := range qty {
+		if err := tx.Model(&model.ProductVariant{}).Where("id = ?", vid).
+			Update("stock", gorm.Expr("stock + ?", n)).Error; err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (s *orderService) notify(o model.Order, subject, text string) {
+	notifyUser(s.db, s.email, o.UserID, subject, text)
+}
+
+// notifyUser best-effort mail; silinmiş hesapta (user_id=0) atlanır.
+func notifyUser(db *gorm.DB, email EmailService, userID uint, subject, text string) {
+	if userID == 0 {
+		return
+	}
+	var u model.User
+	if err := db.First(&u, userID).Error; err != nil {
+		return
+	}
+	_ = email.Send(u.Email, subject, "<p>"+html.EscapeString(text)+"</p>")
+}
@@ -0,0 +1,98 @@
+package service
+
+import (
+	"bufio"
+	_ "embed"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+
+	"example.com/ecom-go/internal/validate"
+)
+
+//go:embed data/banned_passwords.txt
+var bannedPasswords string
+
+// bcrypt 72 byte'tan sonrasını sessizce yok sayar; daha uzununu kabul etmiyoruz.
+const bcryptMaxBytes = 72
+
+// PasswordPolicy kayıt, şifre değiştirme ve sıfırlamada aynı kuralları uygular.
+type PasswordPolicy struct {
+	MinLen   int // karakter (rune) sayısı
+	MaxBytes int
+	banned   map[string]struct{}
+}
+
+// NewPasswordPolicy ENV'den okur:
+//
+//	PASSWORD_MIN_LEN      (varsayılan 8)
+//	PASSWORD_MAX_BYTES    (varsayılan ve üst sınır 72)
+//	PASSWORD_BANNED_FILE  (opsiyonel, gömülü listeye eklenir)
+func NewPasswordPolicy() PasswordPolicy {
+	p := PasswordPolicy{MinLen: 8, MaxBytes: bcryptMaxBytes, banned: map[string]struct{}{}}
+	if n, err := strconv.Atoi(os.Getenv("PASSWORD_MIN_LEN")); err == nil && n > 0 {
+		p.MinLen = n
+	}
+	if n, err := strconv.Atoi(os.Getenv("PASSWORD_MAX_BYTES")); err == nil && n > 0 && n <= bcryptMaxBytes {
+		p.MaxBytes = n
+	}
+	p.loadBanned(strings.NewReader(bannedPasswords))
+	if path := strings.TrimSpace(os.Getenv("PASSWORD_BANNED_FILE")); path != "" {
+		f, err := os.Open(path)
+		if err != nil {
+			log.Printf("password banned list: %v", err)
+		} else {
+			p.loadBanned(f)
+			_ = f.Close()
+		}
+	}
+	return p
+}
+
+func (p *PasswordPolicy) loadBanned(r io.Reader) {
+	sc := bufio.NewScanner(r)
+	for sc.Scan() {
+		line := strings.TrimSpace(sc.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		p.banned[strings.ToLower(line)] = struct{}{}
+	}
+}
+
+// Check ihlal varsa field alanına yazılmış validate.Errors döner.
+// email verilirse e-postayı içeren ya da adresin yerel kısmıyla aynı şifre de reddedilir.
+func (p PasswordPolicy) Check(field, password, email string) error {
+	fe := validate.Errors{}
+	switch {
+	case password == "":
+		fe.Add(field, "is required")
+	case utf8.RuneCountInString(password) < p.MinLen:
+		fe.Add(field, fmt.Sprintf("must be at least %d characters", p.MinLen))
+	case len(password) > p.MaxBytes:
+		fe.Add(field, fmt.Sprintf("must be at most %d bytes", p.MaxBytes))
+	case strings.TrimSpace(password) == "":
+		fe.Add(field, "must not be blank")
+	case p.isBanned(password, email):
+		fe.Add(field, "is too common, choose another")
+	}
+	return fe.Err()
+}
+
+func (p PasswordPolicy) isBanned(password, email string) bool {
+	lp := strings.ToLower(password)
+	if _, ok := p.banned[lp]; ok {
+		return true
+	}
+	if email != "" {
+		local, _, _ := strings.Cut(strings.ToLower(email), "@")
+		if strings.Contains(lp, strings.ToLower(email)) || lp == local {
+			return true
+		}
+	}
+	return false
+}
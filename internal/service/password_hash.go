@@ -0,0 +1,175 @@
+package service
+
+import (
+	crand "crypto/rand"
+	"crypto/subtle"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+
+	"golang.org/x/crypto/argon2"
+	"golang.org/x/crypto/bcrypt"
+	"gorm.io/gorm"
+
+	"example.com/ecom-go/internal/model"
+)
+
+const (
+	AlgoBcrypt   = "bcrypt"
+	AlgoArgon2id = "argon2id"
+)
+
+// PasswordHasher yeni hash'leri seçili algoritmayla üretir, eskileri de doğrulayabilir.
+// Verify "rehash" döndürürse çağıran yeni hash'i kaydetmeli (login sırasında upgrade).
+type PasswordHasher struct {
+	Algo       string
+	BcryptCost int
+	// argon2id parametreleri
+	Memory  uint32 // KiB
+	Time    uint32
+	Threads uint8
+}
+
+// NewPasswordHasher ENV'den okur:
+//
+//	PASSWORD_HASH_ALGO  bcrypt | argon2id (varsayılan bcrypt)
+//	BCRYPT_COST         (varsayılan 12)
+//	ARGON2_MEMORY_KIB / ARGON2_TIME / ARGON2_THREADS
+func NewPasswordHasher() PasswordHasher {
+	h := PasswordHasher{Algo: AlgoBcrypt, BcryptCost: 12, Memory: 64 * 1024, Time: 3, Threads: 2}
+	if v := strings.ToLower(strings.TrimSpace(os.Getenv("PASSWORD_HASH_ALGO"))); v == AlgoArgon2id {
+		h.Algo = AlgoArgon2id
+	}
+	if n, err := strconv.Atoi(os.Getenv("BCRYPT_COST")); err == nil && n >= bcrypt.MinCost && n <= bcrypt.MaxCost {
+		h.BcryptCost = n
+	}
+	if n, err := strconv.ParseUint(os.Getenv("ARGON2_MEMORY_KIB"), 10, 32); err == nil && n >= 8*1024 {
+		h.Memory = uint32(n)
+	}
+	if n, err := strconv.ParseUint(os.Getenv("ARGON2_TIME"), 10, 32); err == nil && n > 0 {
+		h.Time = uint32(n)
+	}
+	if n, err := strconv.ParseUint(os.Getenv("ARGON2_THREADS"), 10, 8); err == nil && n > 0 {
+		h.Threads = uint8(n)
+	}
+	return h
+}
+
+func (h PasswordHasher) Hash(password string) (string, error) {
+	if h.Algo == AlgoArgon2id {
+		salt := make([]byte, 16)
+		if _, err := crand.Read(salt); err != nil {
+			return "", err
+		}
+		key := argon2.IDKey([]byte(password), salt, h.Time, h.Memory, h.Threads, 32)
+		b64 := base64.RawStdEncoding
+		return fmt.Sprintf("$argon2id$v=%d$m=%d,t=%d,p=%d$%s$%s",
+			argon2.Version, h.Memory, h.Time, h.Threads, b64.EncodeToString(salt), b64.EncodeToString(key)), nil
+	}
+	b, err := bcrypt.GenerateFromPassword([]byte(password), h.BcryptCost)
+	return string(b), err
+}
+
+// Verify şifre doğruysa ok=true; hash eski algoritma/zayıf parametreliyse rehash=true.
+func (h PasswordHasher) Verify(hash, password string) (ok, rehash bool) {
+	switch {
+	case strings.HasPrefix(hash, "$argon2id$"):
+		p, salt, key, err := parseArgon2(hash)
+		if err != nil {
+			return false, false
+		}
+		got := argon2.IDKey([]byte(password), salt, p.Time, p.Memory, p.Threads, uint32(len(key)))
+		if subtle.ConstantTimeCompare(got, key) != 1 {
+			return false, false
+		}
+		return true, h.Algo != AlgoArgon2id || p.Memory < h.Memory || p.Time < h.Time || p.Threads < h.Threads
+	case isBcryptHash(hash):
+		if bcrypt.CompareHashAndPassword([]byte(hash), []byte(password)) != nil {
+			return false, false
+		}
+		cost, _ := bcrypt.Cost([]byte(hash))
+		return true, h.Algo != AlgoBcrypt || cost < h.BcryptCost
+	}
+	return false, false
+}
+
+func isBcryptHash(s string) bool {
+	return strings.HasPrefix(s, "$2a$") || strings.HasPrefix(s, "$2b$") || strings.HasPrefix(s, "$2y$")
+}
+
+// IsPasswordHash değer bilinen bir hash formatında mı (legacy kolon taşıması için).
+func IsPasswordHash(s string) bool {
+	return isBcryptHash(s) || strings.HasPrefix(s, "$argon2id$")
+}
+
+func parseArgon2(s string) (p PasswordHasher, salt, key []byte, err error) {
+	// $argon2id$v=19$m=65536,t=3,p=2$salt$key
+	parts := strings.Split(s, "$")
+	if len(parts) != 6 {
+		return p, nil, nil, errors.New("argon2: bad format")
+	}
+	var v int
+	if _, err = fmt.Sscanf(parts[2], "v=%d", &v); err != nil || v != argon2.Version {
+		return p, nil, nil, errors.New("argon2: bad version")
+	}
+	if _, err = fmt.Sscanf(parts[3], "m=%d,t=%d,p=%d", &p.Memory, &p.Time, &p.Threads); err != nil {
+		return p, nil, nil, errors.New("argon2: bad params")
+	}
+	b64 := base64.RawStdEncoding
+	if salt, err = b64.DecodeString(parts[4]); err != nil {
+		return p, nil, nil, err
+	}
+	if key, err = b64.DecodeString(parts[5]); err != nil {
+		return p, nil, nil, err
+	}
+	return p, salt, key, nil
+}
+
+// ---------------------------------------------------
+// Legacy "password" kolonu taşıması (cmd/migrate-passwords)
+// ---------------------------------------------------
+
+// MigrateLegacyPasswords users.password'daki değerleri password_hash'e taşır
+// (düz metinse hash'ler, zaten hash'se olduğu gibi kopyalar) ve kolonu düşürür.
+// Taşınan satır sayısını döner. Kolon yoksa hiçbir şey yapmaz.
+func MigrateLegacyPasswords(db *gorm.DB, h PasswordHasher) (int, error) {
+	if !db.Migrator().HasColumn(&model.User{}, "password") {
+		return 0, nil
+	}
+	type legacyRow struct {
+		ID           uint
+		Password     string
+		PasswordHash string
+	}
+	var n int
+	err := db.Transaction(func(tx *gorm.DB) error {
+		var rows []legacyRow
+		if err := tx.Table("users").
+			Select("id, password, password_hash").
+			Where("password IS NOT NULL AND password <> ''").
+			Find(&rows).Error; err != nil {
+			return err
+		}
+		for _, r := range rows {
+			if r.PasswordHash != "" {
+				continue // password_hash öncelikli; legacy değer atılır
+			}
+			hash := r.Password
+			if !IsPasswordHash(hash) {
+				var err error
+				if hash, err = h.Hash(r.Password); err != nil {
+					return fmt.Errorf("user %d: %w", r.ID, err)
+				}
+			}
+			if err := tx.Table("users").Where("id = ?", r.ID).Update("password_hash", hash).Error; err != nil {
+				return err
+			}
+			n++
+		}
+		return tx.Exec("ALTER TABLE users DROP COLUMN password").Error
+	})
+	return n, err
+}
@@ -0,0 +1,71 @@
+package service
+
+import (
+	"testing"
+
+	"golang.org/x/crypto/bcrypt"
+)
+
+func TestPasswordHasherVerify(t *testing.T) {
+	// testler hızlı kalsın: düşük maliyetli parametreler
+	bcryptAt := func(cost int) string {
+		h, err := bcrypt.GenerateFromPassword([]byte("s3cret-pass"), cost)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return string(h)
+	}
+	argon := PasswordHasher{Algo: AlgoArgon2id, Memory: 8 * 1024, Time: 1, Threads: 1}
+	argonHash, err := argon.Hash("s3cret-pass")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	bcryptHasher := PasswordHasher{Algo: AlgoBcrypt, BcryptCost: 5}
+	for _, tc := range []struct {
+		name       string
+		h          PasswordHasher
+		hash       string
+		password   string
+		ok, rehash bool
+	}{
+		{"bcrypt current cost", bcryptHasher, bcryptAt(5), "s3cret-pass", true, false},
+		{"bcrypt lower cost", bcryptHasher, bcryptAt(4), "s3cret-pass", true, true},
+		{"bcrypt wrong password", bcryptHasher, bcryptAt(4), "wrong", false, false},
+		// algoritma argon2id'ye geçti: bcrypt hash'i eski sayılır
+		{"bcrypt under argon2id", argon, bcryptAt(4), "s3cret-pass", true, true},
+		{"argon2id current params", argon, argonHash, "s3cret-pass", true, false},
+		{"argon2id weaker params", PasswordHasher{Algo: AlgoArgon2id, Memory: 16 * 1024, Time: 1, Threads: 1}, argonHash, "s3cret-pass", true, true},
+		{"argon2id under bcrypt", bcryptHasher, argonHash, "s3cret-pass", true, true},
+		{"argon2id wrong password", argon, argonHash, "wrong", false, false},
+		// legacy kolondaki düz metin / bilinmeyen format asla eşleşmez
+		{"plaintext", bcryptHasher, "s3cret-pass", "s3cret-pass", false, false},
+		{"empty hash", bcryptHasher, "", "", false, false},
+		{"malformed argon2id", argon, "$argon2id$v=19$m=x$salt$key", "s3cret-pass", false, false},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			ok, rehash := tc.h.Verify(tc.hash, tc.password)
+			if ok != tc.ok || rehash != tc.rehash {
+				t.Fatalf("Verify = (%v, %v), want (%v, %v)", ok, rehash, tc.ok, tc.rehash)
+			}
+		})
+	}
+}
+
+func TestPasswordHasherHashRoundTrip(t *testing.T) {
+	for _, h := range []PasswordHasher{
+		{Algo: AlgoBcrypt, BcryptCost: 4},
+		{Algo: AlgoArgon2id, Memory: 8 * 1024, Time: 1, Threads: 1},
+	} {
+		hash, err := h.Hash("round-trip")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !IsPasswordHash(hash) {
+			t.Fatalf("%s: IsPasswordHash(%q) = false", h.Algo, hash)
+		}
+		if ok, rehash := h.Verify(hash, "round-trip"); !ok || rehash {
+			t.Fatalf("%s: Verify = (%v, %v), want (true, false)", h.Algo, ok, rehash)
+		}
+	}
+}
@@ -0,0 +1,57 @@
+package service
+
+import (
+	"errors"
+	"strings"
+	"testing"
+
+	"example.com/ecom-go/internal/validate"
+)
+
+func TestPasswordPolicyCheck(t *testing.T) {
+	p := PasswordPolicy{MinLen: 8, MaxBytes: bcryptMaxBytes, banned: map[string]struct{}{}}
+	p.loadBanned(strings.NewReader("# yorum\n\nqwerty123\nPassword\n"))
+
+	for _, tc := range []struct {
+		name     string
+		password string
+		email    string
+		want     string // "" → geçerli
+	}{
+		{"empty", "", "", "is required"},
+		{"too short", "abc1234", "", "must be at least 8 characters"},
+		// 8 rune ama 16 byte: uzunluk byte değil karakterle sayılır
+		{"rune length", "şşşşğğğğ", "", ""},
+		{"seven runes many bytes", "şşşşğğğ", "", "must be at least 8 characters"},
+		{"72 bytes", strings.Repeat("a", 72), "", ""},
+		{"73 bytes", strings.Repeat("a", 73), "", "must be at most 72 bytes"},
+		// 37 × 2 byte = 74 byte: rune sayısı sınırın altında olsa da bcrypt kesecekti
+		{"multibyte over cap", strings.Repeat("ş", 37), "", "must be at most 72 bytes"},
+		{"blank", strings.Repeat(" ", 10), "", "must not be blank"},
+		{"banned", "qwerty123", "", "is too common, choose another"},
+		{"banned case-insensitive", "PASSWORD", "", "is too common, choose another"},
+		{"equals email", "Ali.Veli@example.com", "ali.veli@example.com", "is too common, choose another"},
+		{"equals email local part", "ali.veli", "ali.veli@example.com", "is too common, choose another"},
+		{"contains email", "ali.veli@example.com2026", "ali.veli@example.com", "is too common, choose another"},
+		// yerel kısım sadece birebir eşitlikte: kısa adlar her şifrede geçebilir
+		{"contains local part", "ali.veli-2026!", "ali.veli@example.com", ""},
+		{"ok", "correct horse battery", "ali@example.com", ""},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			err := p.Check("password", tc.password, tc.email)
+			if tc.want == "" {
+				if err != nil {
+					t.Fatalf("Check(%q) = %v, want nil", tc.password, err)
+				}
+				return
+			}
+			var fe validate.Errors
+			if !errors.As(err, &fe) {
+				t.Fatalf("Check(%q) = %v, want validate.Errors", tc.password, err)
+			}
+			if got := fe["password"]; got != tc.want {
+				t.Fatalf("Check(%q) password = %q, want %q", tc.password, got, tc.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,397 @@
+package service
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"math/big"
+	"sort"
+	"time"
+
+	"gorm.io/gorm"
+
+	"example.com/ecom-go/internal/model"
+	"example.com/ecom-go/internal/money"
+)
+
+// PricedLine sepet satırının sipariş para birimindeki fiyatı. Unit ve Line
+// katalog modunda (vergi dahil/hariç), Tax indirim sonrası tutar üzerinden.
+type PricedLine struct {
+	Item     model.CartItem
+	Unit     money.Money
+	Line     money.Money
+	Discount money.Money
+	Tax      TaxLine
+}
+
+// DiscountLine bir promosyonun sepete toplam etkisi.
+type DiscountLine struct {
+	PromotionID uint        `json:"promotion_id"`
+	Code        string      `json:"code,omitempty"`
+	Name        string      `json:"name"`
+	Amount      money.Money `json:"amount"`
+}
+
+// Quote sepetin fiyatlanmış hali; sepet özeti ve checkout aynı hesabı kullanır.
+type Quote struct {
+	Currency     money.Currency
+	Jurisdiction string
+	TaxIncluded  bool
+	Lines        []PricedLine
+	Items        money.Money // indirim öncesi satırlar toplamı
+	Discounts    []DiscountLine
+	Discount     money.Money
+	Subtotal     money.Money // indirim sonrası net
+	Shipping     *TaxLine    // Method verildiyse
+	Tax          money.Money // satırlar + kargo
+	Total        money.Money
+	Grams        int
+	// CouponErr sepetteki kupon şu an uygulanamıyorsa nedeni (kupon indirimi yok)
+	CouponErr error
+	Warnings  []Warning
+	// PriceChanges eklendiği andan beri fiyatı değişen satırlar; Version sepetin
+	// (satır, adet, güncel fiyat) özeti: kullanıcı değişiklikleri gördüğünü
+	// checkout'a bu sürümle bildirir.
+	PriceChanges []PriceChange
+	Version      string
+}
+
+// PriceChange tutarlar Quote para biriminde.
+type PriceChange struct {
+	LineID   uint        `json:"line_id"`
+	Name     string      `json:"name"`
+	OldPrice money.Money `json:"old_price"`
+	NewPrice money.Money `json:"new_price"`
+}
+
+// Warning kullanıcının checkout'tan önce görmesi gereken durum.
+type Warning struct {
+	Code    string `json:"code"`              // out_of_stock | price_changed | coupon
+	LineID  uint   `json:"line_id,omitempty"` // CartItem.ID
+	Message string `json:"message"`
+}
+
+type PriceInput struct {
+	UserID       uint
+	Currency     money.Currency
+	Jurisdiction string
+	Method       *model.ShippingMethod // nil → kargo hesaplanmaz
+	Coupon       *model.Promotion      // sepete uygulanmış kupon
+	Now          time.Time
+}
+
+// pricing sepet → Quote hesabı: çeviri, promosyonlar, vergi, kargo.
+type pricing struct {
+	fx  CurrencyService
+	tax TaxService
+}
+
+func (p pricing) Price(tx *gorm.DB, items []model.CartItem, in PriceInput) (Quote, error) {
+	cur := in.Currency
+	conv := p.fx.Converter(cur)
+	q := Quote{
+		Currency: cur, Jurisdiction: in.Jurisdiction, TaxIncluded: p.tax.PricesIncludeTax(),
+		Items: money.New(0, cur), Discount: money.New(0, cur), Subtotal: money.New(0, cur), Tax: money.New(0, cur),
+	}
+	for _, it := range items {
+		unit, err := conv(it.BaseUnitPrice())
+		if err != nil {
+			return q, err
+		}
+		l := PricedLine{Item: it, Unit: unit, Line: unit.Mul(int64(it.Qty)), Discount: money.New(0, cur)}
+		if seen, ok := it.AddedPrice(); ok && seen != it.BaseUnitPrice() {
+			old, err := conv(seen)
+			if err != nil {
+				return q, err
+			}
+			q.PriceChanges = append(q.PriceChanges, PriceChange{LineID: it.ID, Name: it.Product.Name, OldPrice: old, NewPrice: unit})
+			q.Warnings = append(q.Warnings, Warning{Code: "price_changed", LineID: it.ID,
+				Message: fmt.Sprintf("%s: price changed from %s to %s", it.Product.Name, old.Format(money.DefaultLocale), unit.Format(money.DefaultLocale))})
+		}
+		// stok kesin olarak checkout'ta düşülür; burada uyarı
+		if it.Variant != nil && it.Variant.Stock < it.Qty {
+			q.Warnings = append(q.Warnings, Warning{Code: "out_of_stock", LineID: it.ID,
+				Message: fmt.Sprintf("%s: only %d left", it.Product.Name, max(it.Variant.Stock, 0))})
+		}
+		q.Items.Amount += l.Line.Amount
+		q.Grams += it.Product.WeightGrams * it.Qty
+		q.Lines = append(q.Lines, l)
+	}
+
+	// otomatik kampanyalar önce, kupon en son; her biri kalan tutar üzerinden
+	var autos []model.Promotion
+	if err := tx.Preload("Products").Preload("Categories").
+		Where("code IS NULL AND active").Order("id asc").Find(&autos).Error; err != nil {
+		return q, err
+	}
+	for i := range autos {
+		if promoProblem(tx, &autos[i], in.UserID, q.Items, conv, in.Now) != nil {
+			continue
+		}
+		if _, err := applyPromotion(tx, &q, &autos[i], conv); err != nil {
+			return q, err
+		}
+	}
+	if in.Coupon != nil {
+		q.CouponErr = promoProblem(tx, in.Coupon, in.UserID, q.Items, conv, in.Now)
+		if q.CouponErr == nil {
+			applied, err := applyPromotion(tx, &q, in.Coupon, conv)
+			if err != nil {
+				return q, err
+			}
+			if !applied {
+				q.CouponErr = fmt.Errorf("%w: no eligible products in cart", ErrCouponInvalid)
+			}
+		}
+		if q.CouponErr != nil {
+			q.Warnings = append(q.Warnings, Warning{Code: "coupon", Message: q.CouponErr.Error()})
+		}
+	}
+
+	// vergi indirim sonrası satır tutarından, satır başına yuvarlanır
+	taxOf := p.tax.Calculator(in.Jurisdiction)
+	afterDiscount := money.New(0, cur)
+	for i := range q.Lines {
+		l := &q.Lines[i]
+		tl, err := taxOf(l.Item.Product.TaxClass, money.New(l.Line.Amount-l.Discount.Amount, cur))
+		if err != nil {
+			return q, err
+		}
+		l.Tax = tl
+		q.Subtotal.Amount += tl.Net.Amount
+		q.Tax.Amount += tl.Tax.Amount
+		afterDiscount.Amount += l.Line.Amount - l.Discount.Amount
+	}
+
+	// kargo ürünlerle aynı modda fiyatlanır, ücretsiz kargo eşiği indirim sonrası tutara bakar
+	total := q.Subtotal.Amount + q.Tax.Amount
+	if in.Method != nil {
+		fee, err := ShippingFee(*in.Method, afterDiscount, q.Grams, conv)
+		if err != nil {
+			return q, err
+		}
+		st, err := taxOf(in.Method.TaxClass, fee)
+		if err != nil {
+			return q, err
+		}
+		q.Shipping = &st
+		q.Tax.Amount += st.Tax.Amount
+		total += st.Net.Amount + st.Tax.Amount
+	}
+	q.Total = money.New(total, cur)
+	q.Version = cartVersion(items)
+	return q, nil
+}
+
+// cartVersion satırlar + adetler + güncel (ürün para birimindeki) fiyatlar
+// değişmedikçe aynı kalır; kur oynaması sürümü değiştirmez.
+func cartVersion(items []model.CartItem) string {
+	h := sha256.New()
+	for _, it := range items {
+		var vid uint
+		if it.VariantID != nil {
+			vid = *it.VariantID
+		}
+		fmt.Fprintf(h, "%d:%d:%d:%s;", it.ID, vid, it.Qty, it.BaseUnitPrice())
+	}
+	return hex.EncodeToString(h.Sum(nil))[:16]
+}
+
+// promoProblem promosyon şu an bu sepete uygulanamıyorsa nedenini döner.
+func promoProblem(tx *gorm.DB, p *model.Promotion, userID uint, items money.Money, conv func(money.Money) (money.Money, error), now time.Time) error {
+	if !p.Active || (p.StartsAt != nil && now.Before(*p.StartsAt)) || (p.EndsAt != nil && !now.Before(*p.EndsAt)) {
+		return fmt.Errorf("%w: not active", ErrCouponInvalid)
+	}
+	if p.MinOrderCents > 0 {
+		min, err := conv(money.New(p.MinOrderCents, money.Currency(p.Currency)))
+		if err != nil {
+			return err
+		}
+		if items.Amount < min.Amount {
+			return fmt.Errorf("%w: minimum order is %s", ErrCouponInvalid, min.Format(money.DefaultLocale))
+		}
+	}
+	if p.UsageLimit > 0 && p.UsedCount >= p.UsageLimit {
+		return ErrCouponExhausted
+	}
+	if p.PerUserLimit > 0 {
+		var n int64
+		if err := tx.Model(&model.PromotionRedemption{}).Where("promotion_id = ? AND user_id = ?", p.ID, userID).Count(&n).Error; err != nil {
+			return err
+		}
+		if n >= int64(p.PerUserLimit) {
+			return fmt.Errorf("%w: already used", ErrCouponExhausted)
+		}
+	}
+	return nil
+}
+
+// promoProducts kapsamdaki ürün id'leri; nil → kapsam yok (tüm ürünler).
+func promoProducts(tx *gorm.DB, p *model.Promotion) (map[uint]bool, error) {
+	if len(p.Products) == 0 && len(p.Categories) == 0 {
+		return nil, nil
+	}
+	set := map[uint]bool{}
+	for _, pr := range p.Products {
+		set[pr.ID] = true
+	}
+	if len(p.Categories) > 0 {
+		catIDs := make([]uint, len(p.Categories))
+		for i, c := range p.Categories {
+			catIDs[i] = c.ID
+		}
+		var ids []uint
+		if err := tx.Raw(`SELECT DISTINCT product_id FROM product_categories WHERE category_id IN (
+				WITH RECURSIVE sub AS (
+					SELECT id FROM categories WHERE id IN ?
+					UNION
+					SELECT c.id FROM categories c JOIN sub ON c.parent_id = sub.id
+				) SELECT id FROM sub)`, catIDs).Scan(&ids).Error; err != nil {
+			return nil, err
+		}
+		for _, id := range ids {
+			set[id] = true
+		}
+	}
+	return set, nil
+}
+
+// applyPromotion indirimi uygun satırlara dağıtır; indirim çıkmadıysa false.
+func applyPromotion(tx *gorm.DB, q *Quote, p *model.Promotion, conv func(money.Money) (money.Money, error)) (bool, error) {
+	scope, err := promoProducts(tx, p)
+	if err != nil {
+		return false, err
+	}
+	var idx []int // uygun satırlar
+	var remaining []int64
+	var base int64
+	for i, l := range q.Lines {
+		if scope != nil && !scope[l.Item.ProductID] {
+			continue
+		}
+		r := l.Line.Amount - l.Discount.Amount
+		if r <= 0 {
+			continue
+		}
+		idx = append(idx, i)
+		remaining = append(remaining, r)
+		base += r
+	}
+	if base == 0 {
+		return false, nil
+	}
+
+	shares := make([]int64, len(idx))
+	switch p.Kind {
+	case model.PromoPercent:
+		amount := money.RoundHalfUp(new(big.Rat).SetFrac(
+			new(big.Int).Mul(big.NewInt(base), big.NewInt(int64(p.PercentBP))), big.NewInt(10000)))
+		shares = allocate(amount, remaining)
+	case model.PromoFixed:
+		amt, err := conv(money.New(p.AmountCents, money.Currency(p.Currency)))
+		if err != nil {
+			return false, err
+		}
+		shares = allocate(min(amt.Amount, base), remaining)
+	case model.PromoBuyXGetY:
+		// her BuyQty+GetQty adetten en ucuz GetQty adet bedava
+		type unit struct {
+			k     int
+			price int64
+		}
+		var units []unit
+		for k, i := range idx {
+			for n := 0; n < q.Lines[i].Item.Qty; n++ {
+				units = append(units, unit{k, q.Lines[i].Unit.Amount})
+			}
+		}
+		group := p.BuyQty + p.GetQty
+		if group <= 0 {
+			return false, nil
+		}
+		free := len(units) / group * p.GetQty
+		sort.SliceStable(units, func(a, b int) bool { return units[a].price < units[b].price })
+		for _, u := range units[:free] {
+			shares[u.k] += u.price
+		}
+		for k := range shares {
+			shares[k] = min(shares[k], remaining[k])
+		}
+	default:
+		return false, fmt.Errorf("unknown promotion kind %q", p.Kind)
+	}
+
+	var total int64
+	for k, i := range idx {
+		q.Lines[i].Discount.Amount += shares[k]
+		total += shares[k]
+	}
+	if total == 0 {
+		return false, nil
+	}
+	d := DiscountLine{PromotionID: p.ID, Name: p.Name, Amount: money.New(total, q.Currency)}
+	if p.Code != nil {
+		d.Code = *p.Code
+	}
+	q.Discounts = append(q.Discounts, d)
+	q.Discount.Amount += total
+	return true, nil
+}
+
+// allocate amount'u ağırlıklarla orantılı böler (en büyük kalan yöntemi);
+// parçaların toplamı her zaman amount'tur, hiçbir parça ağırlığını aşmaz.
+func allocate(amount int64, weights []int64) []int64 {
+	out := make([]int64, len(weights))
+	var sum int64
+	for _, w := range weights {
+		sum += w
+	}
+	if sum == 0 || amount == 0 {
+		return out
+	}
+	type rem struct {
+		i int
+		r *big.Int
+	}
+	rems := make([]rem, len(weights))
+	total, bigSum := big.NewInt(amount), big.NewInt(sum)
+	var given int64
+	for i, w := range weights {
+		qt, r := new(big.Int).QuoRem(new(big.Int).Mul(total, big.NewInt(w)), bigSum, new(big.Int))
+		out[i] = qt.Int64()
+		given += out[i]
+		rems[i] = rem{i, r}
+	}
+	sort.SliceStable(rems, func(a, b int) bool { return rems[a].r.Cmp(rems[b].r) > 0 })
+	for k := 0; given < amount; k++ {
+		out[rems[k%len(rems)].i]++
+		given++
+	}
+	return out
+}
+
+// loadCart sepeti ürün + varyant (seçenek adlarıyla) birlikte yükler.
+func loadCart(tx *gorm.DB, userID uint) ([]model.CartItem, error) {
+	var items []model.CartItem
+	err := preloadVariant(tx.Preload("Product"), "Variant.").Where("user_id = ?", userID).Order("id asc").Find(&items).Error
+	return items, err
+}
+
+// cartCoupon sepete uygulanmış kupon; yoksa nil.
+func cartCoupon(tx *gorm.DB, userID uint) (*model.Promotion, error) {
+	var cc model.CartCoupon
+	err := tx.Where("user_id = ?", userID).First(&cc).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	var p model.Promotion
+	err = tx.Preload("Products").Preload("Categories").First(&p, cc.PromotionID).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, nil
+	}
+	return &p, err
+}
@@ -0,0 +1,275 @@
+package service
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"math/big"
+	"time"
+
+	"gorm.io/gorm"
+
+	"example.com/ecom-go/internal/model"
+	"example.com/ecom-go/internal/money"
+)
+
+// Ürün listesi sıralamaları
+const (
+	SortRelevance = "relevance" // sadece q varken anlamlı
+	SortPriceAsc  = "price_asc"
+	SortPriceDesc = "price_desc"
+	SortNewest    = "newest"
+	SortDefault   = "id"
+)
+
+const (
+	DefaultPageSize = 20
+	MaxPageSize     = 100
+)
+
+// tsConfig arama için Postgres metin yapılandırması; isimler TR/EN karışık
+// olduğu için dil-bağımsız "simple" (kök bulma yok, büyük/küçük harf yok).
+const tsConfig = "simple"
+
+type ProductQuery struct {
+	Category string // slug; alt kategoriler dahil
+	Q        string
+	MinPrice *int64 // Currency'nin en küçük biriminde (kuruş, cent)
+	MaxPrice *int64
+	Sort     string
+	Limit    int
+	Cursor   string
+	Currency money.Currency // boşsa varsayılan; fiyat gösterimi, filtresi ve sırası bu birimde
+}
+
+// ProductPage GET /api/products cevabı.
+type ProductPage struct {
+	Items      []model.Product `json:"items"`
+	Total      int64           `json:"total"`
+	NextCursor string          `json:"next_cursor,omitempty"`
+}
+
+type ProductService interface {
+	Search(q ProductQuery) (ProductPage, error)
+}
+
+type productService struct {
+	db *gorm.DB
+	fx CurrencyService
+}
+
+func NewProductService(db *gorm.DB, fx CurrencyService) ProductService {
+	return &productService{db: db, fx: fx}
+}
+
+// cursor son görülen satırın sıralama anahtarı; sıralama değişirse geçersiz.
+type productCursor struct {
+	Sort  string    `json:"s"`
+	ID    uint      `json:"id"`
+	Price int64     `json:"p,omitempty"`
+	Cur   string    `json:"c,omitempty"` // fiyat sırasında: Price'ın para birimi
+	At    time.Time `json:"t,omitempty"`
+	Rank  float64   `json:"r,omitempty"`
+}
+
+func encodeCursor(c productCursor) string {
+	b, _ := json.Marshal(c)
+	return base64.RawURLEncoding.EncodeToString(b)
+}
+
+func decodeCursor(s string) (productCursor, error) {
+	var c productCursor
+	b, err := base64.RawURLEncoding.DecodeString(s)
+	if err != nil {
+		return c, ErrInvalidCursor
+	}
+	if err := json.Unmarshal(b, &c); err != nil {
+		return c, ErrInvalidCursor
+	}
+	return c, nil
+}
+
+// NormalizeSort boş/uyumsuz sıralamayı varsayılana çeker.
+func NormalizeSort(sort, q string) string {
+	switch sort {
+	case SortPriceAsc, SortPriceDesc, SortNewest, SortDefault:
+		return sort
+	case SortRelevance:
+		if q != "" {
+			return sort
+		}
+	case "":
+		if q != "" {
+			return SortRelevance
+		}
+	}
+	return SortDefault
+}
+
+// listPrices products'ı list_price kolonuyla saran tablo: listede gösterilen fiyat
+// (varyantlı üründe en ucuz varyant, override yoksa ürün fiyatı) to'ya çevrilmiş.
+// Filtre ve sıralama da bu kolonla: kullanıcı gördüğü fiyatla süzer.
+// Çeviri money.Convert ile aynı (hane farkı, yarım sıfırdan uzağa); kuru olmayan
+// para birimindeki üründe NULL.
+func (s *productService) listPrices(to money.Currency) (*gorm.DB, error) {
+	var curs []string
+	if err := s.db.Model(&model.Product{}).Distinct().Pluck("currency", &curs).Error; err != nil {
+		return nil, err
+	}
+	expr, args := "CASE t.currency", []any{}
+	for _, c := range curs {
+		from := money.Currency(c)
+		if from == to {
+			expr += " WHEN ? THEN t.from_cents"
+			args = append(args, c)
+			continue
+		}
+		r, err := s.fx.Rate(from, to)
+		if errors.Is(err, ErrNoExchangeRate) {
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+		num, den := new(big.Int).Set(r.Num()), new(big.Int).Set(r.Denom())
+		if d := to.Exp() - from.Exp(); d > 0 {
+			num.Mul(num, new(big.Int).Exp(big.NewInt(10), big.NewInt(int64(d)), nil))
+		} else if d < 0 {
+			den.Mul(den, new(big.Int).Exp(big.NewInt(10), big.NewInt(int64(-d)), nil))
+		}
+		expr += " WHEN ? THEN ROUND(t.from_cents * ?::numeric / ?::numeric)::bigint"
+		args = append(args, c, num.String(), den.String())
+	}
+	if len(args) == 0 {
+		expr = "NULL::bigint"
+	} else {
+		expr += " END"
+	}
+	inner := s.db.Table(`(SELECT p.*, COALESCE(
+			(SELECT MIN(COALESCE(v.price_cents, p.price_cents)) FROM product_variants v WHERE v.product_id = p.id),
+			p.price_cents) AS from_cents
+		FROM products p) AS t`).
+		Select("t.*, "+expr+" AS list_price", args...)
+	return s.db.Table("(?) AS products", inner), nil
+}
+
+func (s *productService) Search(pq ProductQuery) (ProductPage, error) {
+	pq.Sort = NormalizeSort(pq.Sort, pq.Q)
+	if pq.Limit <= 0 || pq.Limit > MaxPageSize {
+		pq.Limit = DefaultPageSize
+	}
+	if pq.Currency == "" {
+		pq.Currency = s.fx.Default()
+	}
+
+	base, err := s.listPrices(pq.Currency)
+	if err != nil {
+		return ProductPage{}, err
+	}
+	if pq.Category != "" {
+		var cat model.Category
+		if err := s.db.Where("slug = ?", pq.Category).First(&cat).Error; err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				return ProductPage{}, ErrCategoryNotFound
+			}
+			return ProductPage{}, err
+		}
+		base = base.Where(`products.id IN (
+			SELECT pc.product_id FROM product_categories pc WHERE pc.category_id IN (
+				WITH RECURSIVE sub AS (
+					SELECT id FROM categories WHERE id = ?
+					UNION
+					SELECT c.id FROM categories c JOIN sub ON c.parent_id = sub.id
+				) SELECT id FROM sub))`, cat.ID)
+	}
+	tsq := "websearch_to_tsquery('" + tsConfig + "', ?)"
+	if pq.Q != "" {
+		base = base.Where("search_vector @@ "+tsq, pq.Q)
+	}
+	if pq.MinPrice != nil {
+		base = base.Where("list_price >= ?", *pq.MinPrice)
+	}
+	if pq.MaxPrice != nil {
+		base = base.Where("list_price <= ?", *pq.MaxPrice)
+	}
+
+	var page ProductPage
+	if err := base.Session(&gorm.Session{}).Count(&page.Total).Error; err != nil {
+		return page, err
+	}
+
+	q := base.Session(&gorm.Session{})
+	rankExpr := "ts_rank(search_vector, " + tsq + ")"
+	if pq.Sort == SortRelevance {
+		q = q.Select("products.*, "+rankExpr+" AS rank", pq.Q)
+	}
+
+	if pq.Cursor != "" {
+		c, err := decodeCursor(pq.Cursor)
+		if err != nil || c.Sort != pq.Sort {
+			return page, ErrInvalidCursor
+		}
+		// fiyat anahtarı para birimine bağlı: başka birimle devam edilemez
+		if (pq.Sort == SortPriceAsc || pq.Sort == SortPriceDesc) && c.Cur != string(pq.Currency) {
+			return page, ErrInvalidCursor
+		}
+		switch pq.Sort {
+		case SortPriceAsc:
+			q = q.Where("(list_price, id) > (?, ?)", c.Price, c.ID)
+		case SortPriceDesc:
+			q = q.Where("list_price < ? OR (list_price = ? AND id > ?)", c.Price, c.Price, c.ID)
+		case SortNewest:
+			q = q.Where("(created_at, id) < (?, ?)", c.At, c.ID)
+		case SortRelevance:
+			q = q.Where(rankExpr+" < ? OR ("+rankExpr+" = ? AND id > ?)", pq.Q, c.Rank, pq.Q, c.Rank, c.ID)
+		default:
+			q = q.Where("id > ?", c.ID)
+		}
+	}
+
+	switch pq.Sort {
+	case SortPriceAsc:
+		q = q.Order("list_price asc, id asc")
+	case SortPriceDesc:
+		q = q.Order("list_price desc NULLS LAST, id asc")
+	case SortNewest:
+		q = q.Order("created_at desc, id desc")
+	case SortRelevance:
+		q = q.Order("rank desc, id asc")
+	default:
+		q = q.Order("id asc")
+	}
+
+	// bir fazlası: sonraki sayfa var mı?
+	var rows []struct {
+		model.Product
+		ListPrice *int64  `gorm:"column:list_price"`
+		Rank      float64 `gorm:"column:rank"`
+	}
+	if err := q.Limit(pq.Limit + 1).Find(&rows).Error; err != nil {
+		return page, err
+	}
+	more := len(rows) > pq.Limit
+	if more {
+		rows = rows[:pq.Limit]
+	}
+	page.Items = make([]model.Product, 0, len(rows))
+	for _, r := range rows {
+		if r.ListPrice == nil {
+			return page, ErrNoExchangeRate
+		}
+		p := r.Product
+		price := money.New(*r.ListPrice, pq.Currency)
+		p.Price = &price
+		page.Items = append(page.Items, p)
+	}
+	if more {
+		last := rows[len(rows)-1]
+		c := productCursor{Sort: pq.Sort, ID: last.ID, At: last.CreatedAt, Rank: last.Rank}
+		if pq.Sort == SortPriceAsc || pq.Sort == SortPriceDesc {
+			c.Price, c.Cur = *last.ListPrice, string(pq.Currency)
+		}
+		page.NextCursor = encodeCursor(c)
+	}
+	return page, nil
+}
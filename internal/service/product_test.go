@@ -0,0 +1,72 @@
+package service
+
+import (
+	"errors"
+	"fmt"
+	"slices"
+	"testing"
+
+	"example.com/ecom-go/internal/model"
+)
+
+func TestSearchFiltersAndSortsByListPrice(t *testing.T) {
+	db := testDB(t)
+	fx := &currencyService{db: db, def: "TRY"}
+	if _, err := fx.SetRate("USD", "TRY", "30"); err != nil {
+		t.Fatal(err)
+	}
+	cat := model.Category{Name: "Test", Slug: "list-price-test"}
+	if err := db.Create(&cat).Error; err != nil {
+		t.Fatal(err)
+	}
+	half := int64(500)
+	// A: varyantlı, en ucuzu 5 ₺; B: 1 $ = 30 ₺; C: 20 ₺
+	ps := []model.Product{
+		{Name: "A", PriceCents: 1000, Currency: "TRY", Categories: []model.Category{cat},
+			Variants: []model.ProductVariant{{SKU: "LP-A-1", PriceCents: &half}, {SKU: "LP-A-2"}}},
+		{Name: "B", PriceCents: 100, Currency: "USD", Categories: []model.Category{cat}},
+		{Name: "C", PriceCents: 2000, Currency: "TRY", Categories: []model.Category{cat}},
+	}
+	if err := db.Create(&ps).Error; err != nil {
+		t.Fatal(err)
+	}
+	s := &productService{db: db, fx: fx}
+	names := func(p ProductPage) (out []string) {
+		for _, it := range p.Items {
+			out = append(out, fmt.Sprintf("%s:%s:%d", it.Name, it.Price.Currency, it.Price.Amount))
+		}
+		return out
+	}
+	eq := func(got []string, want ...string) bool { return slices.Equal(got, want) }
+
+	// filtre gösterilen (çevrilmiş, varyantlı) fiyatla: 10 ₺ altı sadece A
+	maxTRY := int64(1000)
+	page, err := s.Search(ProductQuery{Category: cat.Slug, MaxPrice: &maxTRY, Currency: "TRY"})
+	if err != nil || !eq(names(page), "A:TRY:500") {
+		t.Fatalf("max_price: %v, %v", names(page), err)
+	}
+	// USD'de 25 ¢ üstü: B (100 ¢) ve C (2000/30 → 67 ¢); A 500/30 → 17 ¢
+	minUSD := int64(25)
+	page, err = s.Search(ProductQuery{Category: cat.Slug, MinPrice: &minUSD, Sort: SortPriceAsc, Currency: "USD"})
+	if err != nil || !eq(names(page), "C:USD:67", "B:USD:100") {
+		t.Fatalf("min_price usd: %v, %v", names(page), err)
+	}
+
+	// sıralama + cursor
+	first, err := s.Search(ProductQuery{Category: cat.Slug, Sort: SortPriceAsc, Limit: 2, Currency: "TRY"})
+	if err != nil || !eq(names(first), "A:TRY:500", "C:TRY:2000") || first.NextCursor == "" {
+		t.Fatalf("page 1: %v, %q, %v", names(first), first.NextCursor, err)
+	}
+	second, err := s.Search(ProductQuery{Category: cat.Slug, Sort: SortPriceAsc, Limit: 2, Cursor: first.NextCursor, Currency: "TRY"})
+	if err != nil || !eq(names(second), "B:TRY:3000") {
+		t.Fatalf("page 2: %v, %v", names(second), err)
+	}
+	// fiyat cursor'ı başka para birimiyle sürdürülemez
+	if _, err := s.Search(ProductQuery{Category: cat.Slug, Sort: SortPriceAsc, Limit: 2, Cursor: first.NextCursor, Currency: "USD"}); !errors.Is(err, ErrInvalidCursor) {
+		t.Fatalf("cursor in other currency: err = %v, want ErrInvalidCursor", err)
+	}
+	desc, err := s.Search(ProductQuery{Category: cat.Slug, Sort: SortPriceDesc, Currency: "TRY"})
+	if err != nil || !eq(names(desc), "B:TRY:3000", "C:TRY:2000", "A:TRY:500") {
+		t.Fatalf("price_desc: %v, %v", names(desc), err)
+	}
+}
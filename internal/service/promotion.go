@@ -0,0 +1,147 @@
+package service
+
+import (
+	"errors"
+	"time"
+
+	"gorm.io/gorm"
+
+	"example.com/ecom-go/internal/model"
+	"example.com/ecom-go/internal/money"
+)
+
+type PromotionInput struct {
+	Name                     string
+	Code                     string // boş → otomatik kampanya
+	Kind                     string
+	PercentBP                int
+	AmountCents              int64
+	Currency                 money.Currency
+	BuyQty, GetQty           int
+	MinOrderCents            int64
+	UsageLimit, PerUserLimit int
+	StartsAt, EndsAt         *time.Time
+	Active                   bool
+	ProductIDs, CategoryIDs  []uint // ikisi de boş → tüm ürünler
+}
+
+type PromotionService interface {
+	List() ([]model.Promotion, error)
+	Create(in PromotionInput) (model.Promotion, error)
+	Update(id uint, in PromotionInput) (model.Promotion, error)
+	// Delete geçmiş siparişlerdeki indirim satırları snapshot olduğu için etkilenmez.
+	Delete(id uint) error
+}
+
+type promotionService struct{ db *gorm.DB }
+
+func NewPromotionService(db *gorm.DB) PromotionService { return &promotionService{db: db} }
+
+// ValidPromotionKind model.Promo* sabitlerinden biri mi.
+func ValidPromotionKind(k string) bool {
+	return k == model.PromoPercent || k == model.PromoFixed || k == model.PromoBuyXGetY
+}
+
+func (s *promotionService) List() ([]model.Promotion, error) {
+	var ps []model.Promotion
+	return ps, s.db.Preload("Products").Preload("Categories").Order("id desc").Find(&ps).Error
+}
+
+func (s *promotionService) Create(in PromotionInput) (model.Promotion, error) {
+	var p model.Promotion
+	return p, s.db.Transaction(func(tx *gorm.DB) error { return savePromotion(tx, &p, in) })
+}
+
+func (s *promotionService) Update(id uint, in PromotionInput) (model.Promotion, error) {
+	var p model.Promotion
+	err := s.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.First(&p, id).Error; err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				return ErrPromotionNotFound
+			}
+			return err
+		}
+		return savePromotion(tx, &p, in)
+	})
+	return p, err
+}
+
+func (s *promotionService) Delete(id uint) error {
+	return s.db.Transaction(func(tx *gorm.DB) error {
+		p := model.Promotion{ID: id}
+		if err := tx.First(&p, id).Error; err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				return ErrPromotionNotFound
+			}
+			return err
+		}
+		if err := tx.Where("promotion_id = ?", id).Delete(&model.CartCoupon{}).Error; err != nil {
+			return err
+		}
+		if err := tx.Model(&p).Association("Products").Clear(); err != nil {
+			return err
+		}
+		if err := tx.Model(&p).Association("Categories").Clear(); err != nil {
+			return err
+		}
+		return tx.Delete(&p).Error
+	})
+}
+
+func savePromotion(tx *gorm.DB, p *model.Promotion, in PromotionInput) error {
+	var code *string
+	if in.Code != "" {
+		c := NormalizeCoupon(in.Code)
+		code = &c
+		var n int64
+		if err := tx.Model(&model.Promotion{}).Where("code = ? AND id <> ?", c, p.ID).Count(&n).Error; err != nil {
+			return err
+		}
+		if n > 0 {
+			return ErrCouponCodeTaken
+		}
+	}
+	var products []model.Product
+	if ids := uniqueIDs(in.ProductIDs); len(ids) > 0 {
+		if err := tx.Where("id IN ?", ids).Find(&products).Error; err != nil {
+			return err
+		}
+		if len(products) != len(ids) {
+			return ErrProductNotFound
+		}
+	}
+	var cats []model.Category
+	if ids := uniqueIDs(in.CategoryIDs); len(ids) > 0 {
+		if err := tx.Where("id IN ?", ids).Find(&cats).Error; err != nil {
+			return err
+		}
+		if len(cats) != len(ids) {
+			return ErrCategoryNotFound
+		}
+	}
+
+	p.Name, p.Code, p.Kind = in.Name, code, in.Kind
+	p.PercentBP, p.AmountCents, p.Currency = in.PercentBP, in.AmountCents, string(in.Currency)
+	p.BuyQty, p.GetQty, p.MinOrderCents = in.BuyQty, in.GetQty, in.MinOrderCents
+	p.UsageLimit, p.PerUserLimit = in.UsageLimit, in.PerUserLimit
+	p.StartsAt, p.EndsAt, p.Active = in.StartsAt, in.EndsAt, in.Active
+	if err := tx.Omit("Products", "Categories").Save(p).Error; err != nil {
+		return err
+	}
+	if err := replaceAssoc(tx.Model(p).Omit("Products.*").Association("Products"), products, len(products)); err != nil {
+		return err
+	}
+	if err := replaceAssoc(tx.Model(p).Omit("Categories.*").Association("Categories"), cats, len(cats)); err != nil {
+		return err
+	}
+	p.Products, p.Categories = products, cats
+	return nil
+}
+
+// replaceAssoc boş listede Clear (Replace boş slice'la join satırlarını silmez).
+func replaceAssoc(a *gorm.Association, vals any, n int) error {
+	if n == 0 {
+		return a.Clear()
+	}
+	return a.Replace(vals)
+}
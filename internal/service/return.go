@@ -0,0 +1,261 @@
+package service
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+
+	"example.com/ecom-go/internal/model"
+	"example.com/ecom-go/internal/validate"
+)
+
+type ReturnInput struct {
+	Items  []RefundLine
+	Reason string
+}
+
+type ReturnService interface {
+	// Open kargolanmış siparişin satırları için talep açar; adet, iade
+	// edilmemiş ve açık taleplerde olmayan miktarı aşamaz.
+	Open(userID, orderID uint, in ReturnInput) (model.ReturnRequest, error)
+	List(userID uint) ([]model.ReturnRequest, error)
+	// Get sadece sahibine; userID 0 → admin.
+	Get(userID, id uint) (model.ReturnRequest, error)
+	// All admin listesi; status boş → hepsi.
+	All(status string) ([]model.ReturnRequest, error)
+	Approve(id uint, note string) (model.ReturnRequest, error)
+	Reject(id uint, note string) (model.ReturnRequest, error)
+	// Receive ürünler depoya ulaştı; restock ise adetler stoğa eklenir.
+	Receive(id uint, restock bool, note string) (model.ReturnRequest, error)
+	// Refund teslim alınan satırları siparişten iade eder (Refund kayıtları).
+	Refund(id uint, note string) (model.ReturnRequest, error)
+}
+
+type returnService struct {
+	db    *gorm.DB
+	email EmailService
+}
+
+func NewReturnService(db *gorm.DB, email EmailService) ReturnService {
+	return &returnService{db: db, email: email}
+}
+
+// openReturn adetleri başka talepte bekleyen durumlar.
+var openReturn = []string{model.ReturnRequested, model.ReturnApproved, model.ReturnReceived}
+
+// returnHeld siparişin açık iade taleplerinde bekleyen adetleri (order_item_id → adet).
+// Bu adetler başka bir talebe ya da admin iadesine verilemez.
+func returnHeld(tx *gorm.DB, orderID uint) (map[uint]int, error) {
+	var pending []struct {
+		OrderItemID uint
+		Qty         int
+	}
+	if err := tx.Model(&model.ReturnItem{}).
+		Select("return_items.order_item_id, SUM(return_items.qty) AS qty").
+		Joins("JOIN return_requests ON return_requests.id = return_items.return_request_id").
+		Where("return_requests.order_id = ? AND return_requests.status IN ?", orderID, openReturn).
+		Group("return_items.order_item_id").Scan(&pending).Error; err != nil {
+		return nil, err
+	}
+	held := map[uint]int{}
+	for _, p := range pending {
+		held[p.OrderItemID] = p.Qty
+	}
+	return held, nil
+}
+
+func (s *returnService) Open(userID, orderID uint, in ReturnInput) (model.ReturnRequest, error) {
+	var r model.ReturnRequest
+	err := s.db.Transaction(func(tx *gorm.DB) error {
+		// sipariş kilidi: aynı satıra eşzamanlı iki talep adetleri aşamasın
+		o, err := loadOrder(tx, userID, orderID, true)
+		if err != nil {
+			return err
+		}
+		if o.Status != model.OrderShipped {
+			return ErrOrderNotReturnable
+		}
+		held, err := returnHeld(tx, o.ID)
+		if err != nil {
+			return err
+		}
+		left := map[uint]int{}
+		for _, it := range o.Items {
+			left[it.ID] = it.Qty - it.RefundedQty - held[it.ID]
+		}
+
+		fe := validate.Errors{}
+		want := map[uint]int{}
+		for _, l := range in.Items {
+			want[l.OrderItemID] += l.Qty
+		}
+		r = model.ReturnRequest{OrderID: o.ID, UserID: o.UserID, Status: model.ReturnRequested, Reason: strings.TrimSpace(in.Reason)}
+		for _, it := range o.Items {
+			k, ok := want[it.ID]
+			if !ok {
+				continue
+			}
+			delete(want, it.ID)
+			if k <= 0 || k > left[it.ID] {
+				fe.Add("items", fmt.Sprintf("order item %d: at most %d can be returned", it.ID, max(left[it.ID], 0)))
+				continue
+			}
+			r.Items = append(r.Items, model.ReturnItem{OrderItemID: it.ID, Qty: k})
+		}
+		for id := range want {
+			fe.Add("items", fmt.Sprintf("order item %d not found", id))
+		}
+		if len(fe) == 0 && len(r.Items) == 0 {
+			fe.Add("items", "choose at least one item")
+		}
+		if err := fe.Err(); err != nil {
+			return err
+		}
+		r.History = []model.ReturnEvent{{Status: model.ReturnRequested}}
+		return tx.Create(&r).Error
+	})
+	if err != nil {
+		return model.ReturnRequest{}, err
+	}
+	notifyUser(s.db, s.email, r.UserID, fmt.Sprintf("Return #%d received", r.ID),
+		fmt.Sprintf("We received your return request #%d for order #%d and will review it shortly.", r.ID, r.OrderID))
+	return r, nil
+}
+
+func (s *returnService) List(userID uint) ([]model.ReturnRequest, error) {
+	var rs []model.ReturnRequest
+	return rs, s.db.Preload("Items").Where("user_id = ?", userID).Order("id desc").Find(&rs).Error
+}
+
+func (s *returnService) Get(userID, id uint) (model.ReturnRequest, error) {
+	return loadReturn(s.db, userID, id, false)
+}
+
+func (s *returnService) All(status string) ([]model.ReturnRequest, error) {
+	var rs []model.ReturnRequest
+	q := s.db.Preload("Items").Order("id desc")
+	if status != "" {
+		q = q.Where("status = ?", status)
+	}
+	return rs, q.Find(&rs).Error
+}
+
+func loadReturn(tx *gorm.DB, userID, id uint, lock bool) (model.ReturnRequest, error) {
+	var r model.ReturnRequest
+	q := tx.Preload("Items").Preload("History", func(db *gorm.DB) *gorm.DB { return db.Order("id") })
+	if lock {
+		q = q.Clauses(clause.Locking{Strength: "UPDATE"})
+	}
+	if userID != 0 {
+		q = q.Where("user_id = ?", userID)
+	}
+	if err := q.First(&r, id).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return r, ErrReturnNotFound
+		}
+		return r, err
+	}
+	return r, nil
+}
+
+// advance talebi from → to geçirir, geçmişe yazar ve kullanıcıya mail atar.
+// fn geçişe özel işi aynı tx içinde yapar (stok, iade); hata → geçiş olmaz.
+func (s *returnService) advance(id uint, from, to, note string, fn func(tx *gorm.DB, r *model.ReturnRequest) error) (model.ReturnRequest, error) {
+	var r model.ReturnRequest
+	note = strings.TrimSpace(note)
+	err := s.db.Transaction(func(tx *gorm.DB) error {
+		var err error
+		if r, err = loadReturn(tx, 0, id, true); err != nil {
+			return err
+		}
+		if r.Status != from {
+			return fmt.Errorf("%w: return is %s", ErrReturnStatus, r.Status)
+		}
+		if fn != nil {
+			if err := fn(tx, &r); err != nil {
+				return err
+			}
+		}
+		ev := model.ReturnEvent{ReturnRequestID: r.ID, Status: to, Note: note}
+		if err := tx.Create(&ev).Error; err != nil {
+			return err
+		}
+		r.Status = to
+		r.History = append(r.History, ev)
+		return tx.Model(&r).Updates(map[string]any{"status": r.Status, "restocked": r.Restocked, "refunded_cents": r.RefundedCents}).Error
+	})
+	if err != nil {
+		return model.ReturnRequest{}, err
+	}
+	text := fmt.Sprintf("Your return #%d for order #%d is now %s.", r.ID, r.OrderID, r.Status)
+	if note != "" {
+		text += " " + note
+	}
+	notifyUser(s.db, s.email, r.UserID, fmt.Sprintf("Return #%d %s", r.ID, r.Status), text)
+	return r, nil
+}
+
+func (s *returnService) Approve(id uint, note string) (model.ReturnRequest, error) {
+	return s.advance(id, model.ReturnRequested, model.ReturnApproved, note, nil)
+}
+
+func (s *returnService) Reject(id uint, note string) (model.ReturnRequest, error) {
+	return s.advance(id, model.ReturnRequested, model.ReturnRejected, note, nil)
+}
+
+func (s *returnService) Receive(id uint, restock bool, note string) (model.ReturnRequest, error) {
+	return s.advance(id, model.ReturnApproved, model.ReturnReceived, note, func(tx *gorm.DB, r *model.ReturnRequest) error {
+		if !restock {
+			return nil
+		}
+		ids := make([]uint, len(r.Items))
+		for i, it := range r.Items {
+			ids[i] = it.OrderItemID
+		}
+		var ois []model.OrderItem
+		if err := tx.Where("id IN ?", ids).Find(&ois).Error; err != nil {
+			return err
+		}
+		variant := map[uint]uint{}
+		for _, oi := range ois {
+			if oi.VariantID != nil {
+				variant[oi.ID] = *oi.VariantID
+			}
+		}
+		qty := map[uint]int{}
+		for _, it := range r.Items {
+			if vid, ok := variant[it.OrderItemID]; ok {
+				qty[vid] += it.Qty
+			}
+		}
+		r.Restocked = true
+		return restockVariants(tx, qty)
+	})
+}
+
+func (s *returnService) Refund(id uint, note string) (model.ReturnRequest, error) {
+	return s.advance(id, model.ReturnReceived, model.ReturnRefunded, note, func(tx *gorm.DB, r *model.ReturnRequest) error {
+		o, err := loadOrder(tx, 0, r.OrderID, true)
+		if err != nil {
+			return err
+		}
+		if o.Status != model.OrderShipped {
+			return ErrOrderNotRefundable
+		}
+		in := RefundInput{Reason: fmt.Sprintf("return #%d", r.ID)}
+		for _, it := range r.Items {
+			in.Items = append(in.Items, RefundLine{OrderItemID: it.OrderItemID, Qty: it.Qty})
+		}
+		// stok teslim alınırken eklendi; burada tekrar eklenmez
+		// talebin kendi adetleri de açık iadede: düşülmez
+		amount, err := refundOrder(tx, &o, in, nil)
+		if err != nil {
+			return err
+		}
+		r.RefundedCents = amount.Amount
+		return nil
+	})
+}
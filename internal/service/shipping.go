@@ -0,0 +1,165 @@
+package service
+
+import (
+	"errors"
+	"strings"
+
+	"gorm.io/gorm"
+
+	"example.com/ecom-go/internal/model"
+	"example.com/ecom-go/internal/money"
+)
+
+type ShippingInput struct {
+	Code, Name, Kind string
+	Currency         money.Currency
+	FeeCents         int64
+	FreeOverCents    int64
+	PerKgCents       int64
+	Countries        []string // boş → her ülke
+	TaxClass         string
+	Active           bool
+	Position         int
+}
+
+type ShippingService interface {
+	// Methods aktif yöntemler; country doluysa sadece o ülkeye gönderenler.
+	Methods(country string) ([]model.ShippingMethod, error)
+	// All admin listesi (pasifler dahil).
+	All() ([]model.ShippingMethod, error)
+	// Find checkout için: aktif ve country'ye gönderen yöntem, yoksa ErrShippingMethodNotFound.
+	Find(code, country string) (model.ShippingMethod, error)
+	Create(in ShippingInput) (model.ShippingMethod, error)
+	Update(id uint, in ShippingInput) (model.ShippingMethod, error)
+	Delete(id uint) error
+}
+
+type shippingService struct{ db *gorm.DB }
+
+func NewShippingService(db *gorm.DB) ShippingService { return &shippingService{db: db} }
+
+// ValidShippingKind model.Shipping* sabitlerinden biri mi.
+func ValidShippingKind(k string) bool {
+	return k == model.ShippingFlat || k == model.ShippingFreeOver || k == model.ShippingWeight
+}
+
+func shipsTo(m model.ShippingMethod, country string) bool {
+	if m.Countries == "" || country == "" {
+		return true
+	}
+	for _, c := range strings.Split(m.Countries, ",") {
+		if c == country {
+			return true
+		}
+	}
+	return false
+}
+
+func (s *shippingService) Methods(country string) ([]model.ShippingMethod, error) {
+	var all []model.ShippingMethod
+	if err := s.db.Where("active").Order("position asc, id asc").Find(&all).Error; err != nil {
+		return nil, err
+	}
+	out := all[:0]
+	for _, m := range all {
+		if shipsTo(m, country) {
+			out = append(out, m)
+		}
+	}
+	return out, nil
+}
+
+func (s *shippingService) All() ([]model.ShippingMethod, error) {
+	var all []model.ShippingMethod
+	return all, s.db.Order("position asc, id asc").Find(&all).Error
+}
+
+func (s *shippingService) Find(code, country string) (model.ShippingMethod, error) {
+	var m model.ShippingMethod
+	err := s.db.Where("code = ? AND active", code).First(&m).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) || (err == nil && !shipsTo(m, country)) {
+		return m, ErrShippingMethodNotFound
+	}
+	return m, err
+}
+
+func (s *shippingService) Create(in ShippingInput) (model.ShippingMethod, error) {
+	var m model.ShippingMethod
+	applyShipping(&m, in)
+	return m, s.db.Transaction(func(tx *gorm.DB) error {
+		if err := shippingCodeFree(tx, in.Code, 0); err != nil {
+			return err
+		}
+		return tx.Create(&m).Error
+	})
+}
+
+func (s *shippingService) Update(id uint, in ShippingInput) (model.ShippingMethod, error) {
+	var m model.ShippingMethod
+	err := s.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.First(&m, id).Error; err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				return ErrShippingMethodNotFound
+			}
+			return err
+		}
+		if err := shippingCodeFree(tx, in.Code, id); err != nil {
+			return err
+		}
+		applyShipping(&m, in)
+		return tx.Save(&m).Error
+	})
+	return m, err
+}
+
+// Delete eski siparişler kodu snapshot'ladığı için kayıt gerçekten silinir.
+func (s *shippingService) Delete(id uint) error {
+	res := s.db.Delete(&model.ShippingMethod{}, id)
+	if res.Error != nil {
+		return res.Error
+	}
+	if res.RowsAffected == 0 {
+		return ErrShippingMethodNotFound
+	}
+	return nil
+}
+
+func applyShipping(m *model.ShippingMethod, in ShippingInput) {
+	m.Code, m.Name, m.Kind, m.Currency = in.Code, in.Name, in.Kind, string(in.Currency)
+	m.FeeCents, m.FreeOverCents, m.PerKgCents = in.FeeCents, in.FreeOverCents, in.PerKgCents
+	m.Countries = strings.Join(in.Countries, ",")
+	m.TaxClass, m.Active, m.Position = in.TaxClass, in.Active, in.Position
+}
+
+func shippingCodeFree(tx *gorm.DB, code string, exceptID uint) error {
+	var n int64
+	if err := tx.Model(&model.ShippingMethod{}).Where("code = ? AND id <> ?", code, exceptID).Count(&n).Error; err != nil {
+		return err
+	}
+	if n > 0 {
+		return ErrShippingCodeTaken
+	}
+	return nil
+}
+
+// ShippingFee yöntemin ücretini sipariş para biriminde hesaplar. subtotal
+// ürünlerin katalog modundaki (vergi dahil/hariç) toplamı, grams toplam ağırlık.
+// Ücret de katalog fiyatları gibi yorumlanır: vergi dahil modda brüt.
+func ShippingFee(m model.ShippingMethod, subtotal money.Money, grams int, conv func(money.Money) (money.Money, error)) (money.Money, error) {
+	cur := money.Currency(m.Currency)
+	fee := money.New(m.FeeCents, cur)
+	switch m.Kind {
+	case model.ShippingFreeOver:
+		threshold, err := conv(money.New(m.FreeOverCents, cur))
+		if err != nil {
+			return fee, err
+		}
+		if subtotal.Amount >= threshold.Amount {
+			return money.New(0, subtotal.Currency), nil
+		}
+	case model.ShippingWeight:
+		kg := (int64(grams) + 999) / 1000 // başlayan kg
+		fee = money.New(m.FeeCents+kg*m.PerKgCents, cur)
+	}
+	return conv(fee)
+}
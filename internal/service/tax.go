@@ -0,0 +1,131 @@
+package service
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+
+	"example.com/ecom-go/internal/model"
+	"example.com/ecom-go/internal/money"
+	"example.com/ecom-go/internal/validate"
+)
+
+// TaxLine bir sipariş satırının vergi dökümü (sipariş para biriminde).
+type TaxLine struct {
+	Class       string
+	BasisPoints int
+	Net, Tax    money.Money
+}
+
+func (l TaxLine) Gross() money.Money { return money.New(l.Net.Amount+l.Tax.Amount, l.Net.Currency) }
+
+type TaxService interface {
+	// PricesIncludeTax katalog fiyatları vergi dahil mi (PRICES_INCLUDE_TAX, varsayılan true).
+	PricesIncludeTax() bool
+	// Jurisdiction adres bilinmediğinde kullanılan vergi bölgesi (TAX_JURISDICTION, TR).
+	Jurisdiction() string
+	// JurisdictionFor teslimat ülkesi için oran tanımlıysa o ülke, değilse Jurisdiction().
+	JurisdictionFor(country string) (string, error)
+	Rates() ([]model.TaxRate, error)
+	SetRate(jurisdiction, class, name string, basisPoints int) (model.TaxRate, error)
+	// SetProductClass sınıfın varsayılan bölgede oranı yoksa reddeder (checkout kırılmasın).
+	SetProductClass(productID uint, class string) error
+	// Calculator satır tutarını (katalog moduna göre brüt ya da net) vergilendirir;
+	// bölgenin oranlarını çağrı boyunca bir kez okur.
+	Calculator(jurisdiction string) func(class string, line money.Money) (TaxLine, error)
+}
+
+type taxService struct {
+	db           *gorm.DB
+	inclusive    bool
+	jurisdiction string
+}
+
+var jurisdictionRe = regexp.MustCompile(`^[A-Z]{2}$`)
+
+// ValidJurisdiction ISO 3166-1 alpha-2 ülke kodu ("TR").
+func ValidJurisdiction(s string) bool { return jurisdictionRe.MatchString(s) }
+
+func NewTaxService(db *gorm.DB) TaxService {
+	inclusive := true
+	if v, err := strconv.ParseBool(os.Getenv("PRICES_INCLUDE_TAX")); err == nil {
+		inclusive = v
+	}
+	j := strings.ToUpper(strings.TrimSpace(os.Getenv("TAX_JURISDICTION")))
+	if !ValidJurisdiction(j) {
+		j = "TR"
+	}
+	return &taxService{db: db, inclusive: inclusive, jurisdiction: j}
+}
+
+func (s *taxService) PricesIncludeTax() bool { return s.inclusive }
+func (s *taxService) Jurisdiction() string   { return s.jurisdiction }
+
+func (s *taxService) JurisdictionFor(country string) (string, error) {
+	if country == "" || country == s.jurisdiction {
+		return s.jurisdiction, nil
+	}
+	var n int64
+	if err := s.db.Model(&model.TaxRate{}).Where("jurisdiction = ?", country).Count(&n).Error; err != nil {
+		return "", err
+	}
+	if n == 0 {
+		return s.jurisdiction, nil
+	}
+	return country, nil
+}
+
+func (s *taxService) Rates() ([]model.TaxRate, error) {
+	var rs []model.TaxRate
+	return rs, s.db.Order("jurisdiction asc, basis_points desc, class asc").Find(&rs).Error
+}
+
+func (s *taxService) SetRate(jurisdiction, class, name string, basisPoints int) (model.TaxRate, error) {
+	tr := model.TaxRate{Jurisdiction: jurisdiction, Class: class, Name: name, BasisPoints: basisPoints}
+	err := s.db.Clauses(clause.OnConflict{
+		Columns:   []clause.Column{{Name: "jurisdiction"}, {Name: "class"}},
+		DoUpdates: clause.AssignmentColumns([]string{"name", "basis_points", "updated_at"}),
+	}).Create(&tr).Error
+	return tr, err
+}
+
+func (s *taxService) SetProductClass(productID uint, class string) error {
+	if err := productExists(s.db, productID); err != nil {
+		return err
+	}
+	var n int64
+	if err := s.db.Model(&model.TaxRate{}).Where("jurisdiction = ? AND class = ?", s.jurisdiction, class).Count(&n).Error; err != nil {
+		return err
+	}
+	if n == 0 {
+		return validate.Errors{"tax_class": fmt.Sprintf("no %s rate defined for this class", s.jurisdiction)}
+	}
+	return s.db.Model(&model.Product{}).Where("id = ?", productID).Update("tax_class", class).Error
+}
+
+func (s *taxService) Calculator(jurisdiction string) func(class string, line money.Money) (TaxLine, error) {
+	rates := map[string]int{}
+	return func(class string, line money.Money) (TaxLine, error) {
+		bp, ok := rates[class]
+		if !ok {
+			var tr model.TaxRate
+			err := s.db.Where("jurisdiction = ? AND class = ?", jurisdiction, class).First(&tr).Error
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				return TaxLine{}, fmt.Errorf("%w: %s/%s", ErrNoTaxRate, jurisdiction, class)
+			}
+			if err != nil {
+				return TaxLine{}, err
+			}
+			bp = tr.BasisPoints
+			rates[class] = bp
+		}
+		net, tax := money.SplitTax(line, bp, s.inclusive)
+		return TaxLine{Class: class, BasisPoints: bp, Net: net, Tax: tax}, nil
+	}
+}
@@ -0,0 +1,267 @@
+package service
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+
+	"gorm.io/gorm"
+
+	"example.com/ecom-go/internal/model"
+	"example.com/ecom-go/internal/money"
+	"example.com/ecom-go/internal/validate"
+)
+
+type VariantInput struct {
+	SKU        string
+	PriceCents *int64 // nil → ürün fiyatı
+	ImageURL   string
+	Stock      int
+	ValueIDs   []uint // her seçenekten tam bir değer
+}
+
+type VariantService interface {
+	// Detail ürünü seçenekleri ve varyantlarıyla, fiyatlar cur'da döner.
+	Detail(productID uint, cur money.Currency) (model.Product, error)
+	AddOption(productID uint, name string, values []string) (model.ProductOption, error)
+	DeleteOption(id uint) error
+	CreateVariant(productID uint, in VariantInput) (model.ProductVariant, error)
+	UpdateVariant(id uint, in VariantInput) (model.ProductVariant, error)
+	DeleteVariant(id uint) error
+}
+
+type variantService struct {
+	db *gorm.DB
+	fx CurrencyService
+}
+
+func NewVariantService(db *gorm.DB, fx CurrencyService) VariantService {
+	return &variantService{db: db, fx: fx}
+}
+
+// preloadVariant varyant + seçilen değerler + değerlerin seçenek adları.
+func preloadVariant(db *gorm.DB, prefix string) *gorm.DB {
+	return db.Preload(prefix + "Values.Option")
+}
+
+func (s *variantService) Detail(productID uint, cur money.Currency) (model.Product, error) {
+	var p model.Product
+	err := preloadVariant(s.db, "Variants.").
+		Preload("Options", func(db *gorm.DB) *gorm.DB { return db.Order("position asc, id asc") }).
+		Preload("Options.Values", func(db *gorm.DB) *gorm.DB { return db.Order("position asc, id asc") }).
+		Preload("Variants", func(db *gorm.DB) *gorm.DB { return db.Order("id asc") }).
+		Preload("Images", func(db *gorm.DB) *gorm.DB { return db.Order("position asc, id asc") }).
+		First(&p, productID).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return p, ErrProductNotFound
+	}
+	if err != nil {
+		return p, err
+	}
+	if cur == "" {
+		cur = s.fx.Default()
+	}
+	conv := s.fx.Converter(cur)
+	price, err := conv(p.BasePrice())
+	if err != nil {
+		return p, err
+	}
+	p.Price = &price
+	for i := range p.Variants {
+		vp, err := conv(p.Variants[i].BasePrice(p))
+		if err != nil {
+			return p, err
+		}
+		p.Variants[i].Price = &vp
+	}
+	return p, nil
+}
+
+func (s *variantService) AddOption(productID uint, name string, values []string) (model.ProductOption, error) {
+	opt := model.ProductOption{ProductID: productID, Name: name}
+	err := s.db.Transaction(func(tx *gorm.DB) error {
+		if err := productExists(tx, productID); err != nil {
+			return err
+		}
+		// mevcut varyantların bu seçenekte değeri olmaz; önce varyantlar silinmeli
+		var n int64
+		if err := tx.Model(&model.ProductVariant{}).Where("product_id = ?", productID).Count(&n).Error; err != nil {
+			return err
+		}
+		if n > 0 {
+			return ErrHasVariants
+		}
+		if err := tx.Model(&model.ProductOption{}).Where("product_id = ? AND LOWER(name) = LOWER(?)", productID, name).Count(&n).Error; err != nil {
+			return err
+		}
+		if n > 0 {
+			return validate.Errors{"name": "option already exists"}
+		}
+		if err := tx.Model(&model.ProductOption{}).Where("product_id = ?", productID).Count(&n).Error; err != nil {
+			return err
+		}
+		opt.Position = int(n)
+		for i, v := range values {
+			opt.Values = append(opt.Values, model.ProductOptionValue{Value: v, Position: i})
+		}
+		return tx.Create(&opt).Error
+	})
+	return opt, err
+}
+
+func (s *variantService) DeleteOption(id uint) error {
+	return s.db.Transaction(func(tx *gorm.DB) error {
+		var opt model.ProductOption
+		if err := tx.First(&opt, id).Error; err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				return ErrOptionNotFound
+			}
+			return err
+		}
+		var n int64
+		if err := tx.Model(&model.ProductVariant{}).Where("product_id = ?", opt.ProductID).Count(&n).Error; err != nil {
+			return err
+		}
+		if n > 0 {
+			return ErrHasVariants
+		}
+		if err := tx.Where("option_id = ?", id).Delete(&model.ProductOptionValue{}).Error; err != nil {
+			return err
+		}
+		return tx.Delete(&opt).Error
+	})
+}
+
+func (s *variantService) CreateVariant(productID uint, in VariantInput) (model.ProductVariant, error) {
+	v := model.ProductVariant{ProductID: productID}
+	err := s.db.Transaction(func(tx *gorm.DB) error {
+		if err := productExists(tx, productID); err != nil {
+			return err
+		}
+		return saveVariant(tx, &v, in)
+	})
+	if err != nil {
+		return v, err
+	}
+	return v, preloadVariant(s.db, "").First(&v, v.ID).Error
+}
+
+func (s *variantService) UpdateVariant(id uint, in VariantInput) (model.ProductVariant, error) {
+	var v model.ProductVariant
+	err := s.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.First(&v, id).Error; err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				return ErrVariantNotFound
+			}
+			return err
+		}
+		return saveVariant(tx, &v, in)
+	})
+	if err != nil {
+		return v, err
+	}
+	return v, preloadVariant(s.db, "").First(&v, v.ID).Error
+}
+
+// saveVariant SKU tekliğini ve seçenek kombinasyonunu doğrulayıp yazar.
+func saveVariant(tx *gorm.DB, v *model.ProductVariant, in VariantInput) error {
+	var n int64
+	if err := tx.Model(&model.ProductVariant{}).Where("LOWER(sku) = LOWER(?) AND id <> ?", in.SKU, v.ID).Count(&n).Error; err != nil {
+		return err
+	}
+	if n > 0 {
+		return ErrSKUTaken
+	}
+
+	var opts []model.ProductOption
+	if err := tx.Preload("Values").Where("product_id = ?", v.ProductID).Find(&opts).Error; err != nil {
+		return err
+	}
+	optOf := map[uint]uint{} // değer → seçenek
+	for _, o := range opts {
+		for _, ov := range o.Values {
+			optOf[ov.ID] = o.ID
+		}
+	}
+	ids := uniqueIDs(in.ValueIDs)
+	seen := map[uint]bool{}
+	for _, id := range ids {
+		oid, ok := optOf[id]
+		if !ok || seen[oid] {
+			return validate.Errors{"option_value_ids": "each option needs exactly one of its own values"}
+		}
+		seen[oid] = true
+	}
+	if len(seen) != len(opts) {
+		return validate.Errors{"option_value_ids": "each option needs exactly one of its own values"}
+	}
+
+	// aynı kombinasyondan ikinci varyant olmasın
+	var others []model.ProductVariant
+	if err := tx.Preload("Values").Where("product_id = ? AND id <> ?", v.ProductID, v.ID).Find(&others).Error; err != nil {
+		return err
+	}
+	key := comboKey(ids)
+	for _, o := range others {
+		var oids []uint
+		for _, ov := range o.Values {
+			oids = append(oids, ov.ID)
+		}
+		if comboKey(uniqueIDs(oids)) == key {
+			return ErrVariantExists
+		}
+	}
+
+	v.SKU, v.PriceCents, v.ImageURL, v.Stock = in.SKU, in.PriceCents, in.ImageURL, in.Stock
+	if err := tx.Omit("Values").Save(v).Error; err != nil {
+		return err
+	}
+	vals := make([]model.ProductOptionValue, 0, len(ids))
+	for _, id := range ids {
+		vals = append(vals, model.ProductOptionValue{ID: id})
+	}
+	assoc := tx.Model(v).Omit("Values.*").Association("Values")
+	if len(vals) == 0 {
+		return assoc.Clear()
+	}
+	return assoc.Replace(vals)
+}
+
+func comboKey(sortedIDs []uint) string {
+	parts := make([]string, len(sortedIDs))
+	for i, id := range sortedIDs {
+		parts[i] = fmt.Sprint(id)
+	}
+	return strings.Join(parts, ",")
+}
+
+func (s *variantService) DeleteVariant(id uint) error {
+	return s.db.Transaction(func(tx *gorm.DB) error {
+		// sepetlerden düşer; sipariş satırları snapshot'ı koruduğu için dokunulmaz
+		if err := tx.Where("variant_id = ?", id).Delete(&model.CartItem{}).Error; err != nil {
+			return err
+		}
+		if err := tx.Exec("DELETE FROM variant_option_values WHERE product_variant_id = ?", id).Error; err != nil {
+			return err
+		}
+		res := tx.Delete(&model.ProductVariant{}, id)
+		if res.Error != nil {
+			return res.Error
+		}
+		if res.RowsAffected == 0 {
+			return ErrVariantNotFound
+		}
+		return nil
+	})
+}
+
+func productExists(tx *gorm.DB, productID uint) error {
+	var n int64
+	if err := tx.Model(&model.Product{}).Where("id = ?", productID).Count(&n).Error; err != nil {
+		return err
+	}
+	if n == 0 {
+		return ErrProductNotFound
+	}
+	return nil
+}
@@ -0,0 +1,42 @@
+// Package storage yüklenen dosyalar (ürün görselleri, thumbnail'ler) için
+// anahtar → blob deposu. Şimdilik yerel disk; S3 uyumlu bir backend aynı
+// arayüzü uygulayarak (Put/Get/Delete, anahtar = object key) eklenebilir.
+package storage
+
+import (
+	"context"
+	"errors"
+	"io"
+	"strings"
+	"time"
+)
+
+var ErrNotFound = errors.New("storage: object not found")
+
+// Info Get ile dönen meta bilgi.
+type Info struct {
+	Size        int64
+	ContentType string
+	ModTime     time.Time
+}
+
+type Storage interface {
+	Put(ctx context.Context, key string, r io.Reader, contentType string) error
+	// Get nesne yoksa ErrNotFound döner; çağıran ReadCloser'ı kapatır.
+	Get(ctx context.Context, key string) (io.ReadCloser, Info, error)
+	Delete(ctx context.Context, key string) error
+}
+
+// ValidKey "products/12/ab.jpg" gibi göreli, ".." içermeyen anahtarlar.
+// Backend'ler bunu kontrol eder (yerelde dizin dışına çıkılmasın).
+func ValidKey(key string) bool {
+	if key == "" || strings.HasPrefix(key, "/") || strings.Contains(key, "\\") {
+		return false
+	}
+	for _, part := range strings.Split(key, "/") {
+		if part == "" || part == "." || part == ".." {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,163 @@
+package validate
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/mail"
+	"sort"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+)
+
+// Tüm JSON gövdeleri için üst sınır (nginx client_max_body_size 2m ile uyumlu, daha sıkı).
+const MaxBodyBytes = 1 << 20
+
+// Sepete tek seferde eklenebilecek / sepette tutulabilecek en fazla adet.
+const MaxQty = 99
+
+// Errors alan adı → hata mesajı. Handler'lar bunu tek tip JSON'a çevirir:
+//
+//	{"error": "validation failed", "fields": {"email": "must be a valid email"}}
+type Errors map[string]string
+
+func (e Errors) Error() string {
+	keys := make([]string, 0, len(e))
+	for k := range e {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	parts := make([]string, 0, len(keys))
+	for _, k := range keys {
+		parts = append(parts, k+": "+e[k])
+	}
+	return "validation failed: " + strings.Join(parts, ", ")
+}
+
+// Add ilk hatayı tutar; aynı alana ikinci mesaj yazılmaz.
+func (e Errors) Add(field, msg string) {
+	if _, ok := e[field]; !ok {
+		e[field] = msg
+	}
+}
+
+// Err hata yoksa nil döner (nil map'i error'a sarmamak için).
+func (e Errors) Err() error {
+	if len(e) == 0 {
+		return nil
+	}
+	return e
+}
+
+// ErrBodyTooLarge gövde MaxBodyBytes'ı aştığında döner (413).
+var ErrBodyTooLarge = errors.New("request body too large")
+
+// Response her handler'ın döndüğü ortak hata gövdesi.
+func Response(err error) (int, map[string]any) {
+	var fe Errors
+	if errors.As(err, &fe) {
+		return http.StatusUnprocessableEntity, map[string]any{"error": "validation failed", "fields": map[string]string(fe)}
+	}
+	if errors.Is(err, ErrBodyTooLarge) {
+		return http.StatusRequestEntityTooLarge, map[string]any{"error": err.Error()}
+	}
+	return http.StatusBadRequest, map[string]any{"error": err.Error()}
+}
+
+// Decode gövdeyi boyut sınırıyla okur, bilinmeyen alanları ve fazladan JSON'u reddeder.
+func Decode(w http.ResponseWriter, r *http.Request, dst any) error {
+	r.Body = http.MaxBytesReader(w, r.Body, MaxBodyBytes)
+	dec := json.NewDecoder(r.Body)
+	dec.DisallowUnknownFields()
+	if err := dec.Decode(dst); err != nil {
+		return decodeErr(err)
+	}
+	if err := dec.Decode(&struct{}{}); !errors.Is(err, io.EOF) {
+		return errors.New("body must contain a single JSON object")
+	}
+	return nil
+}
+
+// Bind gin handler'ları için Decode karşılığı.
+func Bind(c *gin.Context, dst any) error {
+	return Decode(c.Writer, c.Request, dst)
+}
+
+// Fail ortak hata gövdesini yazar ve isteği durdurur.
+func Fail(c *gin.Context, err error) {
+	status, body := Response(err)
+	c.AbortWithStatusJSON(status, body)
+}
+
+func decodeErr(err error) error {
+	var (
+		maxErr    *http.MaxBytesError
+		syntaxErr *json.SyntaxError
+		typeErr   *json.UnmarshalTypeError
+	)
+	switch {
+	case errors.As(err, &maxErr):
+		return ErrBodyTooLarge
+	case errors.Is(err, io.EOF):
+		return errors.New("body must not be empty")
+	case errors.As(err, &syntaxErr), errors.Is(err, io.ErrUnexpectedEOF):
+		return errors.New("malformed JSON")
+	case errors.As(err, &typeErr) && typeErr.Field != "":
+		return Errors{typeErr.Field: "has the wrong type"}
+	case strings.HasPrefix(err.Error(), "json: unknown field "):
+		name := strings.Trim(strings.TrimPrefix(err.Error(), "json: unknown field "), `"`)
+		return Errors{name: "unknown field"}
+	}
+	return errors.New("malformed JSON")
+}
+
+// ---------------------------------------------------
+// Alan kuralları
+// ---------------------------------------------------
+
+// NormalizeEmail boşlukları kırpar ve küçük harfe çevirir.
+func NormalizeEmail(s string) string { return strings.ToLower(strings.TrimSpace(s)) }
+
+func Email(e Errors, field, v string) {
+	if v == "" {
+		e.Add(field, "is required")
+		return
+	}
+	if len(v) > 254 {
+		e.Add(field, "is too long")
+		return
+	}
+	a, err := mail.ParseAddress(v)
+	if err != nil || a.Address != v || !strings.Contains(v[strings.LastIndex(v, "@"):], ".") {
+		e.Add(field, "must be a valid email")
+	}
+}
+
+func Required(e Errors, field, v string) {
+	if strings.TrimSpace(v) == "" {
+		e.Add(field, "is required")
+	}
+}
+
+func PositiveID(e Errors, field string, v uint) {
+	if v == 0 {
+		e.Add(field, "must be a positive id")
+	}
+}
+
+func Qty(e Errors, field string, v int) {
+	if v <= 0 || v > MaxQty {
+		e.Add(field, fmt.Sprintf("must be between 1 and %d", MaxQty))
+	}
+}
+
+// Code 6 haneli doğrulama kodu.
+func Code(e Errors, field, v string) {
+	v = strings.TrimSpace(v)
+	if len(v) != 6 || strings.Trim(v, "0123456789") != "" {
+		e.Add(field, "must be a 6-digit code")
+	}
+}
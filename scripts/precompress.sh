@@ -0,0 +1,9 @@
+#!/usr/bin/env bash
+# web/assets altındaki metin dosyaları için .br kopyalarını üretir (build öncesi).
+# gzip sunucu açılışında bellekte üretiliyor; brotli için `brotli` CLI gerekli.
+set -euo pipefail
+cd "$(dirname "$0")/../web/assets"
+find . -type f \( -name '*.js' -o -name '*.css' -o -name '*.svg' -o -name '*.txt' \) -print0 |
+  while IFS= read -r -d '' f; do
+    brotli --force --best --output="$f.br" "$f"
+  done
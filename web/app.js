@@ -1,9 +1,12 @@
 // basit durum
 const state = { token: null };
 
+// CSRF: sunucunun yazdığı csrf_token cookie'si → X-CSRF-Token header
+const csrfToken = () => (document.cookie.match(/(?:^|; )csrf_token=([^;]*)/) || [])[1] || '';
+
 // genel fetch helper (aynı origin)
 async function api(path, opts = {}) {
-  const headers = Object.assign({ 'Content-Type': 'application/json' }, opts.headers || {});
+  const headers = Object.assign({ 'Content-Type': 'application/json', 'X-CSRF-Token': csrfToken() }, opts.headers || {});
   // JWT varsa Authorization ekle (cookie de var ama ikisi birden çalışır)
   if (state.token) headers['Authorization'] = 'Bearer ' + state.token;
 
@@ -70,7 +73,7 @@ async function loadProducts() {
         <div class="b">
           <div><b>${p.Name}</b></div>
           <div class="price">${(p.PriceCents/100).toFixed(2)} ₺</div>
-          <button onclick="addToCart(${p.ID})">Sepete Ekle</button>
+          <button data-add="${p.ID}">Sepete Ekle</button>
         </div>
       </div>
     `).join('');
@@ -106,4 +109,9 @@ async function checkout() {
   } catch (e) { setMsg('Checkout hata: ' + e.message, false); }
 }
 
+document.getElementById('list')?.addEventListener('click', (e) => {
+  const id = e.target.closest('[data-add]')?.dataset.add;
+  if (id) addToCart(Number(id));
+});
+
 loadProducts();
@@ -1,19 +1,30 @@
 // web/assets/auth.js
 
+// CSRF: sunucunun yazdığı csrf_token cookie'si → X-CSRF-Token header
+const csrfToken = () => (document.cookie.match(/(?:^|; )csrf_token=([^;]*)/) || [])[1] || "";
+
 // Basit fetch wrapper
-async function api(path, opts = {}) {
+async function api(path, opts = {}, retried = false) {
   const res = await fetch(path, {
     method: opts.method || "POST",
-    headers: {"Content-Type": "application/json"},
+    headers: {"Content-Type": "application/json", "X-CSRF-Token": csrfToken()},
     body: opts.body ?? null,
     credentials: "include",
   });
   const txt = await res.text();
   let data; try { data = JSON.parse(txt); } catch { data = txt; }
-  if (!res.ok) throw new Error(data?.error || ("HTTP " + res.status));
+  // 403 csrf: sunucu cookie'yi tazeledi, bir kez tekrar dene
+  if (res.status === 403 && !retried && /csrf/.test(data?.error || "")) return api(path, opts, true);
+  if (!res.ok) throw new Error(errText(data, res.status));
   return data;
 }
 
+// {"error":"validation failed","fields":{...}} → okunur mesaj
+function errText(data, status) {
+  if (data?.fields) return Object.entries(data.fields).map(([k, v]) => `${k}: ${v}`).join(", ");
+  return data?.error || ("HTTP " + status);
+}
+
 function setMsg(s, ok = true) {
   const el = document.getElementById("msg");
   if (el) {
@@ -33,7 +44,7 @@ window.verifyCode = async function(){
       body: JSON.stringify({email, code})
     });
     const data = await r.json();
-    if(!r.ok) throw new Error(data?.error || r.statusText);
+    if(!r.ok) throw new Error(errText(data, r.status));
     // Başarılı: ana sayfaya (ürünler) yönlendir
     location.href = '/';
   }catch(e){
@@ -63,7 +74,13 @@ async function login() {
   if (!email || !password) return setMsg("Email ve şifre gerekli.", false);
 
   try {
-    await api("/api/auth/login", { body: JSON.stringify({ email, password }) });
+    const r = await api("/api/auth/login", { body: JSON.stringify({ email, password }) });
+    // 2FA açıksa ikinci adım: authenticator kodu (veya kurtarma kodu)
+    if (r?.mfa_required) {
+      const code = window.prompt("Doğrulama uygulamasındaki 6 haneli kod (veya kurtarma kodu):");
+      if (!code) return setMsg("Giriş iptal edildi.", false);
+      await api("/api/auth/mfa", { body: JSON.stringify({ mfa_token: r.mfa_token, code: code.trim() }) });
+    }
     setMsg("Giriş başarılı.");
     // varsa ?redirect=... parametresine, yoksa ana sayfaya
     const params = new URLSearchParams(window.location.search);
@@ -74,6 +91,18 @@ async function login() {
   }
 }
 
+// Şifresiz giriş: e-postaya tek kullanımlık link
+async function magicLink() {
+  const email = document.getElementById("logEmail")?.value.trim();
+  if (!email) return setMsg("Email gerekli.", false);
+  try {
+    await api("/api/auth/magic-link", { body: JSON.stringify({ email }) });
+    setMsg("Kayıtlıysan giriş linki e-postana gönderildi (15 dk geçerli).");
+  } catch (e) {
+    setMsg("Link hata: " + e.message, false);
+  }
+}
+
 // Çıkış
 async function logout() {
   try {
@@ -86,12 +115,48 @@ async function logout() {
   }
 }
 
+// Sosyal giriş butonları (OIDC sağlayıcıları sunucudan)
+async function loadProviders() {
+  const box = document.getElementById("oidcBox");
+  if (!box) return;
+  try {
+    const r = await api("/api/auth/oidc/providers", { method: "GET" });
+    const to = new URLSearchParams(window.location.search).get("redirect") || "/";
+    box.innerHTML = (r.providers || []).map(p =>
+      `<a class="btn" href="/api/auth/oidc/${encodeURIComponent(p)}/start?redirect=${encodeURIComponent(to)}">${p} ile giriş</a>`
+    ).join(" ");
+  } catch (_) {}
+}
+
+// OIDC dönüşü: hata (?error=) veya 2FA adımı (#mfa=token)
+async function handleOIDCReturn() {
+  const params = new URLSearchParams(window.location.search);
+  if (params.get("error")) setMsg("Sosyal giriş başarısız: " + params.get("error"), false);
+
+  const m = window.location.hash.match(/^#mfa=(.+)$/);
+  if (!m) return;
+  history.replaceState(null, "", window.location.pathname + window.location.search);
+  const code = window.prompt("Doğrulama uygulamasındaki 6 haneli kod (veya kurtarma kodu):");
+  if (!code) return setMsg("Giriş iptal edildi.", false);
+  try {
+    await api("/api/auth/mfa", { body: JSON.stringify({ mfa_token: m[1], code: code.trim() }) });
+    window.location.href = params.get("redirect") || "/";
+  } catch (e) {
+    setMsg("Giriş hata: " + e.message, false);
+  }
+}
+
 // DOM hazır olunca butonlara bağla + Enter kısayolları
 document.addEventListener("DOMContentLoaded", () => {
   const $ = (id) => document.getElementById(id);
 
+  loadProviders();
+  handleOIDCReturn();
+
   $("btnRegister")?.addEventListener("click", register);
   $("btnLogin")?.addEventListener("click", login);
+  $("btnMagic")?.addEventListener("click", magicLink);
+  $("btnVerifyCode")?.addEventListener("click", verifyCode);
   $("btnLogout")?.addEventListener("click", logout);
 
   // Enter ile kayıt
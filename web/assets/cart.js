@@ -1,5 +1,25 @@
-const api=async(p,o={})=>{const r=await fetch(p,{method:o.method||'GET',headers:{'Content-Type':'application/json'},body:o.body||null,credentials:'include'});const t=await r.text();let d;try{d=JSON.parse(t)}catch{d=t}if(!r.ok)throw new Error(d?.error||('HTTP '+r.status));return d;};
+const csrfToken=()=>(document.cookie.match(/(?:^|; )csrf_token=([^;]*)/)||[])[1]||'';
+const api=async(p,o={},retried=false)=>{const r=await fetch(p,{method:o.method||'GET',headers:{'Content-Type':'application/json','X-CSRF-Token':csrfToken()},body:o.body||null,credentials:'include'});const t=await r.text();let d;try{d=JSON.parse(t)}catch{d=t}if(r.status===403&&!retried&&/csrf/.test(d?.error||''))return api(p,o,true);if(!r.ok)throw Object.assign(new Error(d?.fields?Object.values(d.fields).join(', '):(d?.error||('HTTP '+r.status))),{status:r.status,data:d});return d;};
 const msg=(s,ok=true)=>{const el=document.getElementById('msg');el.textContent=s;el.style.color=ok?'#16a34a':'#ef4444';};
-async function loadCart(){ try{ const items=await api('/api/cart'); document.getElementById('cartBox').textContent=items.length?items.map(it=>`${it.Qty} x ${it.Product.Name} = ${(it.Product.PriceCents*it.Qty/100).toFixed(2)} ₺`).join('\n'):'Boş'; }catch(e){ document.getElementById('cartBox').textContent='Sepet yüklenemedi: '+e.message; } }
-async function checkout(){ try{ const o=await api('/api/checkout',{method:'POST'}); msg(`Sipariş #${o.ID} — Toplam ${(o.TotalCents/100).toFixed(2)} ₺`); loadCart(); }catch(e){ msg('Checkout hata: '+e.message,false); } }
-loadCart();
+// Tutarların hepsi sunucudan (checkout ile aynı fiyatlama); burada sadece biçimlenir.
+function summaryURL(path){ const p=new URLSearchParams(); const cur=displayCurrency(); if(cur)p.set('currency',cur); const a=document.getElementById('address')?.value; if(a)p.set('address_id',a); const m=document.getElementById('shipping')?.value; if(m)p.set('shipping_method',m); const q=p.toString(); return q?`${path}?${q}`:path; }
+function renderSummary(s){ if(!s.lines.length){ document.getElementById('cartBox').textContent='Boş'; document.getElementById('couponBox').textContent=''; return; } const rows=s.lines.map(l=>{const opts=(l.options||[]).map(o=>`${o.name}: ${o.value}`).join(', ');return `${l.qty} x ${l.name}${opts?` (${opts})`:''} = ${fmtMoney(l.line_total)}${l.discount.amount?` (-${fmtMoney(l.discount)})`:''}`;}); rows.push('',`Ara toplam: ${fmtMoney(s.items_total)}`); for(const d of s.discounts)rows.push(`${d.code?d.code+' — ':''}${d.name}: -${fmtMoney(d.amount)}`); rows.push(s.shipping?`Kargo (${s.shipping.name}): ${fmtMoney(s.shipping.amount)}`:'Kargo: bu adrese gönderim yok'); rows.push(`KDV${s.tax_included?' (dahil)':''}: ${fmtMoney(s.tax)}`,`Genel toplam: ${fmtMoney(s.total)}`); document.getElementById('cartBox').textContent=rows.join('\n'); document.getElementById('couponBox').textContent=[s.coupon?`Kupon: ${s.coupon}`:'',...s.warnings.map(w=>'⚠ '+w.message)].filter(Boolean).join('\n'); }
+async function loadCart(){ try{ renderSummary(await api(summaryURL('/api/cart/summary'))); }catch(e){ document.getElementById('cartBox').textContent='Sepet yüklenemedi: '+e.message; } }
+let addrs=[];
+async function loadAddresses(){ try{ addrs=await api('/api/addresses'); const sel=document.getElementById('address'); sel.innerHTML=addrs.length?addrs.map(a=>`<option value="${a.ID}">${esc((a.Label?a.Label+' — ':'')+a.FullName+', '+a.City+' / '+a.Country)}</option>`).join(''):'<option value="">Adres yok — yeni adres ekleyin</option>'; document.getElementById('newAddress').open=!addrs.length; loadShipping(); }catch(e){ msg('Adresler yüklenemedi: '+e.message,false); loadCart(); } }
+async function loadShipping(){ const a=addrs.find(x=>String(x.ID)===document.getElementById('address').value); try{ const ms=await api('/api/shipping-methods'+(a?'?country='+encodeURIComponent(a.Country):'')); document.getElementById('shipping').innerHTML=ms.length?ms.map(m=>`<option value="${esc(m.Code)}">${esc(m.Name)} — ${shippingRule(m)}</option>`).join(''):'<option value="">Bu adrese gönderim yok</option>'; loadCart(); }catch(e){ msg('Kargo seçenekleri yüklenemedi: '+e.message,false); loadCart(); } }
+function shippingRule(m){ const fee=fmtMoney({amount:m.FeeCents,currency:m.Currency}); if(m.Kind==='free_over')return `${fee}, ${fmtMoney({amount:m.FreeOverCents,currency:m.Currency})} üzeri ücretsiz`; if(m.Kind==='weight')return `${fee} + kg başı ${fmtMoney({amount:m.PerKgCents,currency:m.Currency})}`; return fee; }
+function esc(s){ return String(s??'').replace(/[&<>"']/g,c=>({'&':'&amp;','<':'&lt;','>':'&gt;','"':'&quot;',"'":'&#39;'}[c])); }
+async function saveAddress(e){ e.preventDefault(); const body=Object.fromEntries(new FormData(e.target)); body.country=(body.country||'').toUpperCase(); try{ const a=await api('/api/addresses',{method:'POST',body:JSON.stringify(body)}); e.target.reset(); await loadAddresses(); document.getElementById('address').value=a.ID; loadShipping(); msg('Adres kaydedildi'); }catch(err){ msg('Adres kaydedilemedi: '+err.message,false); } }
+async function applyCoupon(e){ e.preventDefault(); const code=new FormData(e.target).get('code'); try{ renderSummary(await api(summaryURL('/api/cart/coupon'),{method:'POST',body:JSON.stringify({code})})); msg('Kupon uygulandı'); }catch(err){ msg('Kupon: '+err.message,false); } }
+async function removeCoupon(){ try{ await api('/api/cart/coupon',{method:'DELETE'}); msg('Kupon kaldırıldı'); loadCart(); }catch(err){ msg('Kupon kaldırılamadı: '+err.message,false); } }
+// Fiyat değiştiyse sunucu 409 + değişiklikler döner; kullanıcı onaylarsa cart_version ile tekrar gönderilir.
+async function checkout(e,version){ try{ const o=await api('/api/checkout',{method:'POST',body:JSON.stringify({currency:displayCurrency()||undefined,address_id:Number(document.getElementById('address').value)||undefined,shipping_method:document.getElementById('shipping').value,cart_version:version})}); msg(`Sipariş #${o.ID} — Toplam ${fmtMoney({amount:o.TotalCents,currency:o.Currency})} (KDV ${fmtMoney({amount:o.TaxCents,currency:o.Currency})})`); loadCart(); }catch(err){ if(err.status===409&&err.data?.changes){ loadCart(); const lines=err.data.changes.map(c=>`${c.name}: ${fmtMoney(c.old_price)} → ${fmtMoney(c.new_price)}`).join('\n'); if(confirm(`Sepete eklediğinizden beri fiyatlar değişti:\n${lines}\n\nGüncel fiyatlarla devam edilsin mi?`))return checkout(e,err.data.cart_version); msg('Sipariş verilmedi: fiyatlar değişti',false); return; } msg('Checkout hata: '+err.message,false); } }
+document.getElementById('btnCheckout')?.addEventListener('click',checkout);
+document.getElementById('address')?.addEventListener('change',loadShipping);
+document.getElementById('shipping')?.addEventListener('change',loadCart);
+document.getElementById('addressForm')?.addEventListener('submit',saveAddress);
+document.getElementById('couponForm')?.addEventListener('submit',applyCoupon);
+document.getElementById('btnRemoveCoupon')?.addEventListener('click',removeCoupon);
+document.addEventListener('currencychange',loadCart);
+loadAddresses();
@@ -0,0 +1,53 @@
+// /web/assets/money.js
+// Tutarlar API'den en küçük birimde tam sayı gelir ({amount, currency});
+// burada float'a çevirmeden (string işlemiyle) locale'e göre yazılır.
+
+const CURRENCIES = {
+  TRY: { exp: 2, sym: "₺" },
+  USD: { exp: 2, sym: "$" },
+  EUR: { exp: 2, sym: "€" },
+  GBP: { exp: 2, sym: "£" },
+  JPY: { exp: 0, sym: "¥" },
+};
+const LOCALES = {
+  "tr-TR": { dec: ",", grp: ".", after: true },
+  "en-US": { dec: ".", grp: ",", after: false },
+};
+
+function fmtMoney(m, locale = "tr-TR") {
+  if (!m) return "";
+  const c = CURRENCIES[m.currency] || { exp: 2, sym: m.currency };
+  const l = LOCALES[locale] || LOCALES["tr-TR"];
+  const neg = m.amount < 0;
+  let digits = String(Math.abs(m.amount)).padStart(c.exp + 1, "0");
+  const int = digits.slice(0, digits.length - c.exp);
+  const frac = c.exp ? digits.slice(-c.exp) : "";
+  const grouped = int.replace(/\B(?=(\d{3})+(?!\d))/g, l.grp);
+  const num = grouped + (frac ? l.dec + frac : "");
+  return (neg ? "-" : "") + (l.after ? `${num} ${c.sym}` : `${c.sym}${num}`);
+}
+
+// Gösterim para birimi: boş → sunucunun varsayılanı
+function displayCurrency() { return localStorage.getItem("currency") || ""; }
+
+function withCurrency(url) {
+  const cur = displayCurrency();
+  if (!cur) return url;
+  return url + (url.includes("?") ? "&" : "?") + "currency=" + encodeURIComponent(cur);
+}
+
+// nav'daki #currency seçicisi; değişince "currencychange" olayı
+(function initCurrencySelect() {
+  const sel = document.getElementById("currency");
+  if (!sel) return;
+  for (const code of Object.keys(CURRENCIES)) {
+    const o = document.createElement("option");
+    o.value = o.textContent = code;
+    sel.appendChild(o);
+  }
+  sel.value = displayCurrency() || "TRY";
+  sel.addEventListener("change", () => {
+    localStorage.setItem("currency", sel.value);
+    document.dispatchEvent(new Event("currencychange"));
+  });
+})();
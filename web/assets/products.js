@@ -19,15 +19,40 @@ function pick(v, ...alts) {
   return undefined;
 }
 
-async function loadProducts() {
+// Arama durumu: form değerleri + sonraki sayfanın cursor'u
+let nextCursor = "";
+
+function searchParams() {
+  const f = document.getElementById("search");
+  const qs = new URLSearchParams();
+  if (!f) return qs;
+  const q = f.q.value.trim();
+  if (q) qs.set("q", q);
+  // ₺ → kuruş (API tam sayı bekler)
+  for (const k of ["min_price", "max_price"]) {
+    if (f[k].value !== "") qs.set(k, String(Math.round(Number(f[k].value) * 100)));
+  }
+  if (f.sort.value) qs.set("sort", f.sort.value);
+  return qs;
+}
+
+async function loadProducts(append = false) {
   try {
-    const data = await api("/api/products");
+    const qs = searchParams();
+    if (append && nextCursor) qs.set("cursor", nextCursor);
+    const cat = document.getElementById("search")?.category.value;
+    const base = cat ? `/api/categories/${encodeURIComponent(cat)}/products` : "/api/products";
+    const page = await api(withCurrency(base + (qs.toString() ? "?" + qs : "")));
     const list = document.getElementById("list");
-    if (!Array.isArray(data)) throw new Error("Beklenmeyen yanıt");
+    if (!page || !Array.isArray(page.items)) throw new Error("Beklenmeyen yanıt");
+    const data = page.items;
+    nextCursor = page.next_cursor || "";
+    document.getElementById("more").hidden = !nextCursor;
+    document.getElementById("total").textContent = `${page.total} ürün`;
 
     const hasExt = (s) => /\.(png|jpe?g|webp|gif|svg)$/i.test(s);
 
-    list.innerHTML = data.map(p => {
+    const html = data.map(p => {
       const id    = pick(p.ID, p.id);
       const name  = pick(p.Name, p.name) || "Ürün";
 
@@ -39,6 +64,9 @@ async function loadProducts() {
       if (raw && /^https?:\/\//.test(raw)) {
         // Tam URL ise (http/https)
         img = hasExt(raw) ? raw : "/assets/img/placeholder.png";
+      } else if (raw && raw.startsWith("/media/")) {
+        // Yüklenen görselin thumbnail'i (uzantısız URL)
+        img = raw;
       } else if (raw && raw.startsWith("/assets/")) {
         // /assets/... ise
         img = hasExt(raw) ? raw : "/assets/img/placeholder.png";
@@ -51,45 +79,114 @@ async function loadProducts() {
         img = "/assets/img/placeholder.png";
       }
 
-      const cents = pick(p.PriceCents, p.price_cents, p.priceCents, 0) || 0;
-      const price = (Number(cents) / 100).toFixed(2);
+      // gösterim para biriminde (sunucu çevirir)
+      const price = fmtMoney(p.Price || { amount: p.PriceCents || 0, currency: p.Currency || "TRY" });
 
       return `
         <div class="card">
-          <img src="${img}" alt="${name}" loading="lazy"
-               onerror="this.onerror=null;this.src='/assets/img/placeholder.png'">
+          <img src="${img}" alt="${name}" loading="lazy">
           <div class="b">
             <div class="t">${name}</div>
-            <div class="price">${price} ₺</div>
-            <button onclick="addToCart(${id})">Sepete Ekle</button>
+            <div class="price">${price}</div>
+            <button data-add="${id}">Sepete Ekle</button>
           </div>
         </div>
       `;
     }).join("");
+    if (append) list.insertAdjacentHTML("beforeend", html);
+    else list.innerHTML = html;
+    if (!append && !data.length) msg("Sonuç bulunamadı", false);
+    else msg("");
+
+    // CSP inline handler'a izin vermiyor: olaylar burada bağlanır
+    // (sayfa eklenince sadece yeni görseller)
+    list.querySelectorAll("img:not([data-bound])").forEach(img => {
+      img.dataset.bound = "1";
+      img.addEventListener("error", () => { img.src = "/assets/img/placeholder.png"; }, { once: true });
+    });
 
   } catch (e) {
     msg("Ürünler yüklenemedi: " + e.message, false);
   }
 }
-async function(){
+// Kategori ağacı → girintili <option> listesi (alt kategoriler dahil filtrelenir)
+async function loadCategories() {
+  const sel = document.getElementById("search")?.category;
+  if (!sel) return;
+  try {
+    const tree = await api("/api/categories");
+    const walk = (nodes, depth) => nodes.forEach(n => {
+      const o = document.createElement("option");
+      o.value = n.slug;
+      o.textContent = "\u00a0\u00a0".repeat(depth) + n.name;
+      sel.appendChild(o);
+      walk(n.children || [], depth + 1);
+    });
+    walk(tree, 0);
+  } catch { /* kategoriler opsiyonel */ }
+}
+
+(async function(){
   const r = await fetch('/api/me');
-  if (r.status === 401) { location.href = '/login.html'; return; }
+  if (r.status === 401) { location.href = '/auth?redirect=/'; return; }
   // giriş varsa mevcut loadProducts()'ı çağır
   if (typeof loadProducts === 'function') loadProducts();
-}();
-async function addToCart(id) {
+  loadCategories();
+})();
+// CSRF: sunucunun yazdığı csrf_token cookie'si → X-CSRF-Token header
+const csrfToken = () => (document.cookie.match(/(?:^|; )csrf_token=([^;]*)/) || [])[1] || "";
+
+async function addToCart(id, btn) {
   try {
-    const r = await fetch("/api/cart/add", {
+    // varyant seçici varsa (bkz. showVariants) seçili varyantla ekle
+    const sel = btn?.closest(".card")?.querySelector("select[data-variant]");
+    const body = { product_id: id, qty: 1 };
+    if (sel) body.variant_id = Number(sel.value);
+    const post = () => fetch("/api/cart/add", {
       method: "POST",
-      headers: { "Content-Type": "application/json" },
+      headers: { "Content-Type": "application/json", "X-CSRF-Token": csrfToken() },
       credentials: "include",
-      body: JSON.stringify({ product_id: id, qty: 1 })
+      body: JSON.stringify(body)
     });
-    if (!r.ok) throw new Error(`HTTP ${r.status}`);
+    let r = await post();
+    if (r.status === 403) r = await post(); // csrf cookie tazelendi, bir kez tekrar
+    if (!r.ok) {
+      const d = await r.json().catch(() => ({}));
+      if (!sel && d.fields?.variant_id) { await showVariants(id, btn); return; }
+      throw new Error(Object.values(d.fields || {})[0] || d.error || `HTTP ${r.status}`);
+    }
     msg("Sepete eklendi");
   } catch (e) {
-    msg("Giriş yapmanız gerekebilir: " + e.message, false);
+    msg("Sepete eklenemedi: " + e.message, false);
   }
 }
 
-document.addEventListener("DOMContentLoaded", loadProducts);
+// Varyantlı ürün: karta seçenek listesi ekler, kullanıcı seçip tekrar ekler
+async function showVariants(id, btn) {
+  const p = await api(withCurrency(`/api/products/${id}`));
+  const sel = document.createElement("select");
+  sel.dataset.variant = "1";
+  for (const v of p.Variants || []) {
+    const o = document.createElement("option");
+    o.value = v.ID;
+    const label = (v.Values || []).map(x => x.Value).join(" / ") || v.SKU;
+    o.textContent = `${label} — ${fmtMoney(v.Price || p.Price)}` + (v.Stock > 0 ? "" : " (tükendi)");
+    o.disabled = v.Stock <= 0;
+    sel.appendChild(o);
+  }
+  btn.before(sel);
+  msg("Lütfen bir seçenek seçin");
+}
+
+document.getElementById("list")?.addEventListener("click", (e) => {
+  const btn = e.target.closest("[data-add]");
+  if (btn) addToCart(Number(btn.dataset.add), btn);
+});
+
+document.getElementById("search")?.addEventListener("submit", (e) => {
+  e.preventDefault();
+  loadProducts();
+});
+document.getElementById("search")?.category.addEventListener("change", () => loadProducts());
+document.addEventListener("currencychange", () => loadProducts());
+document.getElementById("more")?.addEventListener("click", () => loadProducts(true));
@@ -0,0 +1,10 @@
+// Package web frontend dosyalarını binary'ye gömer; sunucu bunları
+// çalışma dizininden bağımsız olarak bellekten servis eder.
+package web
+
+import "embed"
+
+// FS sadece servis edilen sayfalar ve assets/ (login/signup/verify.html eski denemeler).
+//
+//go:embed index.html auth.html cart.html assets
+var FS embed.FS